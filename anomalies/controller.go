@@ -0,0 +1,70 @@
+package anomalies
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"checkout-go/ledgers"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type AnomaliesController struct {
+	AnomalyService *AnomalyService
+	LedgerContext  ledgers.LedgerContextReader
+}
+
+func (c *AnomaliesController) ListAnomalies(w http.ResponseWriter, req *http.Request) {
+	status := Status(req.URL.Query().Get("status"))
+	if status == "" {
+		status = StatusFlagged
+	}
+	if status != StatusFlagged && status != StatusDismissed {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	flagged, err := c.AnomalyService.List(ledgerID, status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(flagged)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *AnomaliesController) DismissAnomaly(w http.ResponseWriter, req *http.Request) {
+	transactionID, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	err = c.AnomalyService.Dismiss(ledgerID, transactionID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *AnomaliesController) RescanAnomalies(w http.ResponseWriter, req *http.Request) {
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	err := c.AnomalyService.Rescan(ledgerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,30 @@
+package anomalies
+
+import "checkout-go/customtypes"
+
+type Status string
+
+const (
+	StatusFlagged   Status = "flagged"
+	StatusDismissed Status = "dismissed"
+)
+
+type Anomaly struct {
+	TransactionID int64   `db:"transaction_id" json:"transactionId"`
+	LedgerID      int64   `db:"ledger_id" json:"ledgerId"`
+	Score         float64 `db:"score" json:"score"`
+	Reason        string  `db:"reason" json:"reason"`
+	Status        Status  `db:"status" json:"status"`
+	Date          string  `db:"date" json:"date"`
+}
+
+// FlaggedTransaction is an anomaly together with the transaction it is about.
+type FlaggedTransaction struct {
+	Anomaly
+	Name   string                  `db:"name" json:"name"`
+	Price  float64                 `db:"price" json:"price"`
+	Seller string                  `db:"seller" json:"sellerName"`
+	Tags   customtypes.StringSlice `db:"tags" json:"tags"`
+	// TransactionDate is kept as stored, in UTC
+	TransactionDate string `db:"transaction_date" json:"transactionDate"`
+}
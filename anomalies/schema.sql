@@ -0,0 +1,8 @@
+CREATE TABLE transaction_anomalies (
+    transaction_id INTEGER PRIMARY KEY,
+    ledger_id INTEGER NOT NULL,
+    score REAL NOT NULL,
+    reason TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'flagged',
+    date TEXT NOT NULL
+);
@@ -0,0 +1,297 @@
+package anomalies
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"checkout-go/customtypes"
+
+	goqu "github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+var ErrNotFound = errors.New("anomaly not found")
+
+const (
+	// minSamples is how many earlier expenses a tag or seller needs before its amounts count as typical.
+	minSamples = 5
+	// threshold is the modified z-score above which an amount is unusual, as suggested by Iglewicz and Hoaglin.
+	threshold = 3.5
+	// largePercentile decides what a large amount is for a merchant seen for the first time.
+	largePercentile = 0.9
+	// dismissedTolerance lets amounts slightly above a dismissed one pass as well.
+	dismissedTolerance = 1.1
+)
+
+type AnomalyService struct {
+	DB *goqu.Database
+}
+
+type expense struct {
+	ID     int64                   `db:"id"`
+	Price  float64                 `db:"price"`
+	Seller string                  `db:"seller"`
+	Tags   customtypes.StringSlice `db:"tags"`
+}
+
+// finding is one reason a transaction looks unusual.
+type finding struct {
+	score  float64
+	reason string
+}
+
+// ScoreTransaction compares an expense with the ledger's other expenses of the same tags and seller and flags it
+// when its amount is far above their median, measured in median absolute deviations, or when it is a large first
+// purchase at a new seller. Amounts at or below one the user dismissed before for the same tag or seller are not
+// flagged again. Payments are never flagged.
+func (service *AnomalyService) ScoreTransaction(ledgerID int64, transactionID int64) error {
+	var t expense
+	found, err := service.DB.From("transactions").
+		Select("id", "price", goqu.L("COALESCE(seller, '')").As("seller"), "tags").
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": transactionID}).
+		ScanStruct(&t)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+	var existing Anomaly
+	found, err = service.DB.From("transaction_anomalies").Where(goqu.Ex{"transaction_id": transactionID}).ScanStruct(&existing)
+	if err != nil {
+		return err
+	}
+	if found && existing.Status == StatusDismissed {
+		return nil
+	}
+
+	findings, err := service.score(ledgerID, t)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		_, err = service.DB.Delete("transaction_anomalies").Where(goqu.Ex{"transaction_id": transactionID}).Executor().Exec()
+		return err
+	}
+	score := 0.0
+	reasons := make([]string, 0, len(findings))
+	for _, f := range findings {
+		score = max(score, f.score)
+		reasons = append(reasons, f.reason)
+	}
+	_, err = service.DB.Insert("transaction_anomalies").
+		Rows(Anomaly{
+			TransactionID: transactionID,
+			LedgerID:      ledgerID,
+			Score:         score,
+			Reason:        strings.Join(reasons, "; "),
+			Status:        StatusFlagged,
+			Date:          time.Now().Format(time.RFC3339),
+		}).
+		OnConflict(goqu.DoUpdate("transaction_id", goqu.Record{
+			"score":  score,
+			"reason": strings.Join(reasons, "; "),
+		})).
+		Executor().Exec()
+	return err
+}
+
+func (service *AnomalyService) score(ledgerID int64, t expense) ([]finding, error) {
+	if t.Price >= 0 {
+		return nil, nil
+	}
+	amount := -t.Price
+	var findings []finding
+	for _, tag := range t.Tags {
+		inTag := goqu.L("EXISTS (SELECT 1 FROM json_each(tags) WHERE value = ?)", tag)
+		f, err := service.compare(ledgerID, t, amount, inTag, fmt.Sprintf("tag %q", tag))
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			findings = append(findings, *f)
+		}
+	}
+	if t.Seller == "" {
+		return findings, nil
+	}
+	bySeller := goqu.C("seller").Eq(t.Seller)
+	sellerAmounts, err := service.amounts(ledgerID, t.ID, bySeller)
+	if err != nil {
+		return nil, err
+	}
+	if len(sellerAmounts) > 0 {
+		f, err := service.compare(ledgerID, t, amount, bySeller, fmt.Sprintf("seller %q", t.Seller))
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			findings = append(findings, *f)
+		}
+		return findings, nil
+	}
+	// A seller without any history: only a large amount compared to everything else is worth a warning
+	all, err := service.amounts(ledgerID, t.ID, goqu.L("1 = 1"))
+	if err != nil {
+		return nil, err
+	}
+	if len(all) < minSamples {
+		return findings, nil
+	}
+	large := percentile(all, largePercentile)
+	if large > 0 && amount > large {
+		findings = append(findings, finding{
+			score:  amount / large,
+			reason: fmt.Sprintf("first expense at seller %q and %.2f is larger than %.0f%% of your expenses", t.Seller, amount, largePercentile*100),
+		})
+	}
+	return findings, nil
+}
+
+// compare scores amount against the expenses matching group.
+func (service *AnomalyService) compare(ledgerID int64, t expense, amount float64, group exp.Expression, name string) (*finding, error) {
+	amounts, err := service.amounts(ledgerID, t.ID, group)
+	if err != nil {
+		return nil, err
+	}
+	if len(amounts) < minSamples {
+		return nil, nil
+	}
+	median := percentile(amounts, 0.5)
+	deviations := make([]float64, len(amounts))
+	for i, a := range amounts {
+		deviations[i] = math.Abs(a - median)
+	}
+	mad := percentile(deviations, 0.5)
+	// Identical amounts leave no deviation at all, so allow a small relative spread instead
+	mad = max(mad, median*0.05, 0.01)
+	score := 0.6745 * (amount - median) / mad
+	if score <= threshold {
+		return nil, nil
+	}
+	dismissed, err := service.largestDismissed(ledgerID, group)
+	if err != nil {
+		return nil, err
+	}
+	if amount <= dismissed*dismissedTolerance {
+		return nil, nil
+	}
+	return &finding{
+		score:  score,
+		reason: fmt.Sprintf("%.2f is far above the typical %.2f for %s", amount, median, name),
+	}, nil
+}
+
+// amounts returns the positive amounts of the ledger's other expenses matching group.
+func (service *AnomalyService) amounts(ledgerID int64, excludeID int64, group exp.Expression) ([]float64, error) {
+	var amounts []float64
+	err := service.DB.From("transactions").
+		Select(goqu.L("-price")).
+		Where(
+			goqu.C("ledger_id").Eq(ledgerID),
+			goqu.C("price").Lt(0),
+			goqu.C("id").Neq(excludeID),
+			group,
+		).
+		ScanVals(&amounts)
+	return amounts, err
+}
+
+// largestDismissed is the highest amount in group the user marked as fine, which raises the bar for flagging.
+func (service *AnomalyService) largestDismissed(ledgerID int64, group exp.Expression) (float64, error) {
+	var largest float64
+	_, err := service.DB.From("transactions").
+		Join(goqu.T("transaction_anomalies"), goqu.On(goqu.I("transaction_anomalies.transaction_id").Eq(goqu.I("transactions.id")))).
+		Select(goqu.L("COALESCE(MAX(-price), 0)")).
+		Where(
+			goqu.I("transactions.ledger_id").Eq(ledgerID),
+			goqu.I("transaction_anomalies.status").Eq(StatusDismissed),
+			group,
+		).
+		ScanVal(&largest)
+	return largest, err
+}
+
+// Rescan scores every expense of the ledger again, e.g. after importing history.
+func (service *AnomalyService) Rescan(ledgerID int64) error {
+	var ids []int64
+	err := service.DB.From("transactions").
+		Select("id").
+		Where(goqu.C("ledger_id").Eq(ledgerID), goqu.C("price").Lt(0)).
+		ScanVals(&ids)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := service.ScoreTransaction(ledgerID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the ledger's anomalies with the given status whose transaction still exists, highest score first.
+func (service *AnomalyService) List(ledgerID int64, status Status) ([]FlaggedTransaction, error) {
+	flagged := []FlaggedTransaction{}
+	err := service.DB.From("transaction_anomalies").
+		Join(goqu.T("transactions"), goqu.On(goqu.I("transactions.id").Eq(goqu.I("transaction_anomalies.transaction_id")))).
+		Select(
+			"transaction_anomalies.transaction_id",
+			"transaction_anomalies.ledger_id",
+			"transaction_anomalies.score",
+			"transaction_anomalies.reason",
+			"transaction_anomalies.status",
+			"transaction_anomalies.date",
+			"transactions.name",
+			"transactions.price",
+			goqu.L("COALESCE(transactions.seller, '')").As("seller"),
+			"transactions.tags",
+			goqu.I("transactions.date").As("transaction_date"),
+		).
+		Where(goqu.Ex{
+			"transaction_anomalies.ledger_id": ledgerID,
+			"transactions.ledger_id":          ledgerID,
+			"transaction_anomalies.status":    status,
+		}).
+		Order(goqu.I("transaction_anomalies.score").Desc()).
+		ScanStructs(&flagged)
+	if err != nil {
+		return nil, err
+	}
+	return flagged, nil
+}
+
+// Dismiss marks a flagged transaction as fine. It stays in the table so that similar amounts are not flagged again.
+func (service *AnomalyService) Dismiss(ledgerID int64, transactionID int64) error {
+	result, err := service.DB.Update("transaction_anomalies").
+		Set(goqu.Record{"status": StatusDismissed}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "transaction_id": transactionID}).
+		Executor().Exec()
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// percentile returns the p-th percentile of values using linear interpolation. values is sorted in place.
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	position := p * float64(len(values)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return values[lower] + (values[upper]-values[lower])*(position-float64(lower))
+}
@@ -0,0 +1,55 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"checkout-go/auth"
+	"checkout-go/settings"
+)
+
+type BackupController struct {
+	BackupService   *BackupService
+	AuthService     auth.UserContextReader
+	SettingsContext settings.SettingsContextReader
+}
+
+// ExportBackup streams the whole account as newline delimited JSON.
+func (c *BackupController) ExportBackup(w http.ResponseWriter, req *http.Request) {
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"checkout-backup.ndjson\"")
+	w.WriteHeader(http.StatusOK)
+	if err := c.BackupService.Export(userID, w); err != nil {
+		fmt.Printf("could not write backup: %s\n", err)
+	}
+}
+
+// ImportBackup takes a backup as the request body. With ?dryRun=true it is checked without storing anything.
+func (c *BackupController) ImportBackup(w http.ResponseWriter, req *http.Request) {
+	dryRun := false
+	if dryRunStr := req.URL.Query().Get("dryRun"); dryRunStr != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(dryRunStr)
+		if err != nil {
+			http.Error(w, "Invalid dryRun", http.StatusBadRequest)
+			return
+		}
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	result, err := c.BackupService.Import(userID, req.Body, dryRun, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,123 @@
+package backup
+
+import (
+	"encoding/json"
+
+	"checkout-go/customtypes"
+	"checkout-go/transactions"
+)
+
+// SchemaVersion is written in the header of every backup. Imports accept backups up to this version, so it goes
+// up whenever a record gains fields an older server would drop.
+//
+//	2: the tax of transactions
+//	3: the reimbursement of transactions
+//	4: custom fields, defined per ledger before its transactions
+const SchemaVersion = 4
+
+type RecordType string
+
+const (
+	RecordHeader        RecordType = "header"
+	RecordSettings      RecordType = "settings"
+	RecordLedger        RecordType = "ledger"
+	RecordCustomField   RecordType = "customField"
+	RecordTransaction   RecordType = "transaction"
+	RecordMonthlyBudget RecordType = "monthlyBudget"
+	RecordTaggedBudget  RecordType = "taggedBudget"
+	RecordEnd           RecordType = "end"
+)
+
+// record is one line of a backup. Data holds one of the types below, depending on Type.
+type record struct {
+	Type RecordType `json:"type"`
+	Data any        `json:"data"`
+}
+
+// rawRecord is a line being imported, with its data decoded once its type is known.
+type rawRecord struct {
+	Type RecordType      `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type Header struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	ExportedAt    string `json:"exportedAt"`
+}
+
+type Settings struct {
+	Timezone       string `json:"timezone"`
+	PeriodStartDay int    `json:"periodStartDay"`
+	PeriodRule     string `json:"periodRule"`
+}
+
+// Ledger is a ledger the user owns. Its ID only links the records that follow it, imports create new ledgers.
+type Ledger struct {
+	ID       int64  `db:"id" json:"id"`
+	Name     string `db:"name" json:"name"`
+	Personal bool   `db:"personal" json:"personal"`
+	// The ledger's overrides of the user settings
+	Timezone       *string `db:"-" json:"timezone,omitempty"`
+	PeriodStartDay *int    `db:"-" json:"periodStartDay,omitempty"`
+	PeriodRule     *string `db:"-" json:"periodRule,omitempty"`
+}
+
+// CustomField is a custom field of a ledger. It comes before the ledger's transactions, whose values are
+// checked against it.
+type CustomField struct {
+	LedgerID int64    `json:"ledgerId"`
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required,omitempty"`
+	Options  []string `json:"options,omitempty"`
+}
+
+type Transaction struct {
+	LedgerID int64                   `json:"ledgerId"`
+	Name     string                  `json:"name"`
+	Price    float64                 `json:"price"`
+	Seller   string                  `json:"seller"`
+	Note     string                  `json:"note"`
+	Date     customtypes.TimeWrapper `json:"date"` // In the offset it was entered with
+	Tags     []string                `json:"tags"`
+	Status   transactions.Status     `json:"status"`
+	// Tax fields are left out for transactions without tax
+	Deductible bool     `json:"deductible,omitempty"`
+	TaxRate    *float64 `json:"taxRate,omitempty"`
+	TaxAmount  float64  `json:"taxAmount,omitempty"`
+	// Reimbursement fields are left out for expenses that are not paid back
+	Reimbursable        bool                             `json:"reimbursable,omitempty"`
+	ReimbursementStatus transactions.ReimbursementStatus `json:"reimbursementStatus,omitempty"`
+	// CustomFields holds the values of the ledger's custom fields
+	CustomFields customtypes.Fields `json:"customFields,omitempty"`
+}
+
+type MonthlyBudget struct {
+	LedgerID int64   `db:"ledger_id" json:"ledgerId"`
+	Name     string  `db:"name" json:"name"`
+	Value    float64 `db:"value" json:"value"`
+	Date     string  `db:"date" json:"date"`
+}
+
+type TaggedBudget struct {
+	LedgerID int64   `db:"ledger_id" json:"ledgerId"`
+	Name     string  `db:"name" json:"name"`
+	Value    float64 `db:"value" json:"value"`
+	Tag      string  `db:"tag" json:"tag"`
+	Date     string  `db:"date" json:"date"`
+}
+
+// End closes a backup, so a truncated file is not mistaken for a complete one.
+type End struct {
+	Records int64 `json:"records"`
+}
+
+type ImportResult struct {
+	DryRun         bool  `json:"dryRun"`
+	Settings       bool  `json:"settings"`
+	Ledgers        int   `json:"ledgers"`
+	CustomFields   int   `json:"customFields"`
+	Transactions   int64 `json:"transactions"`
+	MonthlyBudgets int   `json:"monthlyBudgets"`
+	TaggedBudgets  int   `json:"taggedBudgets"`
+}
@@ -0,0 +1,667 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"checkout-go/anomalies"
+	"checkout-go/customfields"
+	customfielddtos "checkout-go/customfields/dtos"
+	"checkout-go/customtypes"
+	"checkout-go/ledgers"
+	"checkout-go/settings"
+	settingsdtos "checkout-go/settings/dtos"
+	"checkout-go/transactions"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+// batchSize is how many transactions are inserted with one statement while importing.
+const batchSize = 500
+
+type BackupService struct {
+	DB                 *goqu.Database
+	LedgerService      *ledgers.LedgerService
+	SettingsService    *settings.SettingsService
+	AnomalyService     *anomalies.AnomalyService
+	CustomFieldService *customfields.CustomFieldService
+}
+
+// Export writes the user's settings and every ledger they own with its custom fields, transactions and budgets,
+// one JSON record per line. Rows are streamed from the database, so memory use does not grow with the ledgers.
+// Once writing started an error can only cut the backup short, which the missing end record gives away.
+func (service *BackupService) Export(userID int64, w io.Writer) error {
+	// Adopts rows from before ledgers existed, so they are part of the backup
+	if _, err := service.LedgerService.GetPersonalLedger(userID); err != nil {
+		return err
+	}
+	userSettings, err := service.SettingsService.Get(userID)
+	if err != nil {
+		return err
+	}
+	var owned []Ledger
+	err = service.DB.From("ledgers").
+		Select("id", "name", "personal").
+		Where(goqu.Ex{"owner_id": userID}).
+		Order(goqu.C("id").Asc()).
+		ScanStructs(&owned)
+	if err != nil {
+		return err
+	}
+
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	var count int64
+	write := func(recordType RecordType, data any) error {
+		count++
+		return encoder.Encode(record{Type: recordType, Data: data})
+	}
+	header := Header{SchemaVersion: SchemaVersion, ExportedAt: time.Now().Format(time.RFC3339)}
+	if err := encoder.Encode(record{Type: RecordHeader, Data: header}); err != nil {
+		return err
+	}
+	err = write(RecordSettings, Settings{
+		Timezone:       userSettings.Timezone,
+		PeriodStartDay: userSettings.PeriodStartDay,
+		PeriodRule:     userSettings.PeriodRule,
+	})
+	if err != nil {
+		return err
+	}
+	for _, ledger := range owned {
+		overrides, err := service.SettingsService.GetLedger(ledger.ID)
+		if err != nil {
+			return err
+		}
+		ledger.Timezone = overrides.Timezone
+		ledger.PeriodStartDay = overrides.PeriodStartDay
+		ledger.PeriodRule = overrides.PeriodRule
+		if err := write(RecordLedger, ledger); err != nil {
+			return err
+		}
+		if err := service.exportCustomFields(ledger.ID, write); err != nil {
+			return err
+		}
+		if err := service.exportTransactions(ledger.ID, write); err != nil {
+			return err
+		}
+		if err := service.exportBudgets(ledger.ID, write); err != nil {
+			return err
+		}
+	}
+	if err := encoder.Encode(record{Type: RecordEnd, Data: End{Records: count}}); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+func (service *BackupService) exportCustomFields(ledgerID int64, write func(RecordType, any) error) error {
+	fields, err := service.CustomFieldService.List(ledgerID)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		err := write(RecordCustomField, CustomField{
+			LedgerID: ledgerID,
+			Name:     field.Name,
+			Type:     string(field.Type),
+			Required: field.Required,
+			Options:  field.Options,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (service *BackupService) exportTransactions(ledgerID int64, write func(RecordType, any) error) error {
+	scanner, err := service.DB.From("transactions").
+		Select(&transactions.Transaction{}).
+		Where(goqu.C("ledger_id").Eq(ledgerID)).
+		Order(goqu.C("date").Asc(), goqu.C("id").Asc()).
+		Executor().Scanner()
+	if err != nil {
+		return err
+	}
+	defer scanner.Close()
+	for scanner.Next() {
+		var t transactions.Transaction
+		if err := scanner.ScanStruct(&t); err != nil {
+			return err
+		}
+		status := t.Status
+		// Reconciliations are not part of a backup, what they matched stays ticked off
+		if status == transactions.StatusReconciled {
+			status = transactions.StatusCleared
+		}
+		// Expense reports are not part of a backup either, submitted expenses are pending again
+		reimbursement := t.ReimbursementStatus
+		if reimbursement == transactions.ReimbursementSubmitted {
+			reimbursement = transactions.ReimbursementPending
+		}
+		tags := []string(t.Tags)
+		if tags == nil {
+			tags = []string{}
+		}
+		err := write(RecordTransaction, Transaction{
+			LedgerID:            ledgerID,
+			Name:                t.Name,
+			Price:               t.Price,
+			Seller:              t.Seller,
+			Note:                t.Note,
+			Date:                customtypes.TimeWrapper(t.Date.Time().In(time.FixedZone("", t.UTCOffset))),
+			Tags:                tags,
+			Status:              status,
+			Deductible:          t.Deductible,
+			TaxRate:             t.TaxRate,
+			TaxAmount:           t.TaxAmount,
+			Reimbursable:        t.Reimbursable,
+			ReimbursementStatus: reimbursement,
+			CustomFields:        t.CustomFields,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (service *BackupService) exportBudgets(ledgerID int64, write func(RecordType, any) error) error {
+	var monthly []MonthlyBudget
+	err := service.DB.From("monthly_budgets").
+		Select("ledger_id", "name", "value", "date").
+		Where(goqu.C("ledger_id").Eq(ledgerID)).
+		Order(goqu.C("id").Asc()).
+		ScanStructs(&monthly)
+	if err != nil {
+		return err
+	}
+	for _, budget := range monthly {
+		if err := write(RecordMonthlyBudget, budget); err != nil {
+			return err
+		}
+	}
+	var tagged []TaggedBudget
+	err = service.DB.From("tagged_budgets").
+		Select("ledger_id", "name", "value", "tag", "date").
+		Where(goqu.C("ledger_id").Eq(ledgerID)).
+		Order(goqu.C("id").Asc()).
+		ScanStructs(&tagged)
+	if err != nil {
+		return err
+	}
+	for _, budget := range tagged {
+		if err := write(RecordTaggedBudget, budget); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import restores a backup into the user's account in a single database transaction, so it is applied
+// completely or not at all; with dryRun every record is validated and written, then rolled back. The backup's
+// personal ledger is restored into the user's personal ledger if that is still empty, every other ledger
+// becomes a new ledger owned by the user. The backup is read line by line, so its size does not matter.
+func (service *BackupService) Import(userID int64, data io.Reader, dryRun bool, loc *time.Location) (*ImportResult, error) {
+	personal, err := service.LedgerService.GetPersonalLedger(userID)
+	if err != nil {
+		return nil, err
+	}
+	personalEmpty, err := service.isEmpty(personal.ID)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := service.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	imp := importer{
+		service:      service,
+		tx:           tx,
+		userID:       userID,
+		loc:          loc,
+		personalID:   personal.ID,
+		personalFree: personalEmpty,
+		ledgers:      map[int64]int64{},
+		fields:       map[int64][]customfields.Field{},
+		booked:       map[int64]bool{},
+		monthly:      map[int64]bool{},
+		result:       ImportResult{DryRun: dryRun},
+	}
+	if err := imp.run(data); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			fmt.Printf("could not roll back backup import: %v\n", rollbackErr)
+		}
+		return nil, err
+	}
+	if dryRun {
+		if err := tx.Rollback(); err != nil {
+			return nil, err
+		}
+		return &imp.result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if service.AnomalyService != nil {
+		// Scoring a restored account takes long, the import does not wait for it
+		go func(ledgerIDs map[int64]int64) {
+			for _, ledgerID := range ledgerIDs {
+				if err := service.AnomalyService.Rescan(ledgerID); err != nil {
+					fmt.Printf("could not score imported transactions: %v\n", err)
+				}
+			}
+		}(imp.ledgers)
+	}
+	return &imp.result, nil
+}
+
+// isEmpty reports whether a ledger has neither transactions, budgets nor custom fields.
+func (service *BackupService) isEmpty(ledgerID int64) (bool, error) {
+	for _, table := range []string{"transactions", "monthly_budgets", "tagged_budgets", "custom_fields"} {
+		count, err := service.DB.From(table).Where(goqu.C("ledger_id").Eq(ledgerID)).Count()
+		if err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// importer holds what is needed across the records of one import. Only ledger IDs are remembered, so memory
+// stays constant however many transactions there are.
+type importer struct {
+	service      *BackupService
+	tx           *goqu.TxDatabase
+	userID       int64
+	loc          *time.Location
+	personalID   int64
+	personalFree bool
+	// ledgers maps the IDs in the backup to the ledgers they were restored into
+	ledgers map[int64]int64
+	// fields holds the custom fields of the restored ledgers and booked which of them have transactions already
+	fields  map[int64][]customfields.Field
+	booked  map[int64]bool
+	monthly map[int64]bool
+	batch   []any
+	header  bool
+	ended   bool
+	records int64
+	result  ImportResult
+}
+
+func (imp *importer) run(data io.Reader) error {
+	scanner := bufio.NewScanner(data)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := imp.add(line); err != nil {
+			return fmt.Errorf("line %d: %w", number, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !imp.header {
+		return errors.New("backup is empty")
+	}
+	if !imp.ended {
+		return errors.New("backup is incomplete, it has no end record")
+	}
+	return nil
+}
+
+func (imp *importer) add(line []byte) error {
+	var r rawRecord
+	if err := json.Unmarshal(line, &r); err != nil {
+		return fmt.Errorf("invalid record: %w", err)
+	}
+	if imp.ended {
+		return errors.New("record after the end of the backup")
+	}
+	if !imp.header && r.Type != RecordHeader {
+		return errors.New("backup must start with a header")
+	}
+	switch r.Type {
+	case RecordHeader:
+		if imp.header {
+			return errors.New("backup has a second header")
+		}
+		var header Header
+		if err := decode(r.Data, &header); err != nil {
+			return err
+		}
+		if header.SchemaVersion < 1 || header.SchemaVersion > SchemaVersion {
+			return fmt.Errorf("schema version %d is not supported, the newest is %d", header.SchemaVersion, SchemaVersion)
+		}
+		imp.header = true
+		return nil
+	case RecordEnd:
+		var end End
+		if err := decode(r.Data, &end); err != nil {
+			return err
+		}
+		if end.Records != imp.records {
+			return fmt.Errorf("backup has %d records but its end record counts %d", imp.records, end.Records)
+		}
+		imp.ended = true
+		return imp.flush()
+	}
+	imp.records++
+	switch r.Type {
+	case RecordSettings:
+		var s Settings
+		if err := decode(r.Data, &s); err != nil {
+			return err
+		}
+		return imp.addSettings(s)
+	case RecordLedger:
+		var ledger Ledger
+		if err := decode(r.Data, &ledger); err != nil {
+			return err
+		}
+		return imp.addLedger(ledger)
+	case RecordCustomField:
+		var field CustomField
+		if err := decode(r.Data, &field); err != nil {
+			return err
+		}
+		return imp.addCustomField(field)
+	case RecordTransaction:
+		var t Transaction
+		if err := decode(r.Data, &t); err != nil {
+			return err
+		}
+		return imp.addTransaction(t)
+	case RecordMonthlyBudget:
+		var budget MonthlyBudget
+		if err := decode(r.Data, &budget); err != nil {
+			return err
+		}
+		return imp.addMonthlyBudget(budget)
+	case RecordTaggedBudget:
+		var budget TaggedBudget
+		if err := decode(r.Data, &budget); err != nil {
+			return err
+		}
+		return imp.addTaggedBudget(budget)
+	}
+	return fmt.Errorf("unknown record type %q", r.Type)
+}
+
+func decode(data json.RawMessage, target any) error {
+	if len(data) == 0 {
+		return errors.New("record has no data")
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("invalid record: %w", err)
+	}
+	return nil
+}
+
+func (imp *importer) addSettings(s Settings) error {
+	err := settings.Validate(settingsdtos.UpdateSettingsDTO{
+		Timezone:       &s.Timezone,
+		PeriodStartDay: &s.PeriodStartDay,
+		PeriodRule:     &s.PeriodRule,
+	})
+	if err != nil {
+		return err
+	}
+	restored := settings.Settings{
+		UserID:         imp.userID,
+		Timezone:       s.Timezone,
+		PeriodStartDay: s.PeriodStartDay,
+		PeriodRule:     s.PeriodRule,
+	}
+	_, err = imp.tx.Insert("user_settings").
+		Rows(restored).
+		OnConflict(goqu.DoUpdate("user_id", restored)).
+		Executor().Exec()
+	if err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	imp.result.Settings = true
+	return nil
+}
+
+func (imp *importer) addLedger(ledger Ledger) error {
+	if ledger.ID <= 0 {
+		return errors.New("ledger id must be positive")
+	}
+	if _, ok := imp.ledgers[ledger.ID]; ok {
+		return fmt.Errorf("ledger %d appears twice", ledger.ID)
+	}
+	if ledger.Personal && imp.personalFree {
+		imp.personalFree = false
+		imp.ledgers[ledger.ID] = imp.personalID
+	} else {
+		created, err := imp.service.LedgerService.CreateInTx(imp.tx, imp.userID, ledger.Name)
+		if err != nil {
+			return err
+		}
+		imp.ledgers[ledger.ID] = created.ID
+	}
+	imp.result.Ledgers++
+	if ledger.Timezone == nil && ledger.PeriodStartDay == nil && ledger.PeriodRule == nil {
+		return nil
+	}
+	err := settings.Validate(settingsdtos.UpdateSettingsDTO{
+		Timezone:       ledger.Timezone,
+		PeriodStartDay: ledger.PeriodStartDay,
+		PeriodRule:     ledger.PeriodRule,
+	})
+	if err != nil {
+		return fmt.Errorf("ledger %d: %w", ledger.ID, err)
+	}
+	overrides := settings.LedgerSettings{
+		LedgerID:       imp.ledgers[ledger.ID],
+		Timezone:       ledger.Timezone,
+		PeriodStartDay: ledger.PeriodStartDay,
+		PeriodRule:     ledger.PeriodRule,
+	}
+	_, err = imp.tx.Insert("ledger_settings").
+		Rows(overrides).
+		OnConflict(goqu.DoUpdate("ledger_id", overrides)).
+		Executor().Exec()
+	return err
+}
+
+func (imp *importer) ledgerID(backupID int64) (int64, error) {
+	ledgerID, ok := imp.ledgers[backupID]
+	if !ok {
+		return 0, fmt.Errorf("ledger %d is not defined before its records", backupID)
+	}
+	return ledgerID, nil
+}
+
+func (imp *importer) addCustomField(field CustomField) error {
+	ledgerID, err := imp.ledgerID(field.LedgerID)
+	if err != nil {
+		return err
+	}
+	if imp.booked[ledgerID] {
+		return fmt.Errorf("custom field %q comes after transactions of ledger %d", field.Name, field.LedgerID)
+	}
+	created, err := imp.service.CustomFieldService.CreateInTx(imp.tx, imp.userID, ledgerID, customfielddtos.CreateFieldDTO{
+		Name:     field.Name,
+		Type:     field.Type,
+		Required: field.Required,
+		Options:  field.Options,
+	})
+	if err != nil {
+		return err
+	}
+	imp.fields[ledgerID] = append(imp.fields[ledgerID], *created)
+	imp.result.CustomFields++
+	return nil
+}
+
+func (imp *importer) addTransaction(t Transaction) error {
+	ledgerID, err := imp.ledgerID(t.LedgerID)
+	if err != nil {
+		return err
+	}
+	if t.Date.Time().IsZero() {
+		return errors.New("transaction has no date")
+	}
+	if math.IsInf(t.Price, 0) || math.IsNaN(t.Price) {
+		return errors.New("invalid price")
+	}
+	switch t.Status {
+	case "":
+		t.Status = transactions.StatusPending
+	case transactions.StatusPending, transactions.StatusCleared:
+	case transactions.StatusReconciled:
+		t.Status = transactions.StatusCleared
+	default:
+		return fmt.Errorf("invalid status %q", t.Status)
+	}
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	if t.TaxRate != nil && (*t.TaxRate < 0 || *t.TaxRate > 100) {
+		return errors.New("tax rate must be between 0 and 100")
+	}
+	if t.TaxAmount < 0 {
+		return errors.New("tax amount cannot be negative")
+	}
+	switch t.ReimbursementStatus {
+	case "", transactions.ReimbursementPending, transactions.ReimbursementReimbursed:
+	case transactions.ReimbursementSubmitted:
+		t.ReimbursementStatus = transactions.ReimbursementPending
+	default:
+		return fmt.Errorf("invalid reimbursement status %q", t.ReimbursementStatus)
+	}
+	// Required fields are not enforced, the transaction may be older than the field
+	values, err := customfields.Validate(imp.fields[ledgerID], t.CustomFields, false)
+	if err != nil {
+		return err
+	}
+	for name, value := range values {
+		if value == nil {
+			delete(values, name)
+		}
+	}
+	imp.booked[ledgerID] = true
+	date := customtypes.InLocation(t.Date.Time(), imp.loc)
+	_, utcOffset := date.Zone()
+	imp.batch = append(imp.batch, goqu.Record{
+		"user_id":              imp.userID,
+		"ledger_id":            ledgerID,
+		"name":                 t.Name,
+		"price":                t.Price,
+		"date":                 date,
+		"utc_offset":           utcOffset,
+		"seller":               t.Seller,
+		"note":                 t.Note,
+		"tags":                 customtypes.StringSlice(t.Tags),
+		"status":               t.Status,
+		"deductible":           t.Deductible,
+		"tax_rate":             t.TaxRate,
+		"tax_amount":           t.TaxAmount,
+		"reimbursable":         t.Reimbursable,
+		"reimbursement_status": t.ReimbursementStatus,
+		"custom_fields":        values,
+	})
+	imp.result.Transactions++
+	if len(imp.batch) >= batchSize {
+		return imp.flush()
+	}
+	return nil
+}
+
+// flush inserts the transactions collected so far.
+func (imp *importer) flush() error {
+	if len(imp.batch) == 0 {
+		return nil
+	}
+	_, err := imp.tx.Insert("transactions").Rows(imp.batch...).Executor().Exec()
+	if err != nil {
+		return fmt.Errorf("err in inserting transactions: %w", err)
+	}
+	imp.batch = imp.batch[:0]
+	return nil
+}
+
+func budgetDate(date string) (string, error) {
+	if date == "" {
+		return time.Now().Format(time.RFC3339), nil
+	}
+	if _, _, err := customtypes.ParseStoredTime(date); err != nil {
+		return "", fmt.Errorf("invalid date %q", date)
+	}
+	return date, nil
+}
+
+func (imp *importer) addMonthlyBudget(budget MonthlyBudget) error {
+	ledgerID, err := imp.ledgerID(budget.LedgerID)
+	if err != nil {
+		return err
+	}
+	if imp.monthly[ledgerID] {
+		return fmt.Errorf("ledger %d has a second monthly budget", budget.LedgerID)
+	}
+	if budget.Name == "" {
+		return errors.New("budget name cannot be empty")
+	}
+	date, err := budgetDate(budget.Date)
+	if err != nil {
+		return err
+	}
+	_, err = imp.tx.Insert("monthly_budgets").Rows(goqu.Record{
+		"user_id":   imp.userID,
+		"ledger_id": ledgerID,
+		"name":      budget.Name,
+		"value":     budget.Value,
+		"date":      date,
+	}).Executor().Exec()
+	if err != nil {
+		return fmt.Errorf("err in inserting monthly budget: %w", err)
+	}
+	imp.monthly[ledgerID] = true
+	imp.result.MonthlyBudgets++
+	return nil
+}
+
+func (imp *importer) addTaggedBudget(budget TaggedBudget) error {
+	ledgerID, err := imp.ledgerID(budget.LedgerID)
+	if err != nil {
+		return err
+	}
+	if budget.Name == "" {
+		return errors.New("budget name cannot be empty")
+	}
+	if budget.Tag == "" {
+		return errors.New("tagged budget needs a tag")
+	}
+	date, err := budgetDate(budget.Date)
+	if err != nil {
+		return err
+	}
+	_, err = imp.tx.Insert("tagged_budgets").Rows(goqu.Record{
+		"user_id":   imp.userID,
+		"ledger_id": ledgerID,
+		"name":      budget.Name,
+		"value":     budget.Value,
+		"tag":       budget.Tag,
+		"date":      date,
+	}).Executor().Exec()
+	if err != nil {
+		return fmt.Errorf("err in inserting tagged budget: %w", err)
+	}
+	imp.result.TaggedBudgets++
+	return nil
+}
@@ -9,13 +9,17 @@ import (
 
 	"checkout-go/auth"
 	dto "checkout-go/budgets/dtos"
+	"checkout-go/ledgers"
+	"checkout-go/settings"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type BudgetsController struct {
-	BudgetService BudgetService
-	AuthService   auth.UserContextReader
+	BudgetService   BudgetService
+	AuthService     auth.UserContextReader
+	LedgerContext   ledgers.LedgerContextReader
+	SettingsContext settings.SettingsContextReader
 }
 
 func (c *BudgetsController) CreateMonthlyBudget(w http.ResponseWriter, req *http.Request) {
@@ -33,7 +37,8 @@ func (c *BudgetsController) CreateMonthlyBudget(w http.ResponseWriter, req *http
 	}
 
 	userID := c.AuthService.GetUserIDFromRequest(req)
-	monthlyBudget, err := c.BudgetService.CreateMonthylBudget(userID, budget.Name, budget.Value)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	monthlyBudget, err := c.BudgetService.CreateMonthylBudget(userID, ledgerID, budget.Name, budget.Value)
 	if err != nil {
 		fmt.Printf("err: %v\n", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -49,8 +54,8 @@ func (c *BudgetsController) CreateMonthlyBudget(w http.ResponseWriter, req *http
 }
 
 func (c *BudgetsController) GetMonthlyBudget(w http.ResponseWriter, req *http.Request) {
-	userID := c.AuthService.GetUserIDFromRequest(req)
-	monthlyBudget, err := c.BudgetService.GetMonthylBudget(userID)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	monthlyBudget, err := c.BudgetService.GetMonthylBudget(ledgerID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -78,8 +83,8 @@ func (c *BudgetsController) UpdateMonthlyBudget(w http.ResponseWriter, req *http
 		return
 	}
 
-	userID := c.AuthService.GetUserIDFromRequest(req)
-	monthlyBudget, err := c.BudgetService.UpdateMonthylBudget(userID, budget.Name, budget.Value)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	monthlyBudget, err := c.BudgetService.UpdateMonthylBudget(ledgerID, budget.Name, budget.Value)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -94,8 +99,8 @@ func (c *BudgetsController) UpdateMonthlyBudget(w http.ResponseWriter, req *http
 }
 
 func (c *BudgetsController) DeleteMonthlyBudget(w http.ResponseWriter, req *http.Request) {
-	userID := c.AuthService.GetUserIDFromRequest(req)
-	monthlyBudget, err := c.BudgetService.DeleteMonthlyBudget(userID)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	monthlyBudget, err := c.BudgetService.DeleteMonthlyBudget(ledgerID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -124,7 +129,8 @@ func (c *BudgetsController) CreateTaggedBudget(w http.ResponseWriter, req *http.
 	}
 
 	userID := c.AuthService.GetUserIDFromRequest(req)
-	monthlyBudget, err := c.BudgetService.CreateTaggedBudget(userID, budget.Name, budget.Value, budget.Tag)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	monthlyBudget, err := c.BudgetService.CreateTaggedBudget(userID, ledgerID, budget.Name, budget.Value, budget.Tag)
 	if err != nil {
 		fmt.Printf("err: %v\n", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -140,8 +146,8 @@ func (c *BudgetsController) CreateTaggedBudget(w http.ResponseWriter, req *http.
 }
 
 func (c *BudgetsController) GetTaggedBudgets(w http.ResponseWriter, req *http.Request) {
-	userID := c.AuthService.GetUserIDFromRequest(req)
-	budgets, err := c.BudgetService.GetTaggedBudgets(userID)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	budgets, err := c.BudgetService.GetTaggedBudgets(ledgerID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -161,8 +167,8 @@ func (c *BudgetsController) DeleteTaggedBudget(w http.ResponseWriter, req *http.
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
-	userID := c.AuthService.GetUserIDFromRequest(req)
-	transaction, err := c.BudgetService.DeleteTaggedBudget(userID, int64(id))
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	transaction, err := c.BudgetService.DeleteTaggedBudget(ledgerID, int64(id))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -177,8 +183,9 @@ func (c *BudgetsController) DeleteTaggedBudget(w http.ResponseWriter, req *http.
 }
 
 func (c *BudgetsController) GetTaggedBudgetStats(w http.ResponseWriter, req *http.Request) {
-	userID := c.AuthService.GetUserIDFromRequest(req)
-	budgetStats, err := c.BudgetService.GetTaggedBudgetsStats(userID)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	userSettings := c.SettingsContext.GetSettingsFromRequest(req)
+	budgetStats, err := c.BudgetService.GetTaggedBudgetsStats(ledgerID, userSettings)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -210,8 +217,8 @@ func (c *BudgetsController) UpdateTaggedBudget(w http.ResponseWriter, req *http.
 		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
 		return
 	}
-	userID := c.AuthService.GetUserIDFromRequest(req)
-	updatedBudget, err := c.BudgetService.UpdateTaggedBudget(userID, int64(id), budget.Name, budget.Value, budget.Tag)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	updatedBudget, err := c.BudgetService.UpdateTaggedBudget(ledgerID, int64(id), budget.Name, budget.Value, budget.Tag)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -1,9 +1,12 @@
 package budgets
 
 type GetTaggedBudgetStatsDTO struct {
-	ID         int64   `json:"id"`
-	Name       string  `json:"name"`
-	Value      float64 `json:"value"`
-	Tag        string  `json:"tag"`
-	TotalPrice float64 `json:"totalPrice"`
+	ID          int64   `json:"id"`
+	Name        string  `json:"name"`
+	Value       float64 `json:"value"`
+	Tag         string  `json:"tag"`
+	TotalPrice  float64 `json:"totalPrice"`
+	Period      string  `json:"period"`
+	PeriodStart string  `json:"periodStart"`
+	PeriodEnd   string  `json:"periodEnd"`
 }
@@ -9,29 +9,43 @@ import (
 )
 
 type MonthlyBudget struct {
-	ID     int64   `json:"id"`
-	UserID int64   `json:"userId"`
-	Name   string  `json:"name"`
-	Value  float64 `json:"value"`
-	Date   string  `json:"date"`
+	ID       int64   `json:"id"`
+	UserID   int64   `json:"userId"`
+	Name     string  `json:"name"`
+	Value    float64 `json:"value"`
+	Date     string  `json:"date"`
+	LedgerID int64   `json:"ledgerId"`
 }
 
 type TaggedBudget struct {
-	ID     int64   `json:"id"`
-	UserID int64   `json:"userId"`
-	Name   string  `json:"name"`
-	Value  float64 `json:"value"`
-	Tag    string  `json:"tag"`
-	Date   string  `json:"date"`
+	ID       int64   `json:"id"`
+	UserID   int64   `json:"userId"`
+	Name     string  `json:"name"`
+	Value    float64 `json:"value"`
+	Tag      string  `json:"tag"`
+	Date     string  `json:"date"`
+	LedgerID int64   `json:"ledgerId"`
 }
 
 type Transaction struct {
-	ID     int64          `json:"id"`
-	UserID int64          `json:"userId"`
-	Name   string         `json:"name"`
-	Price  float64        `json:"price"`
-	Date   string         `json:"date"`
-	Tags   interface{}    `json:"tags"`
-	Seller sql.NullString `json:"seller"`
-	Note   sql.NullString `json:"note"`
+	ID                  int64           `json:"id"`
+	UserID              int64           `json:"userId"`
+	Name                string          `json:"name"`
+	Price               float64         `json:"price"`
+	Date                string          `json:"date"`
+	Tags                interface{}     `json:"tags"`
+	Seller              sql.NullString  `json:"seller"`
+	Note                sql.NullString  `json:"note"`
+	LedgerID            int64           `json:"ledgerId"`
+	UtcOffset           int64           `json:"utcOffset"`
+	Status              string          `json:"status"`
+	ReconciliationID    sql.NullInt64   `json:"reconciliationId"`
+	Deductible          int64           `json:"deductible"`
+	TaxRate             sql.NullFloat64 `json:"taxRate"`
+	TaxAmount           float64         `json:"taxAmount"`
+	Reimbursable        int64           `json:"reimbursable"`
+	ReimbursementStatus string          `json:"reimbursementStatus"`
+	ExpenseReportID     sql.NullInt64   `json:"expenseReportId"`
+	ProjectID           sql.NullInt64   `json:"projectId"`
+	CustomFields        string          `json:"customFields"`
 }
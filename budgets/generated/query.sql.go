@@ -12,23 +12,25 @@ import (
 
 const createMonthlyBudget = `-- name: CreateMonthlyBudget :one
 INSERT INTO monthly_budgets (
-  user_id, name, value, date
+  user_id, ledger_id, name, value, date
 ) VALUES (
-  ?, ?, ?, ?
+  ?, ?, ?, ?, ?
 )
-RETURNING id, user_id, name, value, date
+RETURNING id, user_id, name, value, date, ledger_id
 `
 
 type CreateMonthlyBudgetParams struct {
-	UserID int64   `json:"userId"`
-	Name   string  `json:"name"`
-	Value  float64 `json:"value"`
-	Date   string  `json:"date"`
+	UserID   int64   `json:"userId"`
+	LedgerID int64   `json:"ledgerId"`
+	Name     string  `json:"name"`
+	Value    float64 `json:"value"`
+	Date     string  `json:"date"`
 }
 
 func (q *Queries) CreateMonthlyBudget(ctx context.Context, arg CreateMonthlyBudgetParams) (MonthlyBudget, error) {
 	row := q.db.QueryRowContext(ctx, createMonthlyBudget,
 		arg.UserID,
+		arg.LedgerID,
 		arg.Name,
 		arg.Value,
 		arg.Date,
@@ -40,30 +42,33 @@ func (q *Queries) CreateMonthlyBudget(ctx context.Context, arg CreateMonthlyBudg
 		&i.Name,
 		&i.Value,
 		&i.Date,
+		&i.LedgerID,
 	)
 	return i, err
 }
 
 const createTaggedBudget = `-- name: CreateTaggedBudget :one
 INSERT INTO tagged_budgets (
-  user_id, name, value, tag, date
+  user_id, ledger_id, name, value, tag, date
 ) VALUES (
-  ?, ?, ?, ?, ?
+  ?, ?, ?, ?, ?, ?
 )
-RETURNING id, user_id, name, value, tag, date
+RETURNING id, user_id, name, value, tag, date, ledger_id
 `
 
 type CreateTaggedBudgetParams struct {
-	UserID int64   `json:"userId"`
-	Name   string  `json:"name"`
-	Value  float64 `json:"value"`
-	Tag    string  `json:"tag"`
-	Date   string  `json:"date"`
+	UserID   int64   `json:"userId"`
+	LedgerID int64   `json:"ledgerId"`
+	Name     string  `json:"name"`
+	Value    float64 `json:"value"`
+	Tag      string  `json:"tag"`
+	Date     string  `json:"date"`
 }
 
 func (q *Queries) CreateTaggedBudget(ctx context.Context, arg CreateTaggedBudgetParams) (TaggedBudget, error) {
 	row := q.db.QueryRowContext(ctx, createTaggedBudget,
 		arg.UserID,
+		arg.LedgerID,
 		arg.Name,
 		arg.Value,
 		arg.Tag,
@@ -77,40 +82,41 @@ func (q *Queries) CreateTaggedBudget(ctx context.Context, arg CreateTaggedBudget
 		&i.Value,
 		&i.Tag,
 		&i.Date,
+		&i.LedgerID,
 	)
 	return i, err
 }
 
 const deleteMonthlyBudget = `-- name: DeleteMonthlyBudget :exec
-DELETE FROM monthly_budgets WHERE user_id = ?
+DELETE FROM monthly_budgets WHERE ledger_id = ?
 `
 
-func (q *Queries) DeleteMonthlyBudget(ctx context.Context, userID int64) error {
-	_, err := q.db.ExecContext(ctx, deleteMonthlyBudget, userID)
+func (q *Queries) DeleteMonthlyBudget(ctx context.Context, ledgerID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteMonthlyBudget, ledgerID)
 	return err
 }
 
 const deleteTaggedBudget = `-- name: DeleteTaggedBudget :exec
-DELETE FROM tagged_budgets WHERE user_id = ? AND id = ?
+DELETE FROM tagged_budgets WHERE ledger_id = ? AND id = ?
 `
 
 type DeleteTaggedBudgetParams struct {
-	UserID int64 `json:"userId"`
-	ID     int64 `json:"id"`
+	LedgerID int64 `json:"ledgerId"`
+	ID       int64 `json:"id"`
 }
 
 func (q *Queries) DeleteTaggedBudget(ctx context.Context, arg DeleteTaggedBudgetParams) error {
-	_, err := q.db.ExecContext(ctx, deleteTaggedBudget, arg.UserID, arg.ID)
+	_, err := q.db.ExecContext(ctx, deleteTaggedBudget, arg.LedgerID, arg.ID)
 	return err
 }
 
 const getMonthlyBudget = `-- name: GetMonthlyBudget :one
-SELECT id, user_id, name, value, date FROM monthly_budgets
-WHERE user_id = ? LIMIT 1
+SELECT id, user_id, name, value, date, ledger_id FROM monthly_budgets
+WHERE ledger_id = ? LIMIT 1
 `
 
-func (q *Queries) GetMonthlyBudget(ctx context.Context, userID int64) (MonthlyBudget, error) {
-	row := q.db.QueryRowContext(ctx, getMonthlyBudget, userID)
+func (q *Queries) GetMonthlyBudget(ctx context.Context, ledgerID int64) (MonthlyBudget, error) {
+	row := q.db.QueryRowContext(ctx, getMonthlyBudget, ledgerID)
 	var i MonthlyBudget
 	err := row.Scan(
 		&i.ID,
@@ -118,22 +124,23 @@ func (q *Queries) GetMonthlyBudget(ctx context.Context, userID int64) (MonthlyBu
 		&i.Name,
 		&i.Value,
 		&i.Date,
+		&i.LedgerID,
 	)
 	return i, err
 }
 
 const getTaggedBudget = `-- name: GetTaggedBudget :one
-SELECT id, user_id, name, value, tag, date FROM tagged_budgets
-WHERE user_id = ? AND id = ?
+SELECT id, user_id, name, value, tag, date, ledger_id FROM tagged_budgets
+WHERE ledger_id = ? AND id = ?
 `
 
 type GetTaggedBudgetParams struct {
-	UserID int64 `json:"userId"`
-	ID     int64 `json:"id"`
+	LedgerID int64 `json:"ledgerId"`
+	ID       int64 `json:"id"`
 }
 
 func (q *Queries) GetTaggedBudget(ctx context.Context, arg GetTaggedBudgetParams) (TaggedBudget, error) {
-	row := q.db.QueryRowContext(ctx, getTaggedBudget, arg.UserID, arg.ID)
+	row := q.db.QueryRowContext(ctx, getTaggedBudget, arg.LedgerID, arg.ID)
 	var i TaggedBudget
 	err := row.Scan(
 		&i.ID,
@@ -142,6 +149,7 @@ func (q *Queries) GetTaggedBudget(ctx context.Context, arg GetTaggedBudgetParams
 		&i.Value,
 		&i.Tag,
 		&i.Date,
+		&i.LedgerID,
 	)
 	return i, err
 }
@@ -160,17 +168,20 @@ LEFT JOIN transactions t
         FROM json_each(t.tags)
         WHERE json_each.value = b.tag
     )
-    AND t.user_id = ?
+    AND t.ledger_id = ?
     AND t.price < 0
-    AND strftime('%Y-%m', t.date) >= strftime('%Y-%m', date('now'))
-    AND strftime('%Y-%m', t.date) < strftime('%Y-%m', date('now', 'start of month', '+1 month'))
-WHERE b.user_id = ?
+    AND t.reimbursement_status != 'reimbursed'
+    AND period_label(t.date, CAST(? AS TEXT), CAST(? AS INTEGER), CAST(? AS TEXT)) = CAST(? AS TEXT)
+WHERE b.ledger_id = ?
 GROUP BY b.id, b.name, b.value
 `
 
 type GetTaggedBudgetStatsParams struct {
-	UserID   int64 `json:"userId"`
-	UserID_2 int64 `json:"userId2"`
+	LedgerID       int64  `json:"ledgerId"`
+	Zone           string `json:"zone"`
+	PeriodStartDay int64  `json:"periodStartDay"`
+	PeriodRule     string `json:"periodRule"`
+	Period         string `json:"period"`
 }
 
 type GetTaggedBudgetStatsRow struct {
@@ -182,7 +193,14 @@ type GetTaggedBudgetStatsRow struct {
 }
 
 func (q *Queries) GetTaggedBudgetStats(ctx context.Context, arg GetTaggedBudgetStatsParams) ([]GetTaggedBudgetStatsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getTaggedBudgetStats, arg.UserID, arg.UserID_2)
+	rows, err := q.db.QueryContext(ctx, getTaggedBudgetStats,
+		arg.LedgerID,
+		arg.Zone,
+		arg.PeriodStartDay,
+		arg.PeriodRule,
+		arg.Period,
+		arg.LedgerID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -211,12 +229,12 @@ func (q *Queries) GetTaggedBudgetStats(ctx context.Context, arg GetTaggedBudgetS
 }
 
 const getTaggedBudgets = `-- name: GetTaggedBudgets :many
-SELECT id, user_id, name, value, tag, date FROM tagged_budgets
-WHERE user_id = ?
+SELECT id, user_id, name, value, tag, date, ledger_id FROM tagged_budgets
+WHERE ledger_id = ?
 `
 
-func (q *Queries) GetTaggedBudgets(ctx context.Context, userID int64) ([]TaggedBudget, error) {
-	rows, err := q.db.QueryContext(ctx, getTaggedBudgets, userID)
+func (q *Queries) GetTaggedBudgets(ctx context.Context, ledgerID int64) ([]TaggedBudget, error) {
+	rows, err := q.db.QueryContext(ctx, getTaggedBudgets, ledgerID)
 	if err != nil {
 		return nil, err
 	}
@@ -231,6 +249,7 @@ func (q *Queries) GetTaggedBudgets(ctx context.Context, userID int64) ([]TaggedB
 			&i.Value,
 			&i.Tag,
 			&i.Date,
+			&i.LedgerID,
 		); err != nil {
 			return nil, err
 		}
@@ -248,34 +267,34 @@ func (q *Queries) GetTaggedBudgets(ctx context.Context, userID int64) ([]TaggedB
 const updateMonthlyBudget = `-- name: UpdateMonthlyBudget :exec
 UPDATE monthly_budgets
 SET name = ?, value = ?
-WHERE user_id = ?
-RETURNING id, user_id, name, value, date
+WHERE ledger_id = ?
+RETURNING id, user_id, name, value, date, ledger_id
 `
 
 type UpdateMonthlyBudgetParams struct {
-	Name   string  `json:"name"`
-	Value  float64 `json:"value"`
-	UserID int64   `json:"userId"`
+	Name     string  `json:"name"`
+	Value    float64 `json:"value"`
+	LedgerID int64   `json:"ledgerId"`
 }
 
 func (q *Queries) UpdateMonthlyBudget(ctx context.Context, arg UpdateMonthlyBudgetParams) error {
-	_, err := q.db.ExecContext(ctx, updateMonthlyBudget, arg.Name, arg.Value, arg.UserID)
+	_, err := q.db.ExecContext(ctx, updateMonthlyBudget, arg.Name, arg.Value, arg.LedgerID)
 	return err
 }
 
 const updateTaggedBudget = `-- name: UpdateTaggedBudget :exec
 UPDATE tagged_budgets
 SET name = ?, value = ?, tag = ?
-WHERE user_id = ? and id = ?
-RETURNING id, user_id, name, value, tag, date
+WHERE ledger_id = ? and id = ?
+RETURNING id, user_id, name, value, tag, date, ledger_id
 `
 
 type UpdateTaggedBudgetParams struct {
-	Name   string  `json:"name"`
-	Value  float64 `json:"value"`
-	Tag    string  `json:"tag"`
-	UserID int64   `json:"userId"`
-	ID     int64   `json:"id"`
+	Name     string  `json:"name"`
+	Value    float64 `json:"value"`
+	Tag      string  `json:"tag"`
+	LedgerID int64   `json:"ledgerId"`
+	ID       int64   `json:"id"`
 }
 
 func (q *Queries) UpdateTaggedBudget(ctx context.Context, arg UpdateTaggedBudgetParams) error {
@@ -283,7 +302,7 @@ func (q *Queries) UpdateTaggedBudget(ctx context.Context, arg UpdateTaggedBudget
 		arg.Name,
 		arg.Value,
 		arg.Tag,
-		arg.UserID,
+		arg.LedgerID,
 		arg.ID,
 	)
 	return err
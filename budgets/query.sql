@@ -1,13 +1,13 @@
 -- name: GetMonthlyBudget :one
 SELECT * FROM monthly_budgets
-WHERE user_id = ? LIMIT 1;
+WHERE ledger_id = ? LIMIT 1;
 
 
 -- name: CreateMonthlyBudget :one
 INSERT INTO monthly_budgets (
-  user_id, name, value, date
+  user_id, ledger_id, name, value, date
 ) VALUES (
-  ?, ?, ?, ?
+  ?, ?, ?, ?, ?
 )
 RETURNING *;
 
@@ -16,42 +16,42 @@ RETURNING *;
 -- name: UpdateMonthlyBudget :exec
 UPDATE monthly_budgets
 SET name = ?, value = ?
-WHERE user_id = ?
+WHERE ledger_id = ?
 RETURNING *;
 
 
 -- name: DeleteMonthlyBudget :exec
-DELETE FROM monthly_budgets WHERE user_id = ?;
+DELETE FROM monthly_budgets WHERE ledger_id = ?;
 
 
 
 -- name: CreateTaggedBudget :one
 INSERT INTO tagged_budgets (
-  user_id, name, value, tag, date
+  user_id, ledger_id, name, value, tag, date
 ) VALUES (
-  ?, ?, ?, ?, ?
+  ?, ?, ?, ?, ?, ?
 )
 RETURNING *;
 
 -- name: GetTaggedBudgets :many
 SELECT * FROM tagged_budgets
-WHERE user_id = ?;
+WHERE ledger_id = ?;
 
 -- name: GetTaggedBudget :one
 SELECT * FROM tagged_budgets
-WHERE user_id = ? AND id = ?;
+WHERE ledger_id = ? AND id = ?;
 
 
 
 -- name: UpdateTaggedBudget :exec
 UPDATE tagged_budgets
 SET name = ?, value = ?, tag = ?
-WHERE user_id = ? and id = ?
+WHERE ledger_id = ? and id = ?
 RETURNING *;
 
 
 -- name: DeleteTaggedBudget :exec
-DELETE FROM tagged_budgets WHERE user_id = ? AND id = ?;
+DELETE FROM tagged_budgets WHERE ledger_id = ? AND id = ?;
 
 -- name: GetTaggedBudgetStats :many
 SELECT 
@@ -67,9 +67,9 @@ LEFT JOIN transactions t
         FROM json_each(t.tags)
         WHERE json_each.value = b.tag
     )
-    AND t.user_id = ?
+    AND t.ledger_id = sqlc.arg(ledger_id)
     AND t.price < 0
-    AND strftime('%Y-%m', t.date) >= strftime('%Y-%m', date('now'))
-    AND strftime('%Y-%m', t.date) < strftime('%Y-%m', date('now', 'start of month', '+1 month'))
-WHERE b.user_id = ?
+    AND t.reimbursement_status != 'reimbursed'
+    AND period_label(t.date, CAST(sqlc.arg(zone) AS TEXT), CAST(sqlc.arg(period_start_day) AS INTEGER), CAST(sqlc.arg(period_rule) AS TEXT)) = CAST(sqlc.arg(period) AS TEXT)
+WHERE b.ledger_id = sqlc.arg(ledger_id)
 GROUP BY b.id, b.name, b.value;
@@ -9,6 +9,7 @@ import (
 
 	dtos "checkout-go/budgets/dtos"
 	queries "checkout-go/budgets/generated"
+	"checkout-go/settings"
 
 	goqu "github.com/doug-martin/goqu/v9"
 )
@@ -17,13 +18,14 @@ type BudgetService struct {
 	DB *goqu.Database
 }
 
-func (service *BudgetService) CreateMonthylBudget(userID int64, name string, value float64) (*queries.MonthlyBudget, error) {
+func (service *BudgetService) CreateMonthylBudget(userID int64, ledgerID int64, name string, value float64) (*queries.MonthlyBudget, error) {
 	q := queries.New(service.DB)
 	params := queries.CreateMonthlyBudgetParams{
-		UserID: userID,
-		Name:   name,
-		Value:  value,
-		Date:   time.Now().Format(time.RFC3339),
+		UserID:   userID,
+		LedgerID: ledgerID,
+		Name:     name,
+		Value:    value,
+		Date:     time.Now().Format(time.RFC3339),
 	}
 	monthylBudget, err := q.CreateMonthlyBudget(context.Background(), params)
 	if err != nil {
@@ -32,9 +34,9 @@ func (service *BudgetService) CreateMonthylBudget(userID int64, name string, val
 	return &monthylBudget, nil
 }
 
-func (service *BudgetService) GetMonthylBudget(userID int64) (*queries.MonthlyBudget, error) {
+func (service *BudgetService) GetMonthylBudget(ledgerID int64) (*queries.MonthlyBudget, error) {
 	q := queries.New(service.DB)
-	monthylBudget, err := q.GetMonthlyBudget(context.Background(), userID)
+	monthylBudget, err := q.GetMonthlyBudget(context.Background(), ledgerID)
 	if err != nil {
 		if err.Error() == "sql: no rows in result set" {
 			return nil, nil
@@ -44,48 +46,49 @@ func (service *BudgetService) GetMonthylBudget(userID int64) (*queries.MonthlyBu
 	return &monthylBudget, nil
 }
 
-func (service *BudgetService) UpdateMonthylBudget(userID int64, name string, value float64) (*queries.MonthlyBudget, error) {
+func (service *BudgetService) UpdateMonthylBudget(ledgerID int64, name string, value float64) (*queries.MonthlyBudget, error) {
 	q := queries.New(service.DB)
 	params := queries.UpdateMonthlyBudgetParams{
-		UserID: userID,
-		Name:   name,
-		Value:  value,
+		LedgerID: ledgerID,
+		Name:     name,
+		Value:    value,
 	}
 	err := q.UpdateMonthlyBudget(context.Background(), params)
 	if err != nil {
 		return nil, err
 	}
 	return &queries.MonthlyBudget{
-		UserID: userID,
-		Name:   name,
-		Value:  value,
+		LedgerID: ledgerID,
+		Name:     name,
+		Value:    value,
 	}, nil
 }
 
-func (service *BudgetService) DeleteMonthlyBudget(userID int64) (*queries.MonthlyBudget, error) {
+func (service *BudgetService) DeleteMonthlyBudget(ledgerID int64) (*queries.MonthlyBudget, error) {
 	q := queries.New(service.DB)
-	monthlyBudget, err := q.GetMonthlyBudget(context.Background(), userID)
+	monthlyBudget, err := q.GetMonthlyBudget(context.Background(), ledgerID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("no monthly budget found")
 		}
 		return nil, err
 	}
-	deleteErr := q.DeleteMonthlyBudget(context.Background(), userID)
+	deleteErr := q.DeleteMonthlyBudget(context.Background(), ledgerID)
 	if deleteErr != nil {
 		return nil, deleteErr
 	}
 	return &monthlyBudget, nil
 }
 
-func (service *BudgetService) CreateTaggedBudget(userID int64, name string, value float64, tag string) (*queries.TaggedBudget, error) {
+func (service *BudgetService) CreateTaggedBudget(userID int64, ledgerID int64, name string, value float64, tag string) (*queries.TaggedBudget, error) {
 	q := queries.New(service.DB)
 	params := queries.CreateTaggedBudgetParams{
-		UserID: userID,
-		Name:   name,
-		Value:  value,
-		Tag:    tag,
-		Date:   time.Now().Format(time.RFC3339),
+		UserID:   userID,
+		LedgerID: ledgerID,
+		Name:     name,
+		Value:    value,
+		Tag:      tag,
+		Date:     time.Now().Format(time.RFC3339),
 	}
 	budget, err := q.CreateTaggedBudget(context.Background(), params)
 	if err != nil {
@@ -94,9 +97,9 @@ func (service *BudgetService) CreateTaggedBudget(userID int64, name string, valu
 	return &budget, nil
 }
 
-func (service *BudgetService) GetTaggedBudgets(userID int64) ([]queries.TaggedBudget, error) {
+func (service *BudgetService) GetTaggedBudgets(ledgerID int64) ([]queries.TaggedBudget, error) {
 	q := queries.New(service.DB)
-	budgets, err := q.GetTaggedBudgets(context.Background(), userID)
+	budgets, err := q.GetTaggedBudgets(context.Background(), ledgerID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return []queries.TaggedBudget{}, nil
@@ -110,11 +113,11 @@ func (service *BudgetService) GetTaggedBudgets(userID int64) ([]queries.TaggedBu
 	return budgets, nil
 }
 
-func (service *BudgetService) DeleteTaggedBudget(userID int64, budgetID int64) (*queries.TaggedBudget, error) {
+func (service *BudgetService) DeleteTaggedBudget(ledgerID int64, budgetID int64) (*queries.TaggedBudget, error) {
 	q := queries.New(service.DB)
 	getBudgetparams := queries.GetTaggedBudgetParams{
-		UserID: userID,
-		ID:     budgetID,
+		LedgerID: ledgerID,
+		ID:       budgetID,
 	}
 	budget, err := q.GetTaggedBudget(context.Background(), getBudgetparams)
 	if err != nil {
@@ -124,8 +127,8 @@ func (service *BudgetService) DeleteTaggedBudget(userID int64, budgetID int64) (
 		return nil, err
 	}
 	deleteBudgetParams := queries.DeleteTaggedBudgetParams{
-		UserID: userID,
-		ID:     budgetID,
+		LedgerID: ledgerID,
+		ID:       budgetID,
 	}
 	deleteErr := q.DeleteTaggedBudget(context.Background(), deleteBudgetParams)
 	if deleteErr != nil {
@@ -134,11 +137,16 @@ func (service *BudgetService) DeleteTaggedBudget(userID int64, budgetID int64) (
 	return &budget, nil
 }
 
-func (service *BudgetService) GetTaggedBudgetsStats(userID int64) ([]dtos.GetTaggedBudgetStatsDTO, error) {
+// GetTaggedBudgetsStats reports the spending per tagged budget in the user's running financial month.
+func (service *BudgetService) GetTaggedBudgetsStats(ledgerID int64, userSettings settings.Settings) ([]dtos.GetTaggedBudgetStatsDTO, error) {
 	q := queries.New(service.DB)
+	period := userSettings.CurrentPeriod()
 	params := queries.GetTaggedBudgetStatsParams{
-		UserID:   userID,
-		UserID_2: userID,
+		LedgerID:       ledgerID,
+		Zone:           userSettings.Location().String(),
+		PeriodStartDay: int64(userSettings.PeriodStartDay),
+		PeriodRule:     userSettings.PeriodRule,
+		Period:         period.Label,
 	}
 	budgets, err := q.GetTaggedBudgetStats(context.Background(), params)
 	budgetsDTO := []dtos.GetTaggedBudgetStatsDTO{}
@@ -158,37 +166,40 @@ func (service *BudgetService) GetTaggedBudgetsStats(userID int64) ([]dtos.GetTag
 			totalPrice = stat.TotalPrice.Float64
 		}
 		budgetsDTO = append(budgetsDTO, dtos.GetTaggedBudgetStatsDTO{
-			ID:         stat.ID,
-			Name:       stat.Name,
-			Value:      stat.Value,
-			Tag:        stat.Tag,
-			TotalPrice: totalPrice,
+			ID:          stat.ID,
+			Name:        stat.Name,
+			Value:       stat.Value,
+			Tag:         stat.Tag,
+			TotalPrice:  totalPrice,
+			Period:      period.Label,
+			PeriodStart: period.Start.Format(time.DateOnly),
+			PeriodEnd:   period.LastDay().Format(time.DateOnly),
 		})
 	}
 	return budgetsDTO, nil
 }
 
-func (service *BudgetService) UpdateTaggedBudget(userID int64, id int64, name string, value float64, tag string) (*queries.TaggedBudget, error) {
+func (service *BudgetService) UpdateTaggedBudget(ledgerID int64, id int64, name string, value float64, tag string) (*queries.TaggedBudget, error) {
 	q := queries.New(service.DB)
 	if tag == "" {
 		return nil, errors.New("empty tags are invalid")
 	}
 	params := queries.UpdateTaggedBudgetParams{
-		ID:     id,
-		UserID: userID,
-		Name:   name,
-		Value:  value,
-		Tag:    tag,
+		ID:       id,
+		LedgerID: ledgerID,
+		Name:     name,
+		Value:    value,
+		Tag:      tag,
 	}
 	err := q.UpdateTaggedBudget(context.Background(), params)
 	if err != nil {
 		return nil, err
 	}
 	return &queries.TaggedBudget{
-		ID:     id,
-		UserID: userID,
-		Name:   name,
-		Value:  value,
-		Tag:    tag,
+		ID:       id,
+		LedgerID: ledgerID,
+		Name:     name,
+		Value:    value,
+		Tag:      tag,
 	}, nil
 }
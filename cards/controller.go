@@ -0,0 +1,253 @@
+package cards
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"checkout-go/auth"
+	dto "checkout-go/cards/dtos"
+	"checkout-go/ledgers"
+	"checkout-go/settings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type CardsController struct {
+	CardService     *CardService
+	AuthService     auth.UserContextReader
+	LedgerContext   ledgers.LedgerContextReader
+	SettingsContext settings.SettingsContextReader
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrPaymentNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func (c *CardsController) CreateCard(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var cardBody dto.CreateCardDTO
+	err = json.Unmarshal(body, &cardBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	card, err := c.CardService.Create(userID, ledgerID, cardBody)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(card)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *CardsController) ListCards(w http.ResponseWriter, req *http.Request) {
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	cards, err := c.CardService.List(ledgerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(cards)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *CardsController) GetCardStatus(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	status, err := c.CardService.Status(ledgerID, id, loc)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(status)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *CardsController) UpdateCard(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var cardBody dto.UpdateCardDTO
+	err = json.Unmarshal(body, &cardBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	card, err := c.CardService.Update(ledgerID, id, cardBody)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(card)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *CardsController) DeleteCard(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	err = c.CardService.Delete(ledgerID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *CardsController) ListStatements(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	count := 6
+	if countStr := req.URL.Query().Get("count"); countStr != "" {
+		count, err = strconv.Atoi(countStr)
+		if err != nil {
+			http.Error(w, "Invalid count", http.StatusBadRequest)
+			return
+		}
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	statements, err := c.CardService.Statements(ledgerID, id, count, loc)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(statements)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *CardsController) AddPayment(w http.ResponseWriter, req *http.Request) {
+	cardID, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var paymentBody dto.CreatePaymentDTO
+	err = json.Unmarshal(body, &paymentBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	payment, err := c.CardService.AddPayment(userID, ledgerID, cardID, paymentBody, loc)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(payment)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *CardsController) ListPayments(w http.ResponseWriter, req *http.Request) {
+	cardID, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	payments, err := c.CardService.ListPayments(ledgerID, cardID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(payments)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *CardsController) DeletePayment(w http.ResponseWriter, req *http.Request) {
+	cardID, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	paymentID, err := strconv.ParseInt(chi.URLParam(req, "paymentID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	err = c.CardService.DeletePayment(ledgerID, cardID, paymentID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,19 @@
+package cards
+
+type CreateCardDTO struct {
+	Name string `json:"name"`
+	// Tag marks the transactions made with the card
+	Tag            string  `json:"tag"`
+	ClosingDay     int     `json:"closingDay"`
+	DueDay         int     `json:"dueDay"`
+	MinimumPercent float64 `json:"minimumPercent"`
+	MinimumAmount  float64 `json:"minimumAmount"`
+}
+
+type UpdateCardDTO CreateCardDTO
+
+type CreatePaymentDTO struct {
+	Amount float64 `json:"amount"`
+	Note   string  `json:"note"`
+	Date   string  `json:"date"`
+}
@@ -0,0 +1,57 @@
+package cards
+
+// Card is a credit card. Expenses with its tag are charges on the card and payments with its tag are refunds.
+// A statement closes on ClosingDay and has to be paid by the following DueDay. The minimum due is MinimumPercent
+// of the statement balance but at least MinimumAmount.
+type Card struct {
+	ID             int64   `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID       int64   `db:"ledger_id" json:"ledgerId"`
+	UserID         int64   `db:"user_id" json:"userId"`
+	Name           string  `db:"name" json:"name"`
+	Tag            string  `db:"tag" json:"tag"`
+	ClosingDay     int     `db:"closing_day" json:"closingDay"`
+	DueDay         int     `db:"due_day" json:"dueDay"`
+	MinimumPercent float64 `db:"minimum_percent" json:"minimumPercent"`
+	MinimumAmount  float64 `db:"minimum_amount" json:"minimumAmount"`
+	Date           string  `db:"date" json:"date"`
+}
+
+// Payment is money moved from another account to the card. It is not a transaction: the charges it pays
+// for were already counted as expenses.
+type Payment struct {
+	ID      int64   `db:"id" goqu:"skipinsert" json:"id"`
+	CardID  int64   `db:"card_id" json:"cardId"`
+	UserID  int64   `db:"user_id" json:"userId"`
+	Amount  float64 `db:"amount" json:"amount"`
+	Note    string  `db:"note" json:"note"`
+	Date    string  `db:"payment_date" json:"date"`
+	Created string  `db:"date" json:"-"`
+}
+
+// Statement is one billing cycle, from the day after the previous closing date up to and including ClosingDate.
+// Balance is what was owed when it closed, unpaid earlier statements included. Paid and Remaining count the
+// payments made after closing.
+type Statement struct {
+	PeriodStart      string  `json:"periodStart"`
+	ClosingDate      string  `json:"closingDate"`
+	DueDate          string  `json:"dueDate"`
+	PreviousBalance  float64 `json:"previousBalance"`
+	Charges          float64 `json:"charges"`
+	Credits          float64 `json:"credits"`
+	Payments         float64 `json:"payments"`
+	Balance          float64 `json:"balance"`
+	MinimumDue       float64 `json:"minimumDue"`
+	Paid             float64 `json:"paid"`
+	Remaining        float64 `json:"remaining"`
+	MinimumRemaining float64 `json:"minimumRemaining"`
+}
+
+type CardStatus struct {
+	Card
+	// CurrentBalance is everything owed today, UnbilledCharges the part that is on no statement yet
+	CurrentBalance    float64    `json:"currentBalance"`
+	UnbilledCharges   float64    `json:"unbilledCharges"`
+	Statement         *Statement `json:"statement"`
+	PreviousStatement *Statement `json:"previousStatement"`
+	NextClosingDate   string     `json:"nextClosingDate"`
+}
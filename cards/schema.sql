@@ -0,0 +1,22 @@
+CREATE TABLE credit_cards (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ledger_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    closing_day INTEGER NOT NULL CHECK (closing_day BETWEEN 1 AND 31),
+    due_day INTEGER NOT NULL CHECK (due_day BETWEEN 1 AND 31),
+    minimum_percent REAL NOT NULL DEFAULT 0,
+    minimum_amount REAL NOT NULL DEFAULT 0,
+    date TEXT NOT NULL
+);
+
+CREATE TABLE credit_card_payments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    card_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    amount REAL NOT NULL CHECK (amount > 0),
+    note TEXT NOT NULL DEFAULT '',
+    payment_date TEXT NOT NULL,
+    date TEXT NOT NULL
+);
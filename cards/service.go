@@ -0,0 +1,274 @@
+package cards
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	dtos "checkout-go/cards/dtos"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+var (
+	ErrNotFound        = errors.New("card not found")
+	ErrPaymentNotFound = errors.New("payment not found")
+)
+
+// maxStatements limits how far back the statement history goes.
+const maxStatements = 24
+
+type CardService struct {
+	DB *goqu.Database
+}
+
+func validate(body dtos.CreateCardDTO) error {
+	if body.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if body.Tag == "" {
+		return errors.New("tag cannot be empty")
+	}
+	if body.ClosingDay < 1 || body.ClosingDay > 31 || body.DueDay < 1 || body.DueDay > 31 {
+		return errors.New("closing and due day must be between 1 and 31")
+	}
+	if body.MinimumPercent < 0 || body.MinimumPercent > 100 || body.MinimumAmount < 0 {
+		return errors.New("invalid minimum payment")
+	}
+	return nil
+}
+
+func (service *CardService) Create(userID int64, ledgerID int64, body dtos.CreateCardDTO) (*Card, error) {
+	if err := validate(body); err != nil {
+		return nil, err
+	}
+	card := Card{
+		LedgerID:       ledgerID,
+		UserID:         userID,
+		Name:           body.Name,
+		Tag:            body.Tag,
+		ClosingDay:     body.ClosingDay,
+		DueDay:         body.DueDay,
+		MinimumPercent: body.MinimumPercent,
+		MinimumAmount:  body.MinimumAmount,
+		Date:           time.Now().Format(time.RFC3339),
+	}
+	result, err := service.DB.Insert("credit_cards").Rows(card).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting card: %w", err)
+	}
+	card.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (service *CardService) Get(ledgerID int64, id int64) (*Card, error) {
+	var card Card
+	found, err := service.DB.From("credit_cards").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).ScanStruct(&card)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &card, nil
+}
+
+func (service *CardService) List(ledgerID int64) ([]Card, error) {
+	cards := []Card{}
+	err := service.DB.From("credit_cards").Where(goqu.Ex{"ledger_id": ledgerID}).Order(goqu.C("name").Asc()).ScanStructs(&cards)
+	if err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+func (service *CardService) Update(ledgerID int64, id int64, body dtos.UpdateCardDTO) (*Card, error) {
+	if err := validate(dtos.CreateCardDTO(body)); err != nil {
+		return nil, err
+	}
+	card, err := service.Get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	card.Name = body.Name
+	card.Tag = body.Tag
+	card.ClosingDay = body.ClosingDay
+	card.DueDay = body.DueDay
+	card.MinimumPercent = body.MinimumPercent
+	card.MinimumAmount = body.MinimumAmount
+	_, err = service.DB.Update("credit_cards").
+		Set(goqu.Record{
+			"name":            card.Name,
+			"tag":             card.Tag,
+			"closing_day":     card.ClosingDay,
+			"due_day":         card.DueDay,
+			"minimum_percent": card.MinimumPercent,
+			"minimum_amount":  card.MinimumAmount,
+		}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).
+		Executor().Exec()
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+func (service *CardService) Delete(ledgerID int64, id int64) error {
+	if _, err := service.Get(ledgerID, id); err != nil {
+		return err
+	}
+	return service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		if _, err := tx.Delete("credit_card_payments").Where(goqu.Ex{"card_id": id}).Executor().Exec(); err != nil {
+			return err
+		}
+		_, err := tx.Delete("credit_cards").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+		return err
+	})
+}
+
+// AddPayment records paying the card from another account. It lowers what is owed on the card but is not
+// booked as a transaction, so the charges are not counted as spending a second time.
+func (service *CardService) AddPayment(userID int64, ledgerID int64, cardID int64, body dtos.CreatePaymentDTO, loc *time.Location) (*Payment, error) {
+	if _, err := service.Get(ledgerID, cardID); err != nil {
+		return nil, err
+	}
+	if body.Amount <= 0 {
+		return nil, errors.New("amount must be greater than 0")
+	}
+	if body.Date == "" {
+		body.Date = time.Now().In(loc).Format(time.DateOnly)
+	} else if _, err := time.Parse(time.DateOnly, body.Date); err != nil {
+		return nil, fmt.Errorf("invalid date: %w", err)
+	}
+	payment := Payment{
+		CardID:  cardID,
+		UserID:  userID,
+		Amount:  body.Amount,
+		Note:    body.Note,
+		Date:    body.Date,
+		Created: time.Now().Format(time.RFC3339),
+	}
+	result, err := service.DB.Insert("credit_card_payments").Rows(payment).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting payment: %w", err)
+	}
+	payment.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (service *CardService) payments(cardID int64) ([]Payment, error) {
+	payments := []Payment{}
+	err := service.DB.From("credit_card_payments").
+		Where(goqu.Ex{"card_id": cardID}).
+		Order(goqu.C("payment_date").Asc(), goqu.C("id").Asc()).
+		ScanStructs(&payments)
+	return payments, err
+}
+
+func (service *CardService) ListPayments(ledgerID int64, cardID int64) ([]Payment, error) {
+	if _, err := service.Get(ledgerID, cardID); err != nil {
+		return nil, err
+	}
+	return service.payments(cardID)
+}
+
+func (service *CardService) DeletePayment(ledgerID int64, cardID int64, paymentID int64) error {
+	if _, err := service.Get(ledgerID, cardID); err != nil {
+		return err
+	}
+	result, err := service.DB.Delete("credit_card_payments").Where(goqu.Ex{"card_id": cardID, "id": paymentID}).Executor().Exec()
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrPaymentNotFound
+	}
+	return nil
+}
+
+// movements collects the card's tagged transactions and payments by local day.
+func (service *CardService) movements(card Card, loc *time.Location) ([]movement, error) {
+	var charges []struct {
+		Date  string  `db:"local_date"`
+		Price float64 `db:"price"`
+	}
+	localDate := goqu.L("strftime('%Y-%m-%d', local_time(date, ?))", loc.String())
+	err := service.DB.From("transactions").
+		Select(localDate.As("local_date"), "price").
+		Where(
+			goqu.C("ledger_id").Eq(card.LedgerID),
+			goqu.L("EXISTS (SELECT 1 FROM json_each(tags) WHERE value = ?)", card.Tag),
+		).
+		ScanStructs(&charges)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := service.payments(card.ID)
+	if err != nil {
+		return nil, err
+	}
+	movements := make([]movement, 0, len(charges)+len(payments))
+	for _, charge := range charges {
+		movements = append(movements, movement{date: charge.Date, amount: -charge.Price})
+	}
+	for _, payment := range payments {
+		movements = append(movements, movement{date: payment.Date, amount: -payment.Amount, payment: true})
+	}
+	return movements, nil
+}
+
+// Status returns what is owed on a card now, its latest statement with the minimum and the due date, and the
+// statement before it.
+func (service *CardService) Status(ledgerID int64, id int64, loc *time.Location) (*CardStatus, error) {
+	card, err := service.Get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	movements, err := service.movements(*card, loc)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	history, nextClosing := statements(*card, movements, today, 2)
+	status := CardStatus{
+		Card:              *card,
+		CurrentBalance:    roundCents(balanceAt(movements, today.Format(time.DateOnly))),
+		UnbilledCharges:   roundCents(sumBetween(movements, history[1].ClosingDate, today.Format(time.DateOnly), false)),
+		Statement:         &history[1],
+		PreviousStatement: &history[0],
+		NextClosingDate:   nextClosing.Format(time.DateOnly),
+	}
+	return &status, nil
+}
+
+// Statements returns the last count closed statements of a card, newest first.
+func (service *CardService) Statements(ledgerID int64, id int64, count int, loc *time.Location) ([]Statement, error) {
+	if count < 1 || count > maxStatements {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxStatements)
+	}
+	card, err := service.Get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	movements, err := service.movements(*card, loc)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().In(loc)
+	history, _ := statements(*card, movements, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), count)
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history, nil
+}
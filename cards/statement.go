@@ -0,0 +1,109 @@
+package cards
+
+import (
+	"math"
+	"time"
+)
+
+// movement is a change of what is owed on a card on a local day: charges are positive, refunds and
+// payments negative.
+type movement struct {
+	date    string
+	amount  float64
+	payment bool
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// dayOfMonth is day in the given month, moved to the last day of shorter months.
+func dayOfMonth(year int, month time.Month, day int) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return time.Date(year, month, min(day, lastDay), 0, 0, 0, 0, time.UTC)
+}
+
+// closingDates returns the last count closing dates before today, oldest first, and the next closing date.
+// A statement closes at the end of its closing day, so one closing today is still open.
+func closingDates(card Card, today time.Time, count int) ([]time.Time, time.Time) {
+	latest := dayOfMonth(today.Year(), today.Month(), card.ClosingDay)
+	if !latest.Before(today) {
+		latest = dayOfMonth(today.Year(), today.Month()-1, card.ClosingDay)
+	}
+	dates := make([]time.Time, count)
+	for i := range dates {
+		dates[count-1-i] = dayOfMonth(latest.Year(), latest.Month()-time.Month(i), card.ClosingDay)
+	}
+	return dates, dayOfMonth(latest.Year(), latest.Month()+1, card.ClosingDay)
+}
+
+// dueDate is the first due day after closing.
+func dueDate(card Card, closing time.Time) time.Time {
+	due := dayOfMonth(closing.Year(), closing.Month(), card.DueDay)
+	if !due.After(closing) {
+		due = dayOfMonth(closing.Year(), closing.Month()+1, card.DueDay)
+	}
+	return due
+}
+
+// sumBetween adds up the movements after from up to and including to.
+func sumBetween(movements []movement, from string, to string, payments bool) float64 {
+	sum := 0.0
+	for _, m := range movements {
+		if m.payment == payments && m.date > from && m.date <= to {
+			sum += m.amount
+		}
+	}
+	return sum
+}
+
+func balanceAt(movements []movement, date string) float64 {
+	return sumBetween(movements, "", date, false) + sumBetween(movements, "", date, true)
+}
+
+func (card Card) minimumDue(balance float64) float64 {
+	if balance <= 0 {
+		return 0
+	}
+	return roundCents(min(max(balance*card.MinimumPercent/100, card.MinimumAmount), balance))
+}
+
+// statements builds the last count statements of card that closed before today, oldest first.
+func statements(card Card, movements []movement, today time.Time, count int) ([]Statement, time.Time) {
+	closings, nextClosing := closingDates(card, today, count)
+	result := make([]Statement, 0, count)
+	for i, closing := range closings {
+		previous := dayOfMonth(closing.Year(), closing.Month()-1, card.ClosingDay).Format(time.DateOnly)
+		end := closing.Format(time.DateOnly)
+		paidUntil := today.Format(time.DateOnly)
+		if i+1 < len(closings) {
+			paidUntil = closings[i+1].Format(time.DateOnly)
+		}
+		statement := Statement{
+			PeriodStart:     dayOfMonth(closing.Year(), closing.Month()-1, card.ClosingDay).AddDate(0, 0, 1).Format(time.DateOnly),
+			ClosingDate:     end,
+			DueDate:         dueDate(card, closing).Format(time.DateOnly),
+			PreviousBalance: roundCents(balanceAt(movements, previous)),
+			Payments:        roundCents(-sumBetween(movements, previous, end, true)),
+			Balance:         roundCents(balanceAt(movements, end)),
+			Paid:            roundCents(-sumBetween(movements, end, paidUntil, true)),
+		}
+		for _, m := range movements {
+			if m.payment || m.date <= previous || m.date > end {
+				continue
+			}
+			if m.amount > 0 {
+				statement.Charges += m.amount
+			} else {
+				statement.Credits -= m.amount
+			}
+		}
+		statement.Charges = roundCents(statement.Charges)
+		statement.Credits = roundCents(statement.Credits)
+		statement.MinimumDue = card.minimumDue(statement.Balance)
+		statement.Remaining = roundCents(max(statement.Balance-statement.Paid, 0))
+		statement.MinimumRemaining = roundCents(max(statement.MinimumDue-statement.Paid, 0))
+		result = append(result, statement)
+	}
+	return result, nextClosing
+}
@@ -0,0 +1,124 @@
+package customfields
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"checkout-go/auth"
+	dto "checkout-go/customfields/dtos"
+	"checkout-go/ledgers"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type CustomFieldsController struct {
+	CustomFieldService *CustomFieldService
+	AuthService        auth.UserContextReader
+	LedgerContext      ledgers.LedgerContextReader
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func (c *CustomFieldsController) ListFields(w http.ResponseWriter, req *http.Request) {
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	fields, err := c.CustomFieldService.List(ledgerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(fields)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *CustomFieldsController) CreateField(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var fieldBody dto.CreateFieldDTO
+	err = json.Unmarshal(body, &fieldBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	field, err := c.CustomFieldService.Create(userID, ledgerID, fieldBody)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(field)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *CustomFieldsController) UpdateField(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var fieldBody dto.UpdateFieldDTO
+	err = json.Unmarshal(body, &fieldBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	field, err := c.CustomFieldService.Update(ledgerID, id, fieldBody)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(field)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// DeleteField removes a field together with its values on the ledger's transactions.
+func (c *CustomFieldsController) DeleteField(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	err = c.CustomFieldService.Delete(ledgerID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
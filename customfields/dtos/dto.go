@@ -0,0 +1,15 @@
+package customfields
+
+type CreateFieldDTO struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	// Options are the allowed values of an enum field
+	Options []string `json:"options"`
+}
+
+// UpdateFieldDTO changes a field's rules, its name and type stay since transactions store values under them.
+type UpdateFieldDTO struct {
+	Required *bool     `json:"required,omitempty"`
+	Options  *[]string `json:"options,omitempty"`
+}
@@ -0,0 +1,28 @@
+package customfields
+
+import "checkout-go/customtypes"
+
+type FieldType string
+
+const (
+	TypeText   FieldType = "text"
+	TypeNumber FieldType = "number"
+	// TypeDate holds a date like "2024-01-31"
+	TypeDate FieldType = "date"
+	TypeBool FieldType = "bool"
+	// TypeEnum holds one of the field's options
+	TypeEnum FieldType = "enum"
+)
+
+// Field is a field a ledger adds to its transactions, its values are stored with each transaction. Every member
+// of the ledger fills in the same fields.
+type Field struct {
+	ID       int64                   `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID int64                   `db:"ledger_id" json:"ledgerId"`
+	UserID   int64                   `db:"user_id" json:"userId"`
+	Name     string                  `db:"name" json:"name"`
+	Type     FieldType               `db:"type" json:"type"`
+	Required bool                    `db:"required" json:"required"`
+	Options  customtypes.StringSlice `db:"options" json:"options"`
+	Date     string                  `db:"date" json:"date"`
+}
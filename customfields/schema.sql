@@ -0,0 +1,13 @@
+ALTER TABLE transactions ADD COLUMN custom_fields TEXT NOT NULL DEFAULT '{}';
+
+CREATE TABLE custom_fields (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ledger_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('text', 'number', 'date', 'bool', 'enum')),
+    required INTEGER NOT NULL DEFAULT 0,
+    options TEXT NOT NULL DEFAULT '[]',
+    date TEXT NOT NULL,
+    UNIQUE (ledger_id, name)
+);
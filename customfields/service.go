@@ -0,0 +1,264 @@
+package customfields
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	dtos "checkout-go/customfields/dtos"
+	"checkout-go/customtypes"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+var ErrNotFound = errors.New("custom field not found")
+
+type CustomFieldService struct {
+	DB *goqu.Database
+}
+
+func validateOptions(fieldType FieldType, options []string) error {
+	if fieldType != TypeEnum {
+		if len(options) > 0 {
+			return errors.New("only enum fields have options")
+		}
+		return nil
+	}
+	if len(options) == 0 {
+		return errors.New("an enum field needs at least one option")
+	}
+	for i, option := range options {
+		if option == "" {
+			return errors.New("options cannot be empty")
+		}
+		if slices.Contains(options[:i], option) {
+			return fmt.Errorf("option %q is listed twice", option)
+		}
+	}
+	return nil
+}
+
+func (service *CustomFieldService) Create(userID int64, ledgerID int64, body dtos.CreateFieldDTO) (*Field, error) {
+	var field *Field
+	err := service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		var err error
+		field, err = service.CreateInTx(tx, userID, ledgerID, body)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+// CreateInTx adds a custom field to a ledger as part of a larger transaction.
+func (service *CustomFieldService) CreateInTx(tx *goqu.TxDatabase, userID int64, ledgerID int64, body dtos.CreateFieldDTO) (*Field, error) {
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	// names are used as JSON object keys in queries
+	if strings.ContainsAny(name, `"\`) {
+		return nil, errors.New(`name cannot contain " or \`)
+	}
+	fieldType := FieldType(body.Type)
+	switch fieldType {
+	case TypeText, TypeNumber, TypeDate, TypeBool, TypeEnum:
+	default:
+		return nil, fmt.Errorf("type must be one of %q, %q, %q, %q or %q", TypeText, TypeNumber, TypeDate, TypeBool, TypeEnum)
+	}
+	if err := validateOptions(fieldType, body.Options); err != nil {
+		return nil, err
+	}
+	count, err := tx.From("custom_fields").Where(goqu.Ex{"ledger_id": ledgerID, "name": name}).Count()
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("custom field %q already exists", name)
+	}
+	field := Field{
+		LedgerID: ledgerID,
+		UserID:   userID,
+		Name:     name,
+		Type:     fieldType,
+		Required: body.Required,
+		Options:  customtypes.StringSlice{},
+		Date:     time.Now().Format(time.RFC3339),
+	}
+	if fieldType == TypeEnum {
+		field.Options = body.Options
+	}
+	result, err := tx.Insert("custom_fields").Rows(field).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting custom field: %w", err)
+	}
+	field.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &field, nil
+}
+
+func (service *CustomFieldService) List(ledgerID int64) ([]Field, error) {
+	return listFields(service.DB.From("custom_fields"), ledgerID)
+}
+
+func listFields(from *goqu.SelectDataset, ledgerID int64) ([]Field, error) {
+	fields := []Field{}
+	err := from.
+		Where(goqu.Ex{"ledger_id": ledgerID}).
+		Order(goqu.C("name").Asc()).
+		ScanStructs(&fields)
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func (service *CustomFieldService) get(ledgerID int64, id int64) (*Field, error) {
+	var field Field
+	found, err := service.DB.From("custom_fields").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).ScanStruct(&field)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &field, nil
+}
+
+// Update changes whether a field is required and the options of an enum field. Values stored before keep
+// their value even when they no longer fit.
+func (service *CustomFieldService) Update(ledgerID int64, id int64, body dtos.UpdateFieldDTO) (*Field, error) {
+	field, err := service.get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	record := goqu.Record{}
+	if body.Required != nil {
+		field.Required = *body.Required
+		record["required"] = field.Required
+	}
+	if body.Options != nil {
+		if err := validateOptions(field.Type, *body.Options); err != nil {
+			return nil, err
+		}
+		field.Options = *body.Options
+		record["options"] = field.Options
+	}
+	if len(record) == 0 {
+		return field, nil
+	}
+	_, err = service.DB.Update("custom_fields").Set(record).Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+	if err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+// Delete removes a field and its values from the ledger's transactions.
+func (service *CustomFieldService) Delete(ledgerID int64, id int64) error {
+	field, err := service.get(ledgerID, id)
+	if err != nil {
+		return err
+	}
+	return service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		_, err := tx.Update("transactions").
+			Set(goqu.Record{"custom_fields": goqu.L("json_remove(custom_fields, ?)", fmt.Sprintf(`$."%s"`, field.Name))}).
+			Where(goqu.Ex{"ledger_id": ledgerID}).
+			Executor().Exec()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Delete("custom_fields").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+		return err
+	})
+}
+
+func checkValue(field Field, value any) error {
+	switch field.Type {
+	case TypeText:
+		if _, ok := value.(string); ok {
+			return nil
+		}
+	case TypeNumber:
+		if _, ok := value.(float64); ok {
+			return nil
+		}
+	case TypeBool:
+		if _, ok := value.(bool); ok {
+			return nil
+		}
+	case TypeDate:
+		if date, ok := value.(string); ok {
+			if _, err := time.Parse(time.DateOnly, date); err != nil {
+				return fmt.Errorf("%s must be a date like 2006-01-02", field.Name)
+			}
+			return nil
+		}
+	case TypeEnum:
+		if option, ok := value.(string); !ok || !slices.Contains(field.Options, option) {
+			return fmt.Errorf("%s must be one of %s", field.Name, strings.Join(field.Options, ", "))
+		}
+		return nil
+	}
+	return fmt.Errorf("%s must be a %s", field.Name, field.Type)
+}
+
+// ValidateFields checks values against the custom fields of a ledger, see Validate.
+func (service *CustomFieldService) ValidateFields(ledgerID int64, values customtypes.Fields, create bool) (customtypes.Fields, error) {
+	fields, err := service.List(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	return Validate(fields, values, create)
+}
+
+// ValidateFieldsInTx is ValidateFields as part of a larger transaction.
+func (service *CustomFieldService) ValidateFieldsInTx(tx *goqu.TxDatabase, ledgerID int64, values customtypes.Fields, create bool) (customtypes.Fields, error) {
+	fields, err := listFields(tx.From("custom_fields"), ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	return Validate(fields, values, create)
+}
+
+// Validate checks values against fields. A null value removes a field and is kept for updates; on create it is
+// dropped and every required field has to have a value.
+func Validate(fields []Field, values customtypes.Fields, create bool) (customtypes.Fields, error) {
+	byName := make(map[string]Field, len(fields))
+	for _, field := range fields {
+		byName[field.Name] = field
+	}
+	valid := customtypes.Fields{}
+	for name, value := range values {
+		field, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown custom field %q", name)
+		}
+		if value == nil {
+			if field.Required {
+				return nil, fmt.Errorf("%s is required", name)
+			}
+			if !create {
+				valid[name] = nil
+			}
+			continue
+		}
+		if err := checkValue(field, value); err != nil {
+			return nil, err
+		}
+		valid[name] = value
+	}
+	if create {
+		for _, field := range fields {
+			if _, ok := valid[field.Name]; field.Required && !ok {
+				return nil, fmt.Errorf("%s is required", field.Name)
+			}
+		}
+	}
+	return valid, nil
+}
@@ -20,19 +20,42 @@ func (t *TimeWrapper) Scan(value any) error {
 	if !ok {
 		return fmt.Errorf("unsupported type: %T", value)
 	}
-	parsedTime, err := time.Parse("2006-01-02T15:04:05Z", strValue)
-	if err == nil {
-		*t = TimeWrapper(parsedTime)
-		return nil
-	}
-	parsedTime, err = time.Parse(time.DateOnly, strValue)
+	parsedTime, _, err := ParseStoredTime(strValue)
 	if err != nil {
-		return fmt.Errorf("failed to parse time: %v", err)
+		return err
 	}
 	*t = TimeWrapper(parsedTime)
 	return nil
 }
 
+// ParseStoredTime parses the date formats that end up in the date columns, keeping any UTC offset.
+// The second return value reports whether the value was a bare calendar date.
+func ParseStoredTime(value string) (time.Time, bool, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+		parsedTime, err := time.Parse(layout, value)
+		if err == nil {
+			return parsedTime, false, nil
+		}
+	}
+	parsedTime, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to parse time: %v", err)
+	}
+	return parsedTime, true, nil
+}
+
+// dateOnlyLocation marks times that were given as a bare calendar date, without a time of day or offset.
+var dateOnlyLocation = time.FixedZone("", 0)
+
+// InLocation pins a time that was given as a bare calendar date to midnight in loc.
+// Times that carried their own offset already name an instant and are returned unchanged.
+func InLocation(t time.Time, loc *time.Location) time.Time {
+	if t.Location() != dateOnlyLocation {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
 func (t TimeWrapper) Time() time.Time {
 	return time.Time(t)
 }
@@ -62,7 +85,7 @@ func (ct *TimeWrapper) UnmarshalJSON(data []byte) error {
 		return nil
 	}
 
-	t, err = time.Parse(time.DateOnly, str)
+	t, err = time.ParseInLocation(time.DateOnly, str, dateOnlyLocation)
 	if err == nil {
 		*ct = TimeWrapper(t)
 		return nil
@@ -0,0 +1,46 @@
+package customtypes
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Fields holds the values of user defined fields by field name, stored as a JSON object.
+type Fields map[string]any
+
+// Scan implements the sql.Scanner interface to deserialize the JSON object from the database
+func (f *Fields) Scan(value any) error {
+	*f = Fields{}
+	if value == nil {
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type: %T", value)
+	}
+
+	if err := json.Unmarshal(data, f); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface to serialize Fields to a JSON object, empty when nil
+func (f Fields) Value() (driver.Value, error) {
+	if f == nil {
+		return "{}", nil
+	}
+
+	bytes, err := json.Marshal(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+	return string(bytes), nil
+}
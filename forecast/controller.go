@@ -0,0 +1,50 @@
+package forecast
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"checkout-go/ledgers"
+	"checkout-go/settings"
+)
+
+type ForecastController struct {
+	ForecastService *ForecastService
+	LedgerContext   ledgers.LedgerContextReader
+	SettingsContext settings.SettingsContextReader
+}
+
+func (c *ForecastController) GetForecast(w http.ResponseWriter, req *http.Request) {
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	userSettings := c.SettingsContext.GetSettingsFromRequest(req)
+	forecast, err := c.ForecastService.Forecast(ledgerID, userSettings, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(forecast)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *ForecastController) GetSafeToSpend(w http.ResponseWriter, req *http.Request) {
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	userSettings := c.SettingsContext.GetSettingsFromRequest(req)
+	safeToSpend, err := c.ForecastService.SafeToSpend(ledgerID, userSettings, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(safeToSpend)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
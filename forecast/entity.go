@@ -0,0 +1,81 @@
+package forecast
+
+import "checkout-go/recurring"
+
+// Forecast projects where the spending of the running financial month will land. Amounts are positive.
+type Forecast struct {
+	Period        string `json:"period"`
+	PeriodStart   string `json:"periodStart"`
+	PeriodEnd     string `json:"periodEnd"`
+	DaysElapsed   int    `json:"daysElapsed"`
+	DaysRemaining int    `json:"daysRemaining"`
+	// Spent is what was spent until today, today included
+	Spent float64 `json:"spent"`
+	// Discretionary is the expected day-to-day spending of the remaining days
+	Discretionary float64 `json:"discretionary"`
+	// Recurring is the sum of the recurring charges still due in the period
+	Recurring float64 `json:"recurring"`
+	Projected float64 `json:"projected"`
+	// Low and High bound the projection with the given Confidence
+	Low              float64                `json:"low"`
+	High             float64                `json:"high"`
+	Confidence       float64                `json:"confidence"`
+	Pace             float64                `json:"pace"`
+	RecurringCharges []recurring.Occurrence `json:"recurringCharges"`
+	Tags             []TagForecast          `json:"tags"`
+	MonthlyBudget    *BudgetForecast        `json:"monthlyBudget"`
+	TaggedBudgets    []BudgetForecast       `json:"taggedBudgets"`
+}
+
+type TagForecast struct {
+	Tag       string  `json:"tag"`
+	Spent     float64 `json:"spent"`
+	Projected float64 `json:"projected"`
+}
+
+type BudgetForecast struct {
+	ID        int64   `json:"id"`
+	Name      string  `json:"name"`
+	Tag       string  `json:"tag,omitempty"`
+	Value     float64 `json:"value"`
+	Spent     float64 `json:"spent"`
+	Projected float64 `json:"projected"`
+	// Remaining is how much of the budget is left at the end of the period if the projection holds
+	Remaining float64 `json:"remaining"`
+	OnTrack   bool    `json:"onTrack"`
+}
+
+// SafeToSpend is what can still be spent today without running short before the end of the financial month.
+// Amounts are positive.
+type SafeToSpend struct {
+	Period    string `json:"period"`
+	PeriodEnd string `json:"periodEnd"`
+	// DaysRemaining counts the days left in the period, today included
+	DaysRemaining int `json:"daysRemaining"`
+	// Daily is the allowance for every remaining day, as it stood at the start of today
+	Daily float64 `json:"daily"`
+	// Today is the Daily allowance minus what was spent today, negative once today went over it
+	Today      float64 `json:"today"`
+	SpentToday float64 `json:"spentToday"`
+	// Available is what is free to spend until the period end, the lower of what the balance and the budget leave
+	Available float64 `json:"available"`
+	// LimitedBy tells which of the two Available comes from, "balance" or "budget"
+	LimitedBy         string                 `json:"limitedBy"`
+	Balance           float64                `json:"balance"`
+	BalanceAvailable  float64                `json:"balanceAvailable"`
+	MonthlyBudget     *float64               `json:"monthlyBudget"`
+	Spent             float64                `json:"spent"`
+	BudgetRemaining   *float64               `json:"budgetRemaining"`
+	BudgetAvailable   *float64               `json:"budgetAvailable"`
+	UpcomingBills     float64                `json:"upcomingBills"`
+	Bills             []recurring.Occurrence `json:"bills"`
+	GoalContributions float64                `json:"goalContributions"`
+	Goals             []GoalContribution     `json:"goals"`
+}
+
+// GoalContribution is what a savings goal still needs this period to stay on schedule.
+type GoalContribution struct {
+	ID     int64   `json:"id"`
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+}
@@ -0,0 +1,133 @@
+package forecast
+
+import (
+	"math"
+	"time"
+
+	"checkout-go/goals"
+	"checkout-go/settings"
+)
+
+const (
+	LimitedByBalance = "balance"
+	LimitedByBudget  = "budget"
+)
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// SafeToSpend works out the daily allowance for the rest of the financial month running at now. The money free
+// until the period end is the balance, less the recurring bills still due and what the savings goals need this
+// period to stay on schedule. With a monthly budget it is also at most what is left of the budget after the bills.
+// Both are taken as they stood at the start of today, so what is spent today lowers today's allowance only.
+func (service *ForecastService) SafeToSpend(ledgerID int64, userSettings settings.Settings, now time.Time) (*SafeToSpend, error) {
+	loc := userSettings.Location()
+	period := userSettings.PeriodContaining(now)
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	days, err := service.dailySpending(ledgerID, period, userSettings)
+	if err != nil {
+		return nil, err
+	}
+	result := SafeToSpend{
+		Period:    period.Label,
+		PeriodEnd: period.LastDay().Format(time.DateOnly),
+		Goals:     []GoalContribution{},
+	}
+	for _, day := range days {
+		if day.date.Before(tomorrow) {
+			result.Spent += day.spent
+		}
+		if day.date.Equal(today) && day.spent != 0 {
+			result.SpentToday = day.spent
+		}
+		if !day.date.Before(today) {
+			result.DaysRemaining++
+		}
+	}
+
+	result.Balance, err = service.TransactionsService.GetBalance(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	result.Bills, err = service.RecurringService.Upcoming(ledgerID, tomorrow, period.End, loc)
+	if err != nil {
+		return nil, err
+	}
+	for _, bill := range result.Bills {
+		result.UpcomingBills += bill.Price
+	}
+	if err := service.goalContributions(ledgerID, period, local, &result); err != nil {
+		return nil, err
+	}
+
+	result.BalanceAvailable = result.Balance + result.SpentToday - result.UpcomingBills - result.GoalContributions
+	result.Available = result.BalanceAvailable
+	result.LimitedBy = LimitedByBalance
+	monthly, err := service.BudgetService.GetMonthylBudget(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	if monthly != nil {
+		remaining := roundCents(monthly.Value - result.Spent)
+		available := roundCents(monthly.Value - (result.Spent - result.SpentToday) - result.UpcomingBills)
+		result.MonthlyBudget = &monthly.Value
+		result.BudgetRemaining = &remaining
+		result.BudgetAvailable = &available
+		if available < result.Available {
+			result.Available = available
+			result.LimitedBy = LimitedByBudget
+		}
+	}
+
+	result.Available = roundCents(max(result.Available, 0))
+	if result.DaysRemaining > 0 {
+		result.Daily = roundCents(result.Available / float64(result.DaysRemaining))
+	}
+	result.Today = roundCents(result.Daily - result.SpentToday)
+	result.Balance = roundCents(result.Balance)
+	result.BalanceAvailable = roundCents(result.BalanceAvailable)
+	result.Spent = roundCents(result.Spent)
+	result.SpentToday = roundCents(result.SpentToday)
+	result.UpcomingBills = roundCents(result.UpcomingBills)
+	result.GoalContributions = roundCents(result.GoalContributions)
+	return &result, nil
+}
+
+// goalContributions adds what every unfinished savings goal still needs in period. A goal needs an even share of
+// what it was missing at the start of the period over the months left, less what went into it since.
+func (service *ForecastService) goalContributions(ledgerID int64, period settings.Period, now time.Time, result *SafeToSpend) error {
+	list, err := service.GoalService.List(ledgerID)
+	if err != nil {
+		return err
+	}
+	start := period.Start.Format(time.DateOnly)
+	today := now.Format(time.DateOnly)
+	for _, goal := range list {
+		contributions, err := service.GoalService.Contributions(goal, now.Location())
+		if err != nil {
+			return err
+		}
+		progress := goals.ComputeProgress(goal, contributions, now)
+		if progress.Completed || progress.MonthsLeft == 0 {
+			continue
+		}
+		contributed := 0.0
+		for _, contribution := range contributions {
+			if contribution.Date >= start && contribution.Date <= today {
+				contributed += contribution.Amount
+			}
+		}
+		planned := (progress.Remaining + contributed) / float64(progress.MonthsLeft)
+		amount := roundCents(planned - contributed)
+		if amount <= 0 {
+			continue
+		}
+		result.Goals = append(result.Goals, GoalContribution{ID: goal.ID, Name: goal.Name, Amount: amount})
+		result.GoalContributions += amount
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package forecast
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"checkout-go/budgets"
+	"checkout-go/goals"
+	"checkout-go/recurring"
+	"checkout-go/settings"
+	"checkout-go/transactions"
+)
+
+// historyPeriods is how many past financial months the weekday pattern is learned from.
+const historyPeriods = 3
+
+// confidence is the probability the month-end total falls between Low and High,
+// confidenceZ the matching quantile of the normal distribution.
+const (
+	confidence  = 0.9
+	confidenceZ = 1.645
+)
+
+// The pace scales the historical pattern to how this month is going so far. It is clamped so that
+// one large purchase early in the month does not double the rest of the projection.
+const (
+	minPace = 0.5
+	maxPace = 2
+)
+
+type ForecastService struct {
+	TransactionsService *transactions.TransactionService
+	BudgetService       *budgets.BudgetService
+	RecurringService    *recurring.RecurringService
+	GoalService         *goals.GoalService
+}
+
+// weekdayPattern is the mean and variance of the discretionary spending per weekday.
+type weekdayPattern struct {
+	mean     [7]float64
+	variance [7]float64
+}
+
+// Forecast projects the total expenses of the financial month running at now. Recurring charges still due are
+// added as they are; the other remaining days are estimated from the spending per weekday of the previous
+// months, scaled by how the elapsed days of this month compare to that pattern.
+func (service *ForecastService) Forecast(ledgerID int64, userSettings settings.Settings, now time.Time) (*Forecast, error) {
+	loc := userSettings.Location()
+	period := userSettings.PeriodContaining(now)
+	local := now.In(loc)
+	tomorrow := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+
+	current, err := service.dailySpending(ledgerID, period, userSettings)
+	if err != nil {
+		return nil, err
+	}
+	labelMonth, err := time.Parse("2006-01", period.Label)
+	if err != nil {
+		return nil, err
+	}
+	historyStart := period.Start
+	var history []daySpending
+	for i := 1; i <= historyPeriods; i++ {
+		past := userSettings.Period(labelMonth.Year(), labelMonth.Month()-time.Month(i))
+		days, err := service.dailySpending(ledgerID, past, userSettings)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, days...)
+		historyStart = past.Start
+	}
+	pastCharges, err := service.RecurringService.Upcoming(ledgerID, historyStart, tomorrow, loc)
+	if err != nil {
+		return nil, err
+	}
+	upcoming, err := service.RecurringService.Upcoming(ledgerID, tomorrow, period.End, loc)
+	if err != nil {
+		return nil, err
+	}
+	history = withoutRecurring(history, pastCharges)
+	discretionary := withoutRecurring(current, pastCharges)
+
+	forecast := Forecast{
+		Period:           period.Label,
+		PeriodStart:      period.Start.Format(time.DateOnly),
+		PeriodEnd:        period.LastDay().Format(time.DateOnly),
+		Confidence:       confidence,
+		Pace:             1,
+		RecurringCharges: upcoming,
+		Tags:             []TagForecast{},
+		TaggedBudgets:    []BudgetForecast{},
+	}
+	var elapsed, remaining []daySpending
+	elapsedDiscretionary := 0.0
+	for i, day := range current {
+		if day.date.Before(tomorrow) {
+			elapsed = append(elapsed, day)
+			forecast.Spent += day.spent
+			elapsedDiscretionary += discretionary[i].spent
+		} else {
+			remaining = append(remaining, day)
+		}
+	}
+	forecast.DaysElapsed = len(elapsed)
+	forecast.DaysRemaining = len(remaining)
+	for _, charge := range upcoming {
+		forecast.Recurring += charge.Price
+	}
+
+	pattern, hasHistory := learnPattern(history)
+	if hasHistory {
+		expected := 0.0
+		for _, day := range elapsed {
+			expected += pattern.mean[day.date.Weekday()]
+		}
+		if expected > 0 {
+			forecast.Pace = min(max(elapsedDiscretionary/expected, minPace), maxPace)
+		}
+	} else {
+		// Without history the elapsed days of this month are all there is to go by
+		pattern, _ = learnPattern(discretionary[:len(elapsed)])
+	}
+	variance := 0.0
+	for _, day := range remaining {
+		forecast.Discretionary += pattern.mean[day.date.Weekday()] * forecast.Pace
+		variance += pattern.variance[day.date.Weekday()] * forecast.Pace * forecast.Pace
+	}
+	forecast.Projected = forecast.Spent + forecast.Discretionary + forecast.Recurring
+	margin := confidenceZ * math.Sqrt(variance)
+	forecast.Low = max(forecast.Projected-margin, forecast.Spent+forecast.Recurring)
+	forecast.High = forecast.Projected + margin
+
+	forecast.Tags, err = service.forecastTags(ledgerID, userSettings, &forecast, period, historyStart, tomorrow, pastCharges, upcoming, hasHistory)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.forecastBudgets(ledgerID, &forecast); err != nil {
+		return nil, err
+	}
+	return &forecast, nil
+}
+
+type daySpending struct {
+	date  time.Time
+	spent float64
+}
+
+// dailySpending returns what was spent on every day of period, days without expenses included.
+func (service *ForecastService) dailySpending(ledgerID int64, period settings.Period, userSettings settings.Settings) ([]daySpending, error) {
+	labelMonth, err := time.Parse("2006-01", period.Label)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := service.TransactionsService.GetExpensesDailyStatisticsForMonthInYear(ledgerID, int(labelMonth.Month()), labelMonth.Year(), userSettings)
+	if err != nil {
+		return nil, err
+	}
+	days := make([]daySpending, 0, len(*stats))
+	for _, stat := range *stats {
+		date, err := time.ParseInLocation(time.DateOnly, stat.Date, userSettings.Location())
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, daySpending{date: date, spent: -stat.Sum})
+	}
+	return days, nil
+}
+
+// withoutRecurring takes the recurring charges out of the days they were due on, leaving the discretionary spending.
+func withoutRecurring(days []daySpending, charges []recurring.Occurrence) []daySpending {
+	due := make(map[string]float64)
+	for _, charge := range charges {
+		due[charge.Date] += charge.Price
+	}
+	result := make([]daySpending, len(days))
+	for i, day := range days {
+		result[i] = daySpending{date: day.date, spent: max(day.spent-due[day.date.Format(time.DateOnly)], 0)}
+	}
+	return result
+}
+
+// learnPattern computes the spending per weekday, reporting false when there was no spending at all.
+func learnPattern(days []daySpending) (weekdayPattern, bool) {
+	var pattern weekdayPattern
+	var sums [7]float64
+	var counts [7]float64
+	total := 0.0
+	for _, day := range days {
+		sums[day.date.Weekday()] += day.spent
+		counts[day.date.Weekday()]++
+		total += day.spent
+	}
+	if total == 0 {
+		return pattern, false
+	}
+	allDays := 0.0
+	for weekday := range sums {
+		allDays += counts[weekday]
+	}
+	for weekday := range sums {
+		if counts[weekday] == 0 {
+			// Not every weekday occurs in a few elapsed days, fall back to the overall mean
+			pattern.mean[weekday] = total / allDays
+			continue
+		}
+		pattern.mean[weekday] = sums[weekday] / counts[weekday]
+	}
+	for _, day := range days {
+		deviation := day.spent - pattern.mean[day.date.Weekday()]
+		pattern.variance[day.date.Weekday()] += deviation * deviation
+	}
+	for weekday := range pattern.variance {
+		if counts[weekday] > 1 {
+			pattern.variance[weekday] /= counts[weekday] - 1
+		}
+	}
+	return pattern, true
+}
+
+// forecastTags splits the projection by tag: what each tag got so far, its share of the historical
+// discretionary spending for the remaining days and its recurring charges still due.
+func (service *ForecastService) forecastTags(
+	ledgerID int64,
+	userSettings settings.Settings,
+	forecast *Forecast,
+	period settings.Period,
+	historyStart time.Time,
+	tomorrow time.Time,
+	pastCharges []recurring.Occurrence,
+	upcoming []recurring.Occurrence,
+	hasHistory bool,
+) ([]TagForecast, error) {
+	_, spent, err := service.TransactionsService.SpentBetween(ledgerID, period.Start, tomorrow, userSettings)
+	if err != nil {
+		return nil, err
+	}
+	share := make(map[string]float64)
+	if hasHistory {
+		historyTotal, historyTags, err := service.TransactionsService.SpentBetween(ledgerID, historyStart, period.Start, userSettings)
+		if err != nil {
+			return nil, err
+		}
+		for _, charge := range pastCharges {
+			if charge.Date >= period.Start.Format(time.DateOnly) {
+				continue
+			}
+			historyTotal -= charge.Price
+			for _, tag := range chargeTags(charge) {
+				historyTags[tag] = max(historyTags[tag]-charge.Price, 0)
+			}
+		}
+		for tag, value := range historyTags {
+			if historyTotal > 0 {
+				share[tag] = value / historyTotal
+			}
+		}
+	} else if forecast.Spent > 0 {
+		for tag, value := range spent {
+			share[tag] = value / forecast.Spent
+		}
+	}
+
+	projected := make(map[string]float64)
+	for tag, value := range spent {
+		projected[tag] += value
+	}
+	for tag, value := range share {
+		projected[tag] += value * forecast.Discretionary
+	}
+	for _, charge := range upcoming {
+		for _, tag := range chargeTags(charge) {
+			projected[tag] += charge.Price
+		}
+	}
+	tags := []TagForecast{}
+	for tag, value := range projected {
+		tags = append(tags, TagForecast{Tag: tag, Spent: spent[tag], Projected: value})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Projected != tags[j].Projected {
+			return tags[i].Projected > tags[j].Projected
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+// chargeTags are the tags a recurring charge is counted under, the empty tag when it has none.
+func chargeTags(charge recurring.Occurrence) []string {
+	if len(charge.Tags) == 0 {
+		return []string{""}
+	}
+	return charge.Tags
+}
+
+// forecastBudgets checks the monthly budget and every tagged budget against the projection.
+func (service *ForecastService) forecastBudgets(ledgerID int64, forecast *Forecast) error {
+	monthly, err := service.BudgetService.GetMonthylBudget(ledgerID)
+	if err != nil {
+		return err
+	}
+	if monthly != nil {
+		budget := newBudgetForecast(monthly.ID, monthly.Name, "", monthly.Value, forecast.Spent, forecast.Projected)
+		forecast.MonthlyBudget = &budget
+	}
+	tagged, err := service.BudgetService.GetTaggedBudgets(ledgerID)
+	if err != nil {
+		return err
+	}
+	for _, budget := range tagged {
+		var spent, projected float64
+		for _, tag := range forecast.Tags {
+			if tag.Tag == budget.Tag {
+				spent, projected = tag.Spent, tag.Projected
+			}
+		}
+		forecast.TaggedBudgets = append(forecast.TaggedBudgets, newBudgetForecast(budget.ID, budget.Name, budget.Tag, budget.Value, spent, projected))
+	}
+	return nil
+}
+
+func newBudgetForecast(id int64, name string, tag string, value float64, spent float64, projected float64) BudgetForecast {
+	return BudgetForecast{
+		ID:        id,
+		Name:      name,
+		Tag:       tag,
+		Value:     value,
+		Spent:     spent,
+		Projected: projected,
+		Remaining: value - projected,
+		OnTrack:   projected <= value,
+	}
+}
@@ -0,0 +1,204 @@
+package goals
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"checkout-go/auth"
+	dto "checkout-go/goals/dtos"
+	"checkout-go/ledgers"
+	"checkout-go/settings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type GoalsController struct {
+	GoalService     *GoalService
+	AuthService     auth.UserContextReader
+	LedgerContext   ledgers.LedgerContextReader
+	SettingsContext settings.SettingsContextReader
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrContributionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func (c *GoalsController) CreateGoal(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var goalBody dto.CreateGoalDTO
+	err = json.Unmarshal(body, &goalBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	goal, err := c.GoalService.Create(userID, ledgerID, goalBody, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(goal)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *GoalsController) ListGoals(w http.ResponseWriter, req *http.Request) {
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	goals, err := c.GoalService.ListProgress(ledgerID, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(goals)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *GoalsController) GetGoalProgress(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	progress, err := c.GoalService.GetProgress(ledgerID, id, loc)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(progress)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *GoalsController) UpdateGoal(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var goalBody dto.UpdateGoalDTO
+	err = json.Unmarshal(body, &goalBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	goal, err := c.GoalService.Update(ledgerID, id, goalBody)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(goal)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *GoalsController) DeleteGoal(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	err = c.GoalService.Delete(ledgerID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *GoalsController) AddContribution(w http.ResponseWriter, req *http.Request) {
+	goalID, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var contributionBody dto.CreateContributionDTO
+	err = json.Unmarshal(body, &contributionBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	contribution, err := c.GoalService.AddContribution(userID, ledgerID, goalID, contributionBody, loc)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(contribution)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *GoalsController) DeleteContribution(w http.ResponseWriter, req *http.Request) {
+	goalID, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	contributionID, err := strconv.ParseInt(chi.URLParam(req, "contributionID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid contribution ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	err = c.GoalService.DeleteContribution(ledgerID, goalID, contributionID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
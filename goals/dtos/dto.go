@@ -0,0 +1,17 @@
+package goals
+
+type CreateGoalDTO struct {
+	Name         string  `json:"name"`
+	TargetAmount float64 `json:"targetAmount"`
+	TargetDate   string  `json:"targetDate"`
+	StartDate    string  `json:"startDate"`
+	Tag          *string `json:"tag"`
+}
+
+type UpdateGoalDTO CreateGoalDTO
+
+type CreateContributionDTO struct {
+	Amount float64 `json:"amount"`
+	Note   string  `json:"note"`
+	Date   string  `json:"date"`
+}
@@ -0,0 +1,53 @@
+package goals
+
+// Goal is something the ledger saves toward. Money counts toward it through manual contributions and,
+// when Tag is set, through transactions with that tag from StartDate on.
+type Goal struct {
+	ID           int64   `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID     int64   `db:"ledger_id" json:"ledgerId"`
+	UserID       int64   `db:"user_id" json:"userId"`
+	Name         string  `db:"name" json:"name"`
+	TargetAmount float64 `db:"target_amount" json:"targetAmount"`
+	TargetDate   string  `db:"target_date" json:"targetDate"`
+	StartDate    string  `db:"start_date" json:"startDate"`
+	Tag          *string `db:"tag" json:"tag"`
+	Date         string  `db:"date" json:"date"`
+}
+
+const (
+	SourceManual      = "manual"
+	SourceTransaction = "transaction"
+)
+
+// Contribution is money put toward a goal. Manual ones have an ID, the ones coming from tagged
+// transactions carry the transaction's ID instead.
+type Contribution struct {
+	ID            int64   `db:"id" goqu:"skipinsert" json:"id,omitempty"`
+	GoalID        int64   `db:"goal_id" json:"goalId"`
+	UserID        int64   `db:"user_id" json:"userId"`
+	Amount        float64 `db:"amount" json:"amount"`
+	Note          string  `db:"note" json:"note"`
+	Date          string  `db:"contribution_date" json:"date"`
+	Created       string  `db:"date" json:"-"`
+	Source        string  `db:"-" json:"source"`
+	TransactionID int64   `db:"-" json:"transactionId,omitempty"`
+}
+
+type Progress struct {
+	Goal
+	Saved      float64 `json:"saved"`
+	Remaining  float64 `json:"remaining"`
+	Percentage float64 `json:"percentage"`
+	// MonthsLeft counts the calendar months until the target date, the current one included
+	MonthsLeft          int     `json:"monthsLeft"`
+	RequiredMonthly     float64 `json:"requiredMonthly"`
+	RecentMonthlyPace   float64 `json:"recentMonthlyPace"`
+	ProjectedCompletion *string `json:"projectedCompletion"`
+	OnTrack             bool    `json:"onTrack"`
+	Completed           bool    `json:"completed"`
+}
+
+type GoalDetails struct {
+	Progress
+	Contributions []Contribution `json:"contributions"`
+}
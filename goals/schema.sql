@@ -0,0 +1,21 @@
+CREATE TABLE savings_goals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ledger_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    target_amount REAL NOT NULL,
+    target_date TEXT NOT NULL,
+    start_date TEXT NOT NULL,
+    tag TEXT,
+    date TEXT NOT NULL
+);
+
+CREATE TABLE goal_contributions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    goal_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    amount REAL NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    contribution_date TEXT NOT NULL,
+    date TEXT NOT NULL
+);
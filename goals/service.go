@@ -0,0 +1,312 @@
+package goals
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	dtos "checkout-go/goals/dtos"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+var (
+	ErrNotFound             = errors.New("goal not found")
+	ErrContributionNotFound = errors.New("contribution not found")
+)
+
+// paceDays is the window the recent contribution pace is measured over.
+const paceDays = 90
+
+const daysPerMonth = 365.2425 / 12
+
+type GoalService struct {
+	DB *goqu.Database
+}
+
+func validateGoal(body dtos.CreateGoalDTO) error {
+	if body.Name == "" {
+		return errors.New("goal name cannot be empty")
+	}
+	if body.TargetAmount <= 0 {
+		return errors.New("target amount must be greater than 0")
+	}
+	if _, err := time.Parse(time.DateOnly, body.TargetDate); err != nil {
+		return fmt.Errorf("invalid target date: %w", err)
+	}
+	if body.StartDate != "" {
+		if _, err := time.Parse(time.DateOnly, body.StartDate); err != nil {
+			return fmt.Errorf("invalid start date: %w", err)
+		}
+	}
+	if body.Tag != nil && *body.Tag == "" {
+		return errors.New("tag cannot be empty, leave it out instead")
+	}
+	return nil
+}
+
+func (service *GoalService) Create(userID int64, ledgerID int64, body dtos.CreateGoalDTO, loc *time.Location) (*Goal, error) {
+	if err := validateGoal(body); err != nil {
+		return nil, err
+	}
+	goal := Goal{
+		LedgerID:     ledgerID,
+		UserID:       userID,
+		Name:         body.Name,
+		TargetAmount: body.TargetAmount,
+		TargetDate:   body.TargetDate,
+		StartDate:    body.StartDate,
+		Tag:          body.Tag,
+		Date:         time.Now().Format(time.RFC3339),
+	}
+	if goal.StartDate == "" {
+		goal.StartDate = time.Now().In(loc).Format(time.DateOnly)
+	}
+	result, err := service.DB.Insert("savings_goals").Rows(goal).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting goal: %w", err)
+	}
+	goal.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+func (service *GoalService) Get(ledgerID int64, id int64) (*Goal, error) {
+	var goal Goal
+	found, err := service.DB.From("savings_goals").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).ScanStruct(&goal)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &goal, nil
+}
+
+func (service *GoalService) List(ledgerID int64) ([]Goal, error) {
+	goals := []Goal{}
+	err := service.DB.From("savings_goals").
+		Where(goqu.Ex{"ledger_id": ledgerID}).
+		Order(goqu.C("target_date").Asc()).
+		ScanStructs(&goals)
+	if err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+func (service *GoalService) Update(ledgerID int64, id int64, body dtos.UpdateGoalDTO) (*Goal, error) {
+	if err := validateGoal(dtos.CreateGoalDTO(body)); err != nil {
+		return nil, err
+	}
+	goal, err := service.Get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	goal.Name = body.Name
+	goal.TargetAmount = body.TargetAmount
+	goal.TargetDate = body.TargetDate
+	if body.StartDate != "" {
+		goal.StartDate = body.StartDate
+	}
+	goal.Tag = body.Tag
+	_, err = service.DB.Update("savings_goals").
+		Set(goqu.Record{
+			"name":          goal.Name,
+			"target_amount": goal.TargetAmount,
+			"target_date":   goal.TargetDate,
+			"start_date":    goal.StartDate,
+			"tag":           goal.Tag,
+		}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).
+		Executor().Exec()
+	if err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+func (service *GoalService) Delete(ledgerID int64, id int64) error {
+	if _, err := service.Get(ledgerID, id); err != nil {
+		return err
+	}
+	return service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		if _, err := tx.Delete("goal_contributions").Where(goqu.Ex{"goal_id": id}).Executor().Exec(); err != nil {
+			return err
+		}
+		_, err := tx.Delete("savings_goals").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+		return err
+	})
+}
+
+func (service *GoalService) AddContribution(userID int64, ledgerID int64, goalID int64, body dtos.CreateContributionDTO, loc *time.Location) (*Contribution, error) {
+	if _, err := service.Get(ledgerID, goalID); err != nil {
+		return nil, err
+	}
+	if body.Amount == 0 {
+		return nil, errors.New("amount cannot be 0")
+	}
+	if body.Date == "" {
+		body.Date = time.Now().In(loc).Format(time.DateOnly)
+	} else if _, err := time.Parse(time.DateOnly, body.Date); err != nil {
+		return nil, fmt.Errorf("invalid date: %w", err)
+	}
+	contribution := Contribution{
+		GoalID:  goalID,
+		UserID:  userID,
+		Amount:  body.Amount,
+		Note:    body.Note,
+		Date:    body.Date,
+		Created: time.Now().Format(time.RFC3339),
+		Source:  SourceManual,
+	}
+	result, err := service.DB.Insert("goal_contributions").Rows(contribution).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting contribution: %w", err)
+	}
+	contribution.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &contribution, nil
+}
+
+func (service *GoalService) DeleteContribution(ledgerID int64, goalID int64, contributionID int64) error {
+	if _, err := service.Get(ledgerID, goalID); err != nil {
+		return err
+	}
+	result, err := service.DB.Delete("goal_contributions").Where(goqu.Ex{"goal_id": goalID, "id": contributionID}).Executor().Exec()
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrContributionNotFound
+	}
+	return nil
+}
+
+// Contributions lists the manual contributions of a goal together with its tagged transactions, oldest first.
+// An expense with the goal's tag is money moved into savings and counts positively, a tagged payment is a withdrawal.
+func (service *GoalService) Contributions(goal Goal, loc *time.Location) ([]Contribution, error) {
+	contributions := []Contribution{}
+	err := service.DB.From("goal_contributions").Where(goqu.Ex{"goal_id": goal.ID}).ScanStructs(&contributions)
+	if err != nil {
+		return nil, err
+	}
+	for i := range contributions {
+		contributions[i].Source = SourceManual
+	}
+	if goal.Tag != nil {
+		var tagged []struct {
+			ID     int64   `db:"id"`
+			UserID int64   `db:"user_id"`
+			Amount float64 `db:"amount"`
+			Name   string  `db:"name"`
+			Date   string  `db:"local_date"`
+		}
+		localDate := goqu.L("strftime('%Y-%m-%d', local_time(date, ?))", loc.String())
+		err = service.DB.From("transactions").
+			Select("id", "user_id", goqu.L("-price").As("amount"), "name", localDate.As("local_date")).
+			Where(
+				goqu.C("ledger_id").Eq(goal.LedgerID),
+				goqu.L("EXISTS (SELECT 1 FROM json_each(tags) WHERE value = ?)", *goal.Tag),
+				localDate.Gte(goal.StartDate),
+			).
+			ScanStructs(&tagged)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tagged {
+			contributions = append(contributions, Contribution{
+				GoalID:        goal.ID,
+				UserID:        t.UserID,
+				Amount:        t.Amount,
+				Note:          t.Name,
+				Date:          t.Date,
+				Source:        SourceTransaction,
+				TransactionID: t.ID,
+			})
+		}
+	}
+	sort.SliceStable(contributions, func(i, j int) bool {
+		return contributions[i].Date < contributions[j].Date
+	})
+	return contributions, nil
+}
+
+// ComputeProgress works out how far a goal is, what it takes per month to reach it in time and when it will be reached
+// if the contributions of the last paceDays days go on at the same rate.
+func ComputeProgress(goal Goal, contributions []Contribution, now time.Time) Progress {
+	progress := Progress{Goal: goal}
+	today := now.Format(time.DateOnly)
+	paceStart := now.AddDate(0, 0, -paceDays).Format(time.DateOnly)
+	recent := 0.0
+	for _, contribution := range contributions {
+		progress.Saved += contribution.Amount
+		if contribution.Date > paceStart && contribution.Date <= today {
+			recent += contribution.Amount
+		}
+	}
+	progress.Remaining = max(goal.TargetAmount-progress.Saved, 0)
+	progress.Percentage = min(progress.Saved/goal.TargetAmount*100, 100)
+	progress.Completed = progress.Remaining == 0
+	progress.RecentMonthlyPace = recent / (paceDays / daysPerMonth)
+
+	targetDate, err := time.ParseInLocation(time.DateOnly, goal.TargetDate, now.Location())
+	if err == nil && !targetDate.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())) {
+		progress.MonthsLeft = (targetDate.Year()-now.Year())*12 + int(targetDate.Month()-now.Month()) + 1
+	}
+	progress.RequiredMonthly = progress.Remaining / float64(max(progress.MonthsLeft, 1))
+
+	if progress.Completed {
+		progress.OnTrack = true
+		return progress
+	}
+	if progress.RecentMonthlyPace > 0 {
+		days := math.Ceil(progress.Remaining / progress.RecentMonthlyPace * daysPerMonth)
+		projected := now.AddDate(0, 0, int(days)).Format(time.DateOnly)
+		progress.ProjectedCompletion = &projected
+		progress.OnTrack = projected <= goal.TargetDate
+	}
+	return progress
+}
+
+func (service *GoalService) GetProgress(ledgerID int64, id int64, loc *time.Location) (*GoalDetails, error) {
+	goal, err := service.Get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	contributions, err := service.Contributions(*goal, loc)
+	if err != nil {
+		return nil, err
+	}
+	return &GoalDetails{
+		Progress:      ComputeProgress(*goal, contributions, time.Now().In(loc)),
+		Contributions: contributions,
+	}, nil
+}
+
+func (service *GoalService) ListProgress(ledgerID int64, loc *time.Location) ([]Progress, error) {
+	goals, err := service.List(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Progress, 0, len(goals))
+	now := time.Now().In(loc)
+	for _, goal := range goals {
+		contributions, err := service.Contributions(goal, loc)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ComputeProgress(goal, contributions, now))
+	}
+	return result, nil
+}
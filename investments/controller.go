@@ -0,0 +1,267 @@
+package investments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"checkout-go/auth"
+	dto "checkout-go/investments/dtos"
+	"checkout-go/ledgers"
+	"checkout-go/settings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type InvestmentsController struct {
+	InvestmentService *InvestmentService
+	AuthService       auth.UserContextReader
+	LedgerContext     ledgers.LedgerContextReader
+	SettingsContext   settings.SettingsContextReader
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrTradeNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func (c *InvestmentsController) CreateSecurity(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var securityBody dto.CreateSecurityDTO
+	err = json.Unmarshal(body, &securityBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	security, err := c.InvestmentService.CreateSecurity(userID, ledgerID, securityBody)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(security)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *InvestmentsController) ListSecurities(w http.ResponseWriter, req *http.Request) {
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	securities, err := c.InvestmentService.ListSecurities(ledgerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(securities)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *InvestmentsController) UpdateSecurity(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var securityBody dto.UpdateSecurityDTO
+	err = json.Unmarshal(body, &securityBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	security, err := c.InvestmentService.UpdateSecurity(ledgerID, id, securityBody)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(security)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *InvestmentsController) DeleteSecurity(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	err = c.InvestmentService.DeleteSecurity(ledgerID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *InvestmentsController) AddTrade(w http.ResponseWriter, req *http.Request) {
+	securityID, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var tradeBody dto.CreateTradeDTO
+	err = json.Unmarshal(body, &tradeBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	trade, err := c.InvestmentService.AddTrade(userID, ledgerID, securityID, tradeBody, loc)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(trade)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *InvestmentsController) ListTrades(w http.ResponseWriter, req *http.Request) {
+	securityID, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	trades, err := c.InvestmentService.ListTrades(ledgerID, securityID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(trades)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *InvestmentsController) DeleteTrade(w http.ResponseWriter, req *http.Request) {
+	securityID, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	tradeID, err := strconv.ParseInt(chi.URLParam(req, "tradeID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	err = c.InvestmentService.DeleteTrade(ledgerID, securityID, tradeID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *InvestmentsController) ListPrices(w http.ResponseWriter, req *http.Request) {
+	securityID, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	prices, err := c.InvestmentService.ListPrices(ledgerID, securityID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(prices)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ImportPrices takes a CSV file as the request body.
+func (c *InvestmentsController) ImportPrices(w http.ResponseWriter, req *http.Request) {
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	imported, err := c.InvestmentService.ImportPrices(ledgerID, req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(map[string]int{"imported": imported})
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *InvestmentsController) GetPortfolio(w http.ResponseWriter, req *http.Request) {
+	method := CostBasisMethod(req.URL.Query().Get("method"))
+	if method == "" {
+		method = CostBasisFIFO
+	}
+	date := req.URL.Query().Get("date")
+	if date == "" {
+		date = time.Now().In(c.SettingsContext.GetSettingsFromRequest(req).Location()).Format(time.DateOnly)
+	} else if _, err := time.Parse(time.DateOnly, date); err != nil {
+		http.Error(w, "Invalid date", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	portfolio, err := c.InvestmentService.Portfolio(ledgerID, method, date)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(portfolio)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,67 @@
+package investments
+
+import "fmt"
+
+// epsilon absorbs the rounding of fractional quantities when a position is sold off completely.
+const epsilon = 1e-9
+
+type lot struct {
+	quantity float64
+	unitCost float64
+}
+
+// position is what replaying the trades of one security leaves behind.
+type position struct {
+	quantity  float64
+	costBasis float64
+	realized  float64
+}
+
+// replay goes through trades in order and returns the resulting position. A buy's fees are part of its cost,
+// a sell's fees lower its proceeds. It fails when a sell is larger than what was held at that point.
+func replay(trades []Trade, method CostBasisMethod) (position, error) {
+	var pos position
+	var lots []lot
+	for _, trade := range trades {
+		if trade.Side == SideBuy {
+			cost := trade.Quantity*trade.Price + trade.Fees
+			pos.quantity += trade.Quantity
+			pos.costBasis += cost
+			lots = append(lots, lot{quantity: trade.Quantity, unitCost: cost / trade.Quantity})
+			continue
+		}
+		if trade.Quantity > pos.quantity+epsilon {
+			return position{}, fmt.Errorf("selling %g on %s but only %g are held", trade.Quantity, trade.Date, pos.quantity)
+		}
+		proceeds := trade.Quantity*trade.Price - trade.Fees
+		var cost float64
+		switch method {
+		case CostBasisAverage:
+			cost = pos.costBasis * trade.Quantity / pos.quantity
+		default:
+			remaining := trade.Quantity
+			for remaining > epsilon && len(lots) > 0 {
+				taken := min(remaining, lots[0].quantity)
+				cost += taken * lots[0].unitCost
+				lots[0].quantity -= taken
+				remaining -= taken
+				if lots[0].quantity <= epsilon {
+					lots = lots[1:]
+				}
+			}
+		}
+		pos.quantity -= trade.Quantity
+		pos.costBasis -= cost
+		pos.realized += proceeds - cost
+		if pos.quantity <= epsilon {
+			pos.quantity = 0
+			pos.costBasis = 0
+			lots = nil
+		}
+	}
+	return pos, nil
+}
+
+func validMethod(method CostBasisMethod) bool {
+	return method == CostBasisFIFO || method == CostBasisAverage
+}
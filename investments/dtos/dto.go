@@ -0,0 +1,19 @@
+package investments
+
+type CreateSecurityDTO struct {
+	Symbol     string `json:"symbol"`
+	Name       string `json:"name"`
+	AssetClass string `json:"assetClass"`
+}
+
+type UpdateSecurityDTO CreateSecurityDTO
+
+type CreateTradeDTO struct {
+	Side     string  `json:"side"`
+	Quantity float64 `json:"quantity"`
+	Price    float64 `json:"price"`
+	Fees     float64 `json:"fees"`
+	Date     string  `json:"date"`
+	// CreateTransaction books the cash leaving for a buy or arriving for a sell as a transaction
+	CreateTransaction bool `json:"createTransaction"`
+}
@@ -0,0 +1,80 @@
+package investments
+
+type Side string
+
+const (
+	SideBuy  Side = "buy"
+	SideSell Side = "sell"
+)
+
+type CostBasisMethod string
+
+const (
+	// CostBasisFIFO sells the oldest lots first.
+	CostBasisFIFO CostBasisMethod = "fifo"
+	// CostBasisAverage spreads the cost of all shares held evenly.
+	CostBasisAverage CostBasisMethod = "average"
+)
+
+type Security struct {
+	ID         int64  `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID   int64  `db:"ledger_id" json:"ledgerId"`
+	UserID     int64  `db:"user_id" json:"userId"`
+	Symbol     string `db:"symbol" json:"symbol"`
+	Name       string `db:"name" json:"name"`
+	AssetClass string `db:"asset_class" json:"assetClass"`
+	Date       string `db:"date" json:"date"`
+}
+
+// Trade is a buy or sell lot. Price is per unit, Fees are for the whole trade.
+type Trade struct {
+	ID            int64   `db:"id" goqu:"skipinsert" json:"id"`
+	SecurityID    int64   `db:"security_id" json:"securityId"`
+	UserID        int64   `db:"user_id" json:"userId"`
+	Side          Side    `db:"side" json:"side"`
+	Quantity      float64 `db:"quantity" json:"quantity"`
+	Price         float64 `db:"price" json:"price"`
+	Fees          float64 `db:"fees" json:"fees"`
+	Date          string  `db:"trade_date" json:"date"`
+	TransactionID *int64  `db:"transaction_id" json:"transactionId"`
+	Created       string  `db:"date" json:"-"`
+}
+
+type Price struct {
+	SecurityID int64   `db:"security_id" json:"securityId"`
+	Date       string  `db:"price_date" json:"date"`
+	Price      float64 `db:"price" json:"price"`
+}
+
+// Holding is the position in one security. Price is the latest known price, the last trade price when
+// no price was loaded.
+type Holding struct {
+	SecurityID     int64   `json:"securityId"`
+	Symbol         string  `json:"symbol"`
+	Name           string  `json:"name"`
+	AssetClass     string  `json:"assetClass"`
+	Quantity       float64 `json:"quantity"`
+	Price          float64 `json:"price"`
+	PriceDate      *string `json:"priceDate"`
+	MarketValue    float64 `json:"marketValue"`
+	CostBasis      float64 `json:"costBasis"`
+	RealizedGain   float64 `json:"realizedGain"`
+	UnrealizedGain float64 `json:"unrealizedGain"`
+}
+
+type Allocation struct {
+	AssetClass  string  `json:"assetClass"`
+	MarketValue float64 `json:"marketValue"`
+	Percentage  float64 `json:"percentage"`
+}
+
+type Portfolio struct {
+	Method         CostBasisMethod `json:"method"`
+	Date           string          `json:"date"`
+	MarketValue    float64         `json:"marketValue"`
+	CostBasis      float64         `json:"costBasis"`
+	RealizedGain   float64         `json:"realizedGain"`
+	UnrealizedGain float64         `json:"unrealizedGain"`
+	Holdings       []Holding       `json:"holdings"`
+	Allocation     []Allocation    `json:"allocation"`
+}
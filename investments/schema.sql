@@ -0,0 +1,32 @@
+CREATE TABLE securities (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ledger_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    symbol TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    asset_class TEXT NOT NULL DEFAULT '',
+    date TEXT NOT NULL,
+    UNIQUE (ledger_id, symbol)
+);
+
+CREATE TABLE security_trades (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    security_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    side TEXT NOT NULL CHECK (side IN ('buy', 'sell')),
+    quantity REAL NOT NULL CHECK (quantity > 0),
+    price REAL NOT NULL CHECK (price >= 0),
+    fees REAL NOT NULL DEFAULT 0 CHECK (fees >= 0),
+    trade_date TEXT NOT NULL,
+    transaction_id INTEGER,
+    date TEXT NOT NULL
+);
+
+CREATE INDEX security_trades_security_id ON security_trades (security_id, trade_date);
+
+CREATE TABLE security_prices (
+    security_id INTEGER NOT NULL,
+    price_date TEXT NOT NULL,
+    price REAL NOT NULL,
+    PRIMARY KEY (security_id, price_date)
+);
@@ -0,0 +1,471 @@
+package investments
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dtos "checkout-go/investments/dtos"
+	"checkout-go/transactions"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+var (
+	ErrNotFound      = errors.New("security not found")
+	ErrTradeNotFound = errors.New("trade not found")
+)
+
+// InvestmentsTag is put on the cash transactions created for trades.
+const InvestmentsTag = "investments"
+
+type InvestmentService struct {
+	DB                  *goqu.Database
+	TransactionsService *transactions.TransactionService
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func validateSecurity(body dtos.CreateSecurityDTO) error {
+	if strings.TrimSpace(body.Symbol) == "" {
+		return errors.New("symbol cannot be empty")
+	}
+	return nil
+}
+
+func (service *InvestmentService) CreateSecurity(userID int64, ledgerID int64, body dtos.CreateSecurityDTO) (*Security, error) {
+	if err := validateSecurity(body); err != nil {
+		return nil, err
+	}
+	security := Security{
+		LedgerID:   ledgerID,
+		UserID:     userID,
+		Symbol:     strings.ToUpper(strings.TrimSpace(body.Symbol)),
+		Name:       body.Name,
+		AssetClass: body.AssetClass,
+		Date:       time.Now().Format(time.RFC3339),
+	}
+	if _, err := service.GetSecurityBySymbol(ledgerID, security.Symbol); err == nil {
+		return nil, fmt.Errorf("security %s already exists", security.Symbol)
+	}
+	result, err := service.DB.Insert("securities").Rows(security).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting security: %w", err)
+	}
+	security.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &security, nil
+}
+
+func (service *InvestmentService) GetSecurity(ledgerID int64, id int64) (*Security, error) {
+	var security Security
+	found, err := service.DB.From("securities").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).ScanStruct(&security)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &security, nil
+}
+
+func (service *InvestmentService) GetSecurityBySymbol(ledgerID int64, symbol string) (*Security, error) {
+	var security Security
+	found, err := service.DB.From("securities").
+		Where(goqu.Ex{"ledger_id": ledgerID, "symbol": strings.ToUpper(strings.TrimSpace(symbol))}).
+		ScanStruct(&security)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &security, nil
+}
+
+func (service *InvestmentService) ListSecurities(ledgerID int64) ([]Security, error) {
+	securities := []Security{}
+	err := service.DB.From("securities").
+		Where(goqu.Ex{"ledger_id": ledgerID}).
+		Order(goqu.C("symbol").Asc()).
+		ScanStructs(&securities)
+	if err != nil {
+		return nil, err
+	}
+	return securities, nil
+}
+
+func (service *InvestmentService) UpdateSecurity(ledgerID int64, id int64, body dtos.UpdateSecurityDTO) (*Security, error) {
+	if err := validateSecurity(dtos.CreateSecurityDTO(body)); err != nil {
+		return nil, err
+	}
+	security, err := service.GetSecurity(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	symbol := strings.ToUpper(strings.TrimSpace(body.Symbol))
+	if other, err := service.GetSecurityBySymbol(ledgerID, symbol); err == nil && other.ID != id {
+		return nil, fmt.Errorf("security %s already exists", symbol)
+	}
+	security.Symbol = symbol
+	security.Name = body.Name
+	security.AssetClass = body.AssetClass
+	_, err = service.DB.Update("securities").
+		Set(goqu.Record{"symbol": security.Symbol, "name": security.Name, "asset_class": security.AssetClass}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).
+		Executor().Exec()
+	if err != nil {
+		return nil, err
+	}
+	return security, nil
+}
+
+// DeleteSecurity removes a security and its price history. Securities with trades cannot be deleted,
+// the trades have to go first so that their cash transactions are removed as well.
+func (service *InvestmentService) DeleteSecurity(ledgerID int64, id int64) error {
+	if _, err := service.GetSecurity(ledgerID, id); err != nil {
+		return err
+	}
+	var trades int64
+	_, err := service.DB.From("security_trades").Select(goqu.COUNT("*")).Where(goqu.Ex{"security_id": id}).ScanVal(&trades)
+	if err != nil {
+		return err
+	}
+	if trades > 0 {
+		return errors.New("security still has trades")
+	}
+	return service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		if _, err := tx.Delete("security_prices").Where(goqu.Ex{"security_id": id}).Executor().Exec(); err != nil {
+			return err
+		}
+		_, err := tx.Delete("securities").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+		return err
+	})
+}
+
+func (service *InvestmentService) trades(securityID int64) ([]Trade, error) {
+	trades := []Trade{}
+	err := service.DB.From("security_trades").
+		Where(goqu.Ex{"security_id": securityID}).
+		Order(goqu.C("trade_date").Asc(), goqu.C("id").Asc()).
+		ScanStructs(&trades)
+	return trades, err
+}
+
+func (service *InvestmentService) ListTrades(ledgerID int64, securityID int64) ([]Trade, error) {
+	if _, err := service.GetSecurity(ledgerID, securityID); err != nil {
+		return nil, err
+	}
+	return service.trades(securityID)
+}
+
+// AddTrade records a buy or sell. With CreateTransaction set the cash side is booked with it: a buy becomes an
+// expense of quantity times price plus fees, a sell a payment of quantity times price minus fees, or an expense
+// when the fees are more than the proceeds.
+func (service *InvestmentService) AddTrade(userID int64, ledgerID int64, securityID int64, body dtos.CreateTradeDTO, loc *time.Location) (*Trade, error) {
+	security, err := service.GetSecurity(ledgerID, securityID)
+	if err != nil {
+		return nil, err
+	}
+	side := Side(body.Side)
+	if side != SideBuy && side != SideSell {
+		return nil, fmt.Errorf("side must be %q or %q", SideBuy, SideSell)
+	}
+	if body.Quantity <= 0 {
+		return nil, errors.New("quantity must be greater than 0")
+	}
+	if body.Price < 0 || body.Fees < 0 {
+		return nil, errors.New("price and fees cannot be negative")
+	}
+	if body.Date == "" {
+		body.Date = time.Now().In(loc).Format(time.DateOnly)
+	}
+	date, err := time.ParseInLocation(time.DateOnly, body.Date, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date: %w", err)
+	}
+	trade := Trade{
+		SecurityID: securityID,
+		UserID:     userID,
+		Side:       side,
+		Quantity:   body.Quantity,
+		Price:      body.Price,
+		Fees:       body.Fees,
+		Date:       body.Date,
+		Created:    time.Now().Format(time.RFC3339),
+	}
+	existing, err := service.trades(securityID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := replay(sortTrades(append(existing, trade)), CostBasisFIFO); err != nil {
+		return nil, err
+	}
+
+	var entry *transactions.NewTransaction
+	if body.CreateTransaction {
+		amount := roundCents(body.Quantity * body.Price)
+		entry = &transactions.NewTransaction{
+			UserID:   int(userID),
+			LedgerID: ledgerID,
+			Note:     security.Name,
+			Date:     date,
+			Tags:     []string{InvestmentsTag},
+		}
+		if side == SideBuy {
+			entry.Name = "Buy " + security.Symbol
+			entry.Price = -roundCents(amount + body.Fees)
+		} else {
+			entry.Name = "Sell " + security.Symbol
+			entry.Price = roundCents(amount - body.Fees)
+		}
+		// A trade that moves no cash, like a sell whose fees eat up the proceeds exactly, books nothing
+		if entry.Price == 0 {
+			entry = nil
+		}
+	}
+	err = service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		if entry != nil {
+			transaction, err := service.TransactionsService.CreateInTx(tx, *entry, loc)
+			if err != nil {
+				return err
+			}
+			transactionID := int64(transaction.ID)
+			trade.TransactionID = &transactionID
+		}
+		result, err := tx.Insert("security_trades").Rows(trade).Executor().Exec()
+		if err != nil {
+			return fmt.Errorf("err in inserting trade: %w", err)
+		}
+		trade.ID, err = result.LastInsertId()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &trade, nil
+}
+
+// DeleteTrade removes a trade together with the cash transaction created for it.
+func (service *InvestmentService) DeleteTrade(ledgerID int64, securityID int64, tradeID int64) error {
+	if _, err := service.GetSecurity(ledgerID, securityID); err != nil {
+		return err
+	}
+	trades, err := service.trades(securityID)
+	if err != nil {
+		return err
+	}
+	var deleted *Trade
+	remaining := make([]Trade, 0, len(trades))
+	for i := range trades {
+		if trades[i].ID == tradeID {
+			deleted = &trades[i]
+			continue
+		}
+		remaining = append(remaining, trades[i])
+	}
+	if deleted == nil {
+		return ErrTradeNotFound
+	}
+	if _, err := replay(remaining, CostBasisFIFO); err != nil {
+		return fmt.Errorf("cannot delete trade: %w", err)
+	}
+	return service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		_, err := tx.Delete("security_trades").Where(goqu.Ex{"security_id": securityID, "id": tradeID}).Executor().Exec()
+		if err != nil {
+			return err
+		}
+		if deleted.TransactionID != nil {
+			return service.TransactionsService.DeleteInTx(tx, ledgerID, int(*deleted.TransactionID))
+		}
+		return nil
+	})
+}
+
+func sortTrades(trades []Trade) []Trade {
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Date < trades[j].Date
+	})
+	return trades
+}
+
+func (service *InvestmentService) ListPrices(ledgerID int64, securityID int64) ([]Price, error) {
+	if _, err := service.GetSecurity(ledgerID, securityID); err != nil {
+		return nil, err
+	}
+	prices := []Price{}
+	err := service.DB.From("security_prices").
+		Where(goqu.Ex{"security_id": securityID}).
+		Order(goqu.C("price_date").Asc()).
+		ScanStructs(&prices)
+	if err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+// ImportPrices loads a price history from CSV with the columns symbol, date and price, in any order and named
+// in a header row. A price already known for a security and day is replaced. Nothing is stored when a row is
+// invalid or names a symbol the ledger does not hold.
+func (service *InvestmentService) ImportPrices(ledgerID int64, data io.Reader) (int, error) {
+	reader := csv.NewReader(data)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("could not read header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"symbol", "date", "price"} {
+		if _, ok := columns[name]; !ok {
+			return 0, fmt.Errorf("missing column %q", name)
+		}
+	}
+	securities, err := service.ListSecurities(ledgerID)
+	if err != nil {
+		return 0, err
+	}
+	bySymbol := make(map[string]int64, len(securities))
+	for _, security := range securities {
+		bySymbol[security.Symbol] = security.ID
+	}
+	var prices []Price
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		symbol := strings.ToUpper(strings.TrimSpace(record[columns["symbol"]]))
+		securityID, ok := bySymbol[symbol]
+		if !ok {
+			return 0, fmt.Errorf("line %d: unknown symbol %q", line, symbol)
+		}
+		date := strings.TrimSpace(record[columns["date"]])
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return 0, fmt.Errorf("line %d: invalid date: %w", line, err)
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[columns["price"]]), 64)
+		if err != nil || price < 0 {
+			return 0, fmt.Errorf("line %d: invalid price %q", line, record[columns["price"]])
+		}
+		prices = append(prices, Price{SecurityID: securityID, Date: date, Price: price})
+	}
+	if len(prices) == 0 {
+		return 0, nil
+	}
+	err = service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		for _, price := range prices {
+			_, err := tx.Insert("security_prices").
+				Rows(price).
+				OnConflict(goqu.DoUpdate("security_id, price_date", goqu.Record{"price": price.Price})).
+				Executor().Exec()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(prices), nil
+}
+
+// Portfolio values every holding of the ledger at the end of date, using the cost basis method given.
+// Securities that were sold off completely still show up for their realized gains.
+func (service *InvestmentService) Portfolio(ledgerID int64, method CostBasisMethod, date string) (*Portfolio, error) {
+	if !validMethod(method) {
+		return nil, fmt.Errorf("cost basis method must be %q or %q", CostBasisFIFO, CostBasisAverage)
+	}
+	securities, err := service.ListSecurities(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	portfolio := Portfolio{
+		Method:     method,
+		Date:       date,
+		Holdings:   []Holding{},
+		Allocation: []Allocation{},
+	}
+	byClass := make(map[string]float64)
+	for _, security := range securities {
+		var trades []Trade
+		err := service.DB.From("security_trades").
+			Where(goqu.C("security_id").Eq(security.ID), goqu.C("trade_date").Lte(date)).
+			Order(goqu.C("trade_date").Asc(), goqu.C("id").Asc()).
+			ScanStructs(&trades)
+		if err != nil {
+			return nil, err
+		}
+		if len(trades) == 0 {
+			continue
+		}
+		pos, err := replay(trades, method)
+		if err != nil {
+			return nil, err
+		}
+		holding := Holding{
+			SecurityID:   security.ID,
+			Symbol:       security.Symbol,
+			Name:         security.Name,
+			AssetClass:   security.AssetClass,
+			Quantity:     pos.quantity,
+			Price:        trades[len(trades)-1].Price,
+			CostBasis:    pos.costBasis,
+			RealizedGain: pos.realized,
+		}
+		var price Price
+		found, err := service.DB.From("security_prices").
+			Where(goqu.C("security_id").Eq(security.ID), goqu.C("price_date").Lte(date)).
+			Order(goqu.C("price_date").Desc()).
+			ScanStruct(&price)
+		if err != nil {
+			return nil, err
+		}
+		lastTrade := trades[len(trades)-1].Date
+		if found && price.Date >= lastTrade {
+			holding.Price = price.Price
+			holding.PriceDate = &price.Date
+		} else {
+			holding.PriceDate = &lastTrade
+		}
+		holding.MarketValue = holding.Quantity * holding.Price
+		holding.UnrealizedGain = holding.MarketValue - holding.CostBasis
+
+		portfolio.Holdings = append(portfolio.Holdings, holding)
+		portfolio.MarketValue += holding.MarketValue
+		portfolio.CostBasis += holding.CostBasis
+		portfolio.RealizedGain += holding.RealizedGain
+		portfolio.UnrealizedGain += holding.UnrealizedGain
+		byClass[holding.AssetClass] += holding.MarketValue
+	}
+	for class, value := range byClass {
+		allocation := Allocation{AssetClass: class, MarketValue: value}
+		if portfolio.MarketValue > 0 {
+			allocation.Percentage = value / portfolio.MarketValue * 100
+		}
+		portfolio.Allocation = append(portfolio.Allocation, allocation)
+	}
+	sort.Slice(portfolio.Allocation, func(i, j int) bool {
+		if portfolio.Allocation[i].MarketValue != portfolio.Allocation[j].MarketValue {
+			return portfolio.Allocation[i].MarketValue > portfolio.Allocation[j].MarketValue
+		}
+		return portfolio.Allocation[i].AssetClass < portfolio.Allocation[j].AssetClass
+	})
+	return &portfolio, nil
+}
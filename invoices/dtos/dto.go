@@ -0,0 +1,35 @@
+package invoices
+
+type CreateClientDTO struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Address string `json:"address"`
+}
+
+type UpdateClientDTO CreateClientDTO
+
+type ItemDTO struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	UnitPrice   float64 `json:"unitPrice"`
+}
+
+type CreateInvoiceDTO struct {
+	ClientID int64 `json:"clientId"`
+	// Number is given out in sequence when empty
+	Number string `json:"number"`
+	// IssueDate defaults to today and DueDate to 30 days after it
+	IssueDate string `json:"issueDate"`
+	DueDate   string `json:"dueDate"`
+	// TaxRate is the VAT or sales tax in percent added to the items
+	TaxRate float64   `json:"taxRate"`
+	Notes   string    `json:"notes"`
+	Items   []ItemDTO `json:"items"`
+}
+
+type UpdateInvoiceDTO CreateInvoiceDTO
+
+type PayInvoiceDTO struct {
+	// Date is when the money came in, today when empty
+	Date string `json:"date"`
+}
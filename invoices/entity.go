@@ -0,0 +1,94 @@
+package invoices
+
+import "checkout-go/transactions"
+
+type Status string
+
+const (
+	StatusDraft Status = "draft"
+	StatusSent  Status = "sent"
+	StatusPaid  Status = "paid"
+	// StatusOverdue is never stored, it is a sent invoice past its due date.
+	StatusOverdue Status = "overdue"
+)
+
+type Client struct {
+	ID       int64  `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID int64  `db:"ledger_id" json:"ledgerId"`
+	UserID   int64  `db:"user_id" json:"userId"`
+	Name     string `db:"name" json:"name"`
+	Email    string `db:"email" json:"email"`
+	Address  string `db:"address" json:"address"`
+	Date     string `db:"date" json:"date"`
+}
+
+// Invoice is a bill to a client. Its income is only booked, as a payment, once the invoice is paid.
+type Invoice struct {
+	ID        int64   `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID  int64   `db:"ledger_id" json:"ledgerId"`
+	UserID    int64   `db:"user_id" json:"userId"`
+	ClientID  int64   `db:"client_id" json:"clientId"`
+	Number    string  `db:"number" json:"number"`
+	Status    Status  `db:"status" json:"status"`
+	IssueDate string  `db:"issue_date" json:"issueDate"`
+	DueDate   string  `db:"due_date" json:"dueDate"`
+	TaxRate   float64 `db:"tax_rate" json:"taxRate"`
+	Subtotal  float64 `db:"subtotal" json:"subtotal"`
+	Tax       float64 `db:"tax" json:"tax"`
+	Total     float64 `db:"total" json:"total"`
+	Notes     string  `db:"notes" json:"notes"`
+	PaymentID *int64  `db:"payment_id" json:"paymentId,omitempty"`
+	SentAt    *string `db:"sent_at" json:"sentAt,omitempty"`
+	PaidAt    *string `db:"paid_at" json:"paidAt,omitempty"`
+	Date      string  `db:"date" json:"date"`
+}
+
+type Item struct {
+	ID          int64   `db:"id" goqu:"skipinsert" json:"id"`
+	InvoiceID   int64   `db:"invoice_id" json:"invoiceId"`
+	Position    int     `db:"position" json:"position"`
+	Description string  `db:"description" json:"description"`
+	Quantity    float64 `db:"quantity" json:"quantity"`
+	UnitPrice   float64 `db:"unit_price" json:"unitPrice"`
+	Amount      float64 `db:"amount" json:"amount"`
+}
+
+type InvoiceDetails struct {
+	Invoice
+	Client  Client                    `json:"client"`
+	Items   []Item                    `json:"items"`
+	Payment *transactions.Transaction `json:"payment,omitempty"`
+}
+
+// ClientReceivable sums what a client owes on invoices that are sent and not paid yet.
+type ClientReceivable struct {
+	ClientID int64   `json:"clientId"`
+	Name     string  `json:"name"`
+	Count    int     `json:"count"`
+	Total    float64 `json:"total"`
+	Overdue  float64 `json:"overdue"`
+}
+
+type Receivables struct {
+	Total    float64            `json:"total"`
+	Overdue  float64            `json:"overdue"`
+	Clients  []ClientReceivable `json:"clients"`
+	Invoices []Invoice          `json:"invoices"`
+}
+
+// AgingBucket holds the outstanding invoices that are overdue by MinDays to MaxDays days. The first bucket
+// holds the invoices that are not due yet, the last one has no MaxDays.
+type AgingBucket struct {
+	Label    string    `json:"label"`
+	MinDays  int       `json:"minDays"`
+	MaxDays  *int      `json:"maxDays"`
+	Count    int       `json:"count"`
+	Total    float64   `json:"total"`
+	Invoices []Invoice `json:"invoices"`
+}
+
+type Aging struct {
+	AsOf    string        `json:"asOf"`
+	Total   float64       `json:"total"`
+	Buckets []AgingBucket `json:"buckets"`
+}
@@ -0,0 +1,110 @@
+package invoices
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+
+	"checkout-go/pdf"
+)
+
+func formatAmount(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+func formatQuantity(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func statusLabel(status Status) string {
+	return strings.ToUpper(string(status[:1])) + string(status[1:])
+}
+
+// WritePDF writes an invoice as a printable document: the client, the dates, the items in a table and the
+// totals with the tax.
+func WritePDF(w io.Writer, invoice *InvoiceDetails) error {
+	const quantityWidth, amountWidth = 10, 12
+	descriptionWidth := pdf.MonoColumns - quantityWidth - 2*amountWidth
+	row := func(description, quantity, unitPrice, amount string) string {
+		return pdf.Fit(description, descriptionWidth-1) + " " + fmt.Sprintf("%*s%*s%*s", quantityWidth, quantity, amountWidth, unitPrice, amountWidth, amount)
+	}
+
+	doc := pdf.New()
+	doc.Heading("Invoice " + invoice.Number)
+	doc.Text("Issued on " + invoice.IssueDate)
+	doc.Text("Due on " + invoice.DueDate)
+	doc.Text("Status: " + statusLabel(invoice.Status))
+	doc.Space()
+	doc.Bold("Bill to")
+	doc.Text(invoice.Client.Name)
+	for _, line := range strings.Split(invoice.Client.Address, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			doc.Text(line)
+		}
+	}
+	if invoice.Client.Email != "" {
+		doc.Text(invoice.Client.Email)
+	}
+	doc.Space()
+	doc.Mono(row("Description", "Quantity", "Unit price", "Amount"))
+	doc.Mono(strings.Repeat("-", pdf.MonoColumns))
+	for _, item := range invoice.Items {
+		doc.Mono(row(item.Description, formatQuantity(item.Quantity), formatAmount(item.UnitPrice), formatAmount(item.Amount)))
+	}
+	doc.Mono(strings.Repeat("-", pdf.MonoColumns))
+	doc.Mono(row("Subtotal", "", "", formatAmount(invoice.Subtotal)))
+	if invoice.TaxRate > 0 {
+		doc.Mono(row("Tax "+formatQuantity(invoice.TaxRate)+"%", "", "", formatAmount(invoice.Tax)))
+	}
+	doc.Mono(row("Total", "", "", formatAmount(invoice.Total)))
+	if invoice.Notes != "" {
+		doc.Space()
+		for _, line := range strings.Split(invoice.Notes, "\n") {
+			doc.Text(line)
+		}
+	}
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"amount":   formatAmount,
+	"quantity": formatQuantity,
+	"status":   statusLabel,
+	"lines":    func(text string) []string { return strings.Split(strings.TrimSpace(text), "\n") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 14px; margin: 40px; }
+table { border-collapse: collapse; width: 100%; margin-top: 24px; }
+th, td { padding: 6px 8px; border-bottom: 1px solid #ddd; text-align: left; }
+.number { text-align: right; }
+.total td { font-weight: bold; border-bottom: none; }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Number}}</h1>
+<p>Issued on {{.IssueDate}}<br>Due on {{.DueDate}}<br>Status: {{status .Status}}</p>
+<h3>Bill to</h3>
+<p>{{.Client.Name}}{{range lines .Client.Address}}{{if .}}<br>{{.}}{{end}}{{end}}{{if .Client.Email}}<br>{{.Client.Email}}{{end}}</p>
+<table>
+<tr><th>Description</th><th class="number">Quantity</th><th class="number">Unit price</th><th class="number">Amount</th></tr>
+{{range .Items}}<tr><td>{{.Description}}</td><td class="number">{{quantity .Quantity}}</td><td class="number">{{amount .UnitPrice}}</td><td class="number">{{amount .Amount}}</td></tr>
+{{end}}<tr><td colspan="3">Subtotal</td><td class="number">{{amount .Subtotal}}</td></tr>
+{{if gt .TaxRate 0.0}}<tr><td colspan="3">Tax {{quantity .TaxRate}}%</td><td class="number">{{amount .Tax}}</td></tr>
+{{end}}<tr class="total"><td colspan="3">Total</td><td class="number">{{amount .Total}}</td></tr>
+</table>
+{{if .Notes}}<p>{{range $i, $line := lines .Notes}}{{if $i}}<br>{{end}}{{$line}}{{end}}</p>
+{{end}}</body>
+</html>
+`))
+
+// WriteHTML writes an invoice as a web page with the same content as the PDF.
+func WriteHTML(w io.Writer, invoice *InvoiceDetails) error {
+	return invoiceTemplate.Execute(w, invoice)
+}
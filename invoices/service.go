@@ -0,0 +1,624 @@
+package invoices
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	dto "checkout-go/invoices/dtos"
+	"checkout-go/transactions"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+var (
+	ErrNotFound       = errors.New("invoice not found")
+	ErrClientNotFound = errors.New("client not found")
+)
+
+// InvoicesTag is put on the payments booked for paid invoices.
+const InvoicesTag = "invoices"
+
+// defaultTermDays is how long a client has to pay when an invoice has no due date.
+const defaultTermDays = 30
+
+type InvoiceService struct {
+	DB                  *goqu.Database
+	TransactionsService *transactions.TransactionService
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func today(loc *time.Location) string {
+	return time.Now().In(loc).Format(time.DateOnly)
+}
+
+// settle shows a sent invoice that is past its due date as overdue.
+func (invoice *Invoice) settle(today string) {
+	if invoice.Status == StatusSent && invoice.DueDate < today {
+		invoice.Status = StatusOverdue
+	}
+}
+
+func validateClient(body dto.CreateClientDTO) error {
+	if strings.TrimSpace(body.Name) == "" {
+		return errors.New("name cannot be empty")
+	}
+	return nil
+}
+
+func (service *InvoiceService) CreateClient(userID int64, ledgerID int64, body dto.CreateClientDTO) (*Client, error) {
+	if err := validateClient(body); err != nil {
+		return nil, err
+	}
+	client := Client{
+		LedgerID: ledgerID,
+		UserID:   userID,
+		Name:     strings.TrimSpace(body.Name),
+		Email:    strings.TrimSpace(body.Email),
+		Address:  strings.TrimSpace(body.Address),
+		Date:     time.Now().Format(time.RFC3339),
+	}
+	result, err := service.DB.Insert("clients").Rows(client).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting client: %w", err)
+	}
+	client.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (service *InvoiceService) ListClients(ledgerID int64) ([]Client, error) {
+	clients := []Client{}
+	err := service.DB.From("clients").
+		Where(goqu.C("ledger_id").Eq(ledgerID)).
+		Order(goqu.C("name").Asc()).
+		ScanStructs(&clients)
+	if err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+func (service *InvoiceService) getClient(ledgerID int64, id int64) (*Client, error) {
+	var client Client
+	found, err := service.DB.From("clients").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).ScanStruct(&client)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrClientNotFound
+	}
+	return &client, nil
+}
+
+func (service *InvoiceService) UpdateClient(ledgerID int64, id int64, body dto.UpdateClientDTO) (*Client, error) {
+	if err := validateClient(dto.CreateClientDTO(body)); err != nil {
+		return nil, err
+	}
+	client, err := service.getClient(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	client.Name = strings.TrimSpace(body.Name)
+	client.Email = strings.TrimSpace(body.Email)
+	client.Address = strings.TrimSpace(body.Address)
+	_, err = service.DB.Update("clients").
+		Set(goqu.Record{"name": client.Name, "email": client.Email, "address": client.Address}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).
+		Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update client: %w", err)
+	}
+	return client, nil
+}
+
+// DeleteClient removes a client that was never invoiced.
+func (service *InvoiceService) DeleteClient(ledgerID int64, id int64) error {
+	if _, err := service.getClient(ledgerID, id); err != nil {
+		return err
+	}
+	count, err := service.DB.From("invoices").Where(goqu.Ex{"ledger_id": ledgerID, "client_id": id}).Count()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("client has %d invoices", count)
+	}
+	_, err = service.DB.Delete("clients").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+	return err
+}
+
+// prepare checks an invoice and works out its items and amounts. Tax is added on top of the items.
+func (service *InvoiceService) prepare(ledgerID int64, body dto.CreateInvoiceDTO, loc *time.Location) (*Invoice, []Item, error) {
+	if _, err := service.getClient(ledgerID, body.ClientID); err != nil {
+		return nil, nil, err
+	}
+	if body.TaxRate < 0 || body.TaxRate > 100 {
+		return nil, nil, errors.New("tax rate must be between 0 and 100")
+	}
+	if len(body.Items) == 0 {
+		return nil, nil, errors.New("an invoice needs at least one item")
+	}
+	invoice := Invoice{
+		LedgerID:  ledgerID,
+		ClientID:  body.ClientID,
+		Number:    strings.TrimSpace(body.Number),
+		IssueDate: body.IssueDate,
+		DueDate:   body.DueDate,
+		TaxRate:   body.TaxRate,
+		Notes:     body.Notes,
+	}
+	if invoice.IssueDate == "" {
+		invoice.IssueDate = today(loc)
+	}
+	issued, err := time.Parse(time.DateOnly, invoice.IssueDate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid issue date: %w", err)
+	}
+	if invoice.DueDate == "" {
+		invoice.DueDate = issued.AddDate(0, 0, defaultTermDays).Format(time.DateOnly)
+	}
+	if _, err := time.Parse(time.DateOnly, invoice.DueDate); err != nil {
+		return nil, nil, fmt.Errorf("invalid due date: %w", err)
+	}
+	if invoice.DueDate < invoice.IssueDate {
+		return nil, nil, errors.New("due date cannot be before issue date")
+	}
+	items := make([]Item, 0, len(body.Items))
+	for i, item := range body.Items {
+		if strings.TrimSpace(item.Description) == "" {
+			return nil, nil, fmt.Errorf("item %d: description cannot be empty", i+1)
+		}
+		if item.Quantity <= 0 {
+			return nil, nil, fmt.Errorf("item %d: quantity must be greater than 0", i+1)
+		}
+		if item.UnitPrice < 0 {
+			return nil, nil, fmt.Errorf("item %d: unit price cannot be negative", i+1)
+		}
+		amount := roundCents(item.Quantity * item.UnitPrice)
+		items = append(items, Item{
+			Position:    i + 1,
+			Description: strings.TrimSpace(item.Description),
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Amount:      amount,
+		})
+		invoice.Subtotal += amount
+	}
+	invoice.Subtotal = roundCents(invoice.Subtotal)
+	invoice.Tax = roundCents(invoice.Subtotal * invoice.TaxRate / 100)
+	invoice.Total = roundCents(invoice.Subtotal + invoice.Tax)
+	if invoice.Total <= 0 {
+		return nil, nil, errors.New("invoice total must be greater than 0")
+	}
+	return &invoice, items, nil
+}
+
+// nextNumber gives out the invoice numbers of a ledger in sequence, skipping numbers already taken.
+func nextNumber(tx *goqu.TxDatabase, ledgerID int64) (string, error) {
+	count, err := tx.From("invoices").Where(goqu.C("ledger_id").Eq(ledgerID)).Count()
+	if err != nil {
+		return "", err
+	}
+	for n := count + 1; ; n++ {
+		number := fmt.Sprintf("INV-%04d", n)
+		taken, err := tx.From("invoices").Where(goqu.Ex{"ledger_id": ledgerID, "number": number}).Count()
+		if err != nil {
+			return "", err
+		}
+		if taken == 0 {
+			return number, nil
+		}
+	}
+}
+
+func numberTaken(tx *goqu.TxDatabase, ledgerID int64, number string, id int64) error {
+	taken, err := tx.From("invoices").
+		Where(goqu.Ex{"ledger_id": ledgerID, "number": number}, goqu.C("id").Neq(id)).
+		Count()
+	if err != nil {
+		return err
+	}
+	if taken > 0 {
+		return fmt.Errorf("invoice number %q is already used", number)
+	}
+	return nil
+}
+
+func insertItems(tx *goqu.TxDatabase, invoiceID int64, items []Item) error {
+	for i := range items {
+		items[i].InvoiceID = invoiceID
+		result, err := tx.Insert("invoice_items").Rows(items[i]).Executor().Exec()
+		if err != nil {
+			return fmt.Errorf("err in inserting invoice item: %w", err)
+		}
+		items[i].ID, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create adds a draft invoice.
+func (service *InvoiceService) Create(userID int64, ledgerID int64, body dto.CreateInvoiceDTO, loc *time.Location) (*InvoiceDetails, error) {
+	invoice, items, err := service.prepare(ledgerID, body, loc)
+	if err != nil {
+		return nil, err
+	}
+	invoice.UserID = userID
+	invoice.Status = StatusDraft
+	invoice.Date = time.Now().Format(time.RFC3339)
+	err = service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		if invoice.Number == "" {
+			number, err := nextNumber(tx, ledgerID)
+			if err != nil {
+				return err
+			}
+			invoice.Number = number
+		} else if err := numberTaken(tx, ledgerID, invoice.Number, 0); err != nil {
+			return err
+		}
+		result, err := tx.Insert("invoices").Rows(invoice).Executor().Exec()
+		if err != nil {
+			return fmt.Errorf("err in inserting invoice: %w", err)
+		}
+		invoice.ID, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		return insertItems(tx, invoice.ID, items)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return service.Get(ledgerID, invoice.ID, loc)
+}
+
+// List returns the invoices newest first, only those with status when it is given. Overdue invoices are stored
+// as sent, so filtering by sent leaves them out.
+func (service *InvoiceService) List(ledgerID int64, status Status, loc *time.Location) ([]Invoice, error) {
+	query := service.DB.From("invoices").Where(goqu.C("ledger_id").Eq(ledgerID))
+	switch status {
+	case "":
+	case StatusDraft, StatusPaid:
+		query = query.Where(goqu.C("status").Eq(status))
+	case StatusSent, StatusOverdue:
+		query = query.Where(goqu.C("status").Eq(StatusSent))
+	default:
+		return nil, fmt.Errorf("status must be %q, %q, %q or %q", StatusDraft, StatusSent, StatusPaid, StatusOverdue)
+	}
+	var stored []Invoice
+	err := query.Order(goqu.C("issue_date").Desc(), goqu.C("id").Desc()).ScanStructs(&stored)
+	if err != nil {
+		return nil, err
+	}
+	now := today(loc)
+	invoices := []Invoice{}
+	for _, invoice := range stored {
+		invoice.settle(now)
+		if status == "" || invoice.Status == status {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+func (service *InvoiceService) getInvoice(ledgerID int64, id int64) (*Invoice, error) {
+	var invoice Invoice
+	found, err := service.DB.From("invoices").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).ScanStruct(&invoice)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &invoice, nil
+}
+
+// Get returns an invoice with its client, its items and the payment it was paid with.
+func (service *InvoiceService) Get(ledgerID int64, id int64, loc *time.Location) (*InvoiceDetails, error) {
+	invoice, err := service.getInvoice(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	invoice.settle(today(loc))
+	client, err := service.getClient(ledgerID, invoice.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	details := InvoiceDetails{Invoice: *invoice, Client: *client, Items: []Item{}}
+	err = service.DB.From("invoice_items").
+		Where(goqu.C("invoice_id").Eq(id)).
+		Order(goqu.C("position").Asc()).
+		ScanStructs(&details.Items)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.PaymentID != nil {
+		list, err := service.TransactionsService.List(ledgerID, transactions.TransactionList{IDs: &[]int{int(*invoice.PaymentID)}})
+		if err != nil {
+			return nil, err
+		}
+		if len(*list) > 0 {
+			details.Payment = &(*list)[0]
+		}
+	}
+	return &details, nil
+}
+
+// Update replaces the client, dates and items of a draft invoice. Invoices that were sent stay as they are.
+func (service *InvoiceService) Update(ledgerID int64, id int64, body dto.UpdateInvoiceDTO, loc *time.Location) (*InvoiceDetails, error) {
+	stored, err := service.getInvoice(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Status != StatusDraft {
+		return nil, errors.New("only draft invoices can be changed")
+	}
+	invoice, items, err := service.prepare(ledgerID, dto.CreateInvoiceDTO(body), loc)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.Number == "" {
+		invoice.Number = stored.Number
+	}
+	err = service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		if err := numberTaken(tx, ledgerID, invoice.Number, id); err != nil {
+			return err
+		}
+		_, err := tx.Update("invoices").
+			Set(goqu.Record{
+				"client_id":  invoice.ClientID,
+				"number":     invoice.Number,
+				"issue_date": invoice.IssueDate,
+				"due_date":   invoice.DueDate,
+				"tax_rate":   invoice.TaxRate,
+				"subtotal":   invoice.Subtotal,
+				"tax":        invoice.Tax,
+				"total":      invoice.Total,
+				"notes":      invoice.Notes,
+			}).
+			Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).
+			Executor().Exec()
+		if err != nil {
+			return fmt.Errorf("failed to update invoice: %w", err)
+		}
+		_, err = tx.Delete("invoice_items").Where(goqu.C("invoice_id").Eq(id)).Executor().Exec()
+		if err != nil {
+			return err
+		}
+		return insertItems(tx, id, items)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return service.Get(ledgerID, id, loc)
+}
+
+// Delete removes an invoice that is not paid. A paid invoice is kept along with the payment it booked.
+func (service *InvoiceService) Delete(ledgerID int64, id int64) error {
+	invoice, err := service.getInvoice(ledgerID, id)
+	if err != nil {
+		return err
+	}
+	if invoice.Status == StatusPaid {
+		return errors.New("a paid invoice cannot be deleted")
+	}
+	return service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		_, err := tx.Delete("invoice_items").Where(goqu.C("invoice_id").Eq(id)).Executor().Exec()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Delete("invoices").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+		return err
+	})
+}
+
+// Send marks a draft invoice as sent to the client, from then on it counts as a receivable.
+func (service *InvoiceService) Send(ledgerID int64, id int64, loc *time.Location) (*InvoiceDetails, error) {
+	invoice, err := service.getInvoice(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.Status != StatusDraft {
+		return nil, fmt.Errorf("invoice is already %s", invoice.Status)
+	}
+	_, err = service.DB.Update("invoices").
+		Set(goqu.Record{"status": StatusSent, "sent_at": time.Now().Format(time.RFC3339)}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).
+		Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update invoice: %w", err)
+	}
+	return service.Get(ledgerID, id, loc)
+}
+
+// Pay marks a sent invoice as paid and books its total as a payment from the client, with the invoice's tax
+// recorded on it.
+func (service *InvoiceService) Pay(userID int64, ledgerID int64, id int64, body dto.PayInvoiceDTO, loc *time.Location) (*InvoiceDetails, error) {
+	invoice, err := service.getInvoice(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	switch invoice.Status {
+	case StatusDraft:
+		return nil, errors.New("invoice has not been sent yet")
+	case StatusPaid:
+		return nil, errors.New("invoice is already paid")
+	}
+	client, err := service.getClient(ledgerID, invoice.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	date := time.Now().In(loc)
+	if body.Date != "" {
+		date, err = time.ParseInLocation(time.DateOnly, body.Date, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date: %w", err)
+		}
+	}
+	err = service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		// Claiming the invoice first keeps a repeated or concurrent request from booking the payment twice
+		result, err := tx.Update("invoices").
+			Set(goqu.Record{"status": StatusPaid, "paid_at": date.Format(time.RFC3339)}).
+			Where(goqu.Ex{"ledger_id": ledgerID, "id": id, "status": StatusSent}).
+			Executor().Exec()
+		if err != nil {
+			return fmt.Errorf("failed to update invoice: %w", err)
+		}
+		claimed, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if claimed == 0 {
+			return errors.New("invoice is already paid")
+		}
+		entry := transactions.NewTransaction{
+			UserID:   int(userID),
+			LedgerID: ledgerID,
+			Name:     "Invoice " + invoice.Number,
+			Price:    invoice.Total,
+			Seller:   client.Name,
+			Date:     date,
+			Tags:     []string{InvoicesTag},
+		}
+		if invoice.Tax > 0 {
+			entry.Tax = &transactions.TaxInput{TaxRate: &invoice.TaxRate, TaxAmount: &invoice.Tax}
+		}
+		payment, err := service.TransactionsService.CreateInTx(tx, entry, loc)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Update("invoices").
+			Set(goqu.Record{"payment_id": payment.ID}).
+			Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).
+			Executor().Exec()
+		if err != nil {
+			return fmt.Errorf("failed to update invoice: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return service.Get(ledgerID, id, loc)
+}
+
+// outstanding lists the sent invoices that are not paid, the longest overdue first.
+func (service *InvoiceService) outstanding(ledgerID int64, loc *time.Location) ([]Invoice, error) {
+	all, err := service.List(ledgerID, "", loc)
+	if err != nil {
+		return nil, err
+	}
+	invoices := []Invoice{}
+	for _, invoice := range all {
+		if invoice.Status == StatusSent || invoice.Status == StatusOverdue {
+			invoices = append(invoices, invoice)
+		}
+	}
+	sort.SliceStable(invoices, func(i, j int) bool {
+		return invoices[i].DueDate < invoices[j].DueDate
+	})
+	return invoices, nil
+}
+
+// Receivables sums what clients owe on sent invoices, in total and per client.
+func (service *InvoiceService) Receivables(ledgerID int64, loc *time.Location) (*Receivables, error) {
+	invoices, err := service.outstanding(ledgerID, loc)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := service.ListClients(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	names := map[int64]string{}
+	for _, client := range clients {
+		names[client.ID] = client.Name
+	}
+	receivables := Receivables{Clients: []ClientReceivable{}, Invoices: invoices}
+	byClient := map[int64]*ClientReceivable{}
+	for _, invoice := range invoices {
+		client, ok := byClient[invoice.ClientID]
+		if !ok {
+			client = &ClientReceivable{ClientID: invoice.ClientID, Name: names[invoice.ClientID]}
+			byClient[invoice.ClientID] = client
+		}
+		client.Count++
+		client.Total += invoice.Total
+		receivables.Total += invoice.Total
+		if invoice.Status == StatusOverdue {
+			client.Overdue += invoice.Total
+			receivables.Overdue += invoice.Total
+		}
+	}
+	for _, client := range byClient {
+		client.Total = roundCents(client.Total)
+		client.Overdue = roundCents(client.Overdue)
+		receivables.Clients = append(receivables.Clients, *client)
+	}
+	sort.Slice(receivables.Clients, func(i, j int) bool {
+		if receivables.Clients[i].Total != receivables.Clients[j].Total {
+			return receivables.Clients[i].Total > receivables.Clients[j].Total
+		}
+		return receivables.Clients[i].Name < receivables.Clients[j].Name
+	})
+	receivables.Total = roundCents(receivables.Total)
+	receivables.Overdue = roundCents(receivables.Overdue)
+	return &receivables, nil
+}
+
+// Aging sorts the outstanding invoices into buckets by how many days they are past due.
+func (service *InvoiceService) Aging(ledgerID int64, loc *time.Location) (*Aging, error) {
+	invoices, err := service.outstanding(ledgerID, loc)
+	if err != nil {
+		return nil, err
+	}
+	now := today(loc)
+	asOf, err := time.Parse(time.DateOnly, now)
+	if err != nil {
+		return nil, err
+	}
+	bound := func(days int) *int { return &days }
+	aging := Aging{AsOf: now, Buckets: []AgingBucket{
+		{Label: "current", MinDays: 0, MaxDays: bound(0)},
+		{Label: "1-30", MinDays: 1, MaxDays: bound(30)},
+		{Label: "31-60", MinDays: 31, MaxDays: bound(60)},
+		{Label: "61-90", MinDays: 61, MaxDays: bound(90)},
+		{Label: "90+", MinDays: 91},
+	}}
+	for i := range aging.Buckets {
+		aging.Buckets[i].Invoices = []Invoice{}
+	}
+	for _, invoice := range invoices {
+		due, err := time.Parse(time.DateOnly, invoice.DueDate)
+		if err != nil {
+			return nil, err
+		}
+		days := max(int(asOf.Sub(due).Hours()/24), 0)
+		for i := range aging.Buckets {
+			bucket := &aging.Buckets[i]
+			if days >= bucket.MinDays && (bucket.MaxDays == nil || days <= *bucket.MaxDays) {
+				bucket.Count++
+				bucket.Total += invoice.Total
+				bucket.Invoices = append(bucket.Invoices, invoice)
+				break
+			}
+		}
+		aging.Total += invoice.Total
+	}
+	for i := range aging.Buckets {
+		aging.Buckets[i].Total = roundCents(aging.Buckets[i].Total)
+	}
+	aging.Total = roundCents(aging.Total)
+	return &aging, nil
+}
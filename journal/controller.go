@@ -0,0 +1,79 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"checkout-go/auth"
+	"checkout-go/ledgers"
+	"checkout-go/settings"
+)
+
+type JournalController struct {
+	JournalService  *JournalService
+	AuthService     auth.UserContextReader
+	LedgerContext   ledgers.LedgerContextReader
+	SettingsContext settings.SettingsContextReader
+}
+
+func formatFromRequest(req *http.Request) Format {
+	format := Format(req.URL.Query().Get("format"))
+	if format == "" {
+		return FormatLedger
+	}
+	return format
+}
+
+// ExportJournal takes ?format=ledger|hledger|beancount, ?commodity= and an optional ?from= and ?to= date.
+func (c *JournalController) ExportJournal(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	commodity := query.Get("commodity")
+	if commodity == "" {
+		commodity = DefaultCommodity
+	}
+	format := formatFromRequest(req)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	journal, err := c.JournalService.Export(ledgerID, format, commodity, query.Get("from"), query.Get("to"), loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"checkout.%s\"", format.Extension()))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(journal); err != nil {
+		fmt.Printf("could not write journal: %s\n", err)
+	}
+}
+
+// ImportJournal takes the journal as the request body and its ?format=. With ?preview=true it only returns
+// what would be imported.
+func (c *JournalController) ImportJournal(w http.ResponseWriter, req *http.Request) {
+	preview := false
+	if previewStr := req.URL.Query().Get("preview"); previewStr != "" {
+		var err error
+		preview, err = strconv.ParseBool(previewStr)
+		if err != nil {
+			http.Error(w, "Invalid preview", http.StatusBadRequest)
+			return
+		}
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	result, err := c.JournalService.Import(userID, ledgerID, formatFromRequest(req), req.Body, preview, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,26 @@
+package journal
+
+// Format is a plain-text accounting syntax.
+type Format string
+
+const (
+	FormatLedger    Format = "ledger"
+	FormatHledger   Format = "hledger"
+	FormatBeancount Format = "beancount"
+)
+
+func (f Format) valid() bool {
+	return f == FormatLedger || f == FormatHledger || f == FormatBeancount
+}
+
+// Extension is the file extension journals of the format usually have.
+func (f Format) Extension() string {
+	switch f {
+	case FormatHledger:
+		return "journal"
+	case FormatBeancount:
+		return "beancount"
+	default:
+		return "ledger"
+	}
+}
@@ -0,0 +1,215 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"checkout-go/transactions"
+)
+
+const (
+	// AssetsAccount is the account the ledger's money is kept in.
+	AssetsAccount   = "Assets:Checking"
+	openingAccount  = "Equity:Opening-Balances"
+	uncategorized   = "Uncategorized"
+	otherIncome     = "Other"
+	timeLayout      = "15:04:05Z07:00"
+	metadataTime    = "time"
+	metadataNote    = "note"
+	metadataTags    = "tags"
+	metadataPayee   = "payee"
+	openingBalances = "Opening balance"
+)
+
+// entry is a journal transaction in between checkout-go and the text formats.
+type entry struct {
+	line     int
+	date     string
+	cleared  bool
+	payee    string
+	name     string
+	tags     []string
+	metadata map[string]string
+	postings []posting
+}
+
+type posting struct {
+	account string
+	amount  float64
+	elided  bool
+	// balance is asserted after the posting when set
+	balance *float64
+}
+
+// accountName turns a tag into an account component, which may only hold letters, digits and dashes and has
+// to start with a capital letter.
+func accountName(tag string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range tag {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		} else {
+			dash = true
+		}
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		return ""
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// categoryAccount books expenses under their first tag and income under Income.
+func categoryAccount(price float64, tags []string) string {
+	root, name := "Expenses", uncategorized
+	if price > 0 {
+		root, name = "Income", otherIncome
+	}
+	if len(tags) > 0 {
+		if tagName := accountName(tags[0]); tagName != "" {
+			name = tagName
+		}
+	}
+	return root + ":" + name
+}
+
+// isCategory tells the accounts whose postings are income or spending, everything else moves money around.
+func isCategory(account string) bool {
+	root, _, _ := strings.Cut(account, ":")
+	switch strings.ToLower(root) {
+	case "expenses", "expense", "income", "revenue", "revenues":
+		return true
+	}
+	return false
+}
+
+// toEntry lays a transaction out as a posting to its category and one to the assets account. The date and
+// time are kept in the offset the transaction was entered with.
+func toEntry(t transactions.Transaction) entry {
+	date := t.Date.Time()
+	if t.UTCOffset != 0 {
+		date = date.In(time.FixedZone("", t.UTCOffset))
+	}
+	e := entry{
+		date:     date.Format(time.DateOnly),
+		cleared:  t.Status != transactions.StatusPending,
+		payee:    singleLine(t.Seller),
+		name:     singleLine(t.Name),
+		tags:     t.Tags,
+		metadata: map[string]string{metadataTime: date.Format(timeLayout)},
+	}
+	if t.Note != "" {
+		e.metadata[metadataNote] = t.Note
+	}
+	category := categoryAccount(t.Price, t.Tags)
+	if t.Price > 0 {
+		e.postings = []posting{{account: AssetsAccount, amount: t.Price}, {account: category, amount: -t.Price}}
+	} else {
+		e.postings = []posting{{account: category, amount: -t.Price}, {account: AssetsAccount, amount: t.Price}}
+	}
+	return e
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// toTransaction books the income and expense postings of an entry as one transaction. It returns false for
+// entries that only move money between other accounts.
+func (e entry) toTransaction(loc *time.Location) (*transactions.ImportedTransaction, bool, error) {
+	sum, elided := 0.0, -1
+	for i, p := range e.postings {
+		if p.elided {
+			if elided >= 0 {
+				return nil, false, fmt.Errorf("line %d: more than one posting without an amount", e.line)
+			}
+			elided = i
+			continue
+		}
+		sum += p.amount
+	}
+	if elided >= 0 {
+		e.postings[elided].amount = -sum
+	}
+	price, category, largest := 0.0, "", -1.0
+	for _, p := range e.postings {
+		if !isCategory(p.account) {
+			continue
+		}
+		price -= p.amount
+		if abs := max(p.amount, -p.amount); abs > largest {
+			category, largest = p.account, abs
+		}
+	}
+	if category == "" {
+		return nil, false, nil
+	}
+	date, err := e.time(loc)
+	if err != nil {
+		return nil, false, err
+	}
+	t := transactions.ImportedTransaction{
+		Line:   e.line,
+		Name:   e.name,
+		Price:  roundCents(price),
+		Seller: e.payee,
+		Note:   e.metadata[metadataNote],
+		Date:   date,
+		Tags:   e.tags,
+		Status: transactions.StatusPending,
+	}
+	if e.cleared {
+		t.Status = transactions.StatusCleared
+	}
+	if payee, ok := e.metadata[metadataPayee]; ok {
+		t.Seller = payee
+	}
+	if tags, ok := e.metadata[metadataTags]; ok {
+		if err := json.Unmarshal([]byte(tags), &t.Tags); err != nil {
+			return nil, false, fmt.Errorf("line %d: invalid tags: %w", e.line, err)
+		}
+	}
+	// Journals from elsewhere categorize by account instead of by tag
+	if len(t.Tags) == 0 {
+		_, leaf, _ := strings.Cut(category, ":")
+		if i := strings.LastIndex(leaf, ":"); i >= 0 {
+			leaf = leaf[i+1:]
+		}
+		if leaf != "" && leaf != uncategorized && leaf != otherIncome {
+			t.Tags = []string{strings.ToLower(leaf)}
+		}
+	}
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	return &t, true, nil
+}
+
+// time combines the date with the time of day of the metadata, midnight in loc without one.
+func (e entry) time(loc *time.Location) (time.Time, error) {
+	clock, ok := e.metadata[metadataTime]
+	if !ok {
+		date, err := time.ParseInLocation(time.DateOnly, e.date, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("line %d: invalid date: %w", e.line, err)
+		}
+		return date, nil
+	}
+	if date, err := time.Parse(time.DateOnly+" "+timeLayout, e.date+" "+clock); err == nil {
+		return date, nil
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if date, err := time.ParseInLocation(time.DateOnly+" "+layout, e.date+" "+clock, loc); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("line %d: invalid time %q", e.line, clock)
+}
@@ -0,0 +1,376 @@
+package journal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// parse reads the transactions of a journal. Directives like account, commodity, open, balance or price are
+// skipped, as are virtual postings, they have no counterpart in checkout-go.
+func parse(data io.Reader, format Format) ([]entry, error) {
+	scanner := bufio.NewScanner(data)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var entries []entry
+	var current *entry
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" {
+			current = nil
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			current = nil
+			if line[0] < '0' || line[0] > '9' {
+				continue
+			}
+			var e *entry
+			var err error
+			if format == FormatBeancount {
+				e, err = parseBeancountHeader(line)
+			} else {
+				e, err = parseLedgerHeader(line, format)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", number, err)
+			}
+			if e != nil {
+				e.line = number
+				entries = append(entries, *e)
+				current = &entries[len(entries)-1]
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		text := strings.TrimSpace(line)
+		if comment, ok := strings.CutPrefix(text, ";"); ok {
+			if format != FormatBeancount {
+				current.parseComment(strings.TrimSpace(comment), format)
+			}
+			continue
+		}
+		if format == FormatBeancount {
+			if key, value, ok := beancountMetadata(text); ok {
+				current.metadata[key] = value
+				continue
+			}
+		}
+		if err := current.parsePosting(text, format); err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// parseDate accepts dates with dashes, slashes or dots and without leading zeros.
+func parseDate(value string) (string, error) {
+	value = strings.NewReplacer("/", "-", ".", "-").Replace(value)
+	date, err := time.Parse("2006-1-2", value)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q", value)
+	}
+	return date.Format(time.DateOnly), nil
+}
+
+func newEntry(date string) *entry {
+	return &entry{date: date, metadata: map[string]string{}}
+}
+
+// parseLedgerHeader reads "DATE[=DATE2] [*|!] [(CODE)] DESCRIPTION  ; COMMENT". It returns nil for lines
+// starting with a digit that are not transactions.
+func parseLedgerHeader(line string, format Format) (*entry, error) {
+	dateField, rest := line, ""
+	if end := strings.IndexAny(line, " \t"); end >= 0 {
+		dateField, rest = line[:end], line[end:]
+	}
+	dateField, _, _ = strings.Cut(dateField, "=")
+	date, err := parseDate(dateField)
+	if err != nil {
+		return nil, err
+	}
+	e := newEntry(date)
+	rest = strings.ReplaceAll(strings.TrimSpace(rest), "\t", "  ")
+	if description, comment, ok := strings.Cut(rest, "  ;"); ok {
+		rest = strings.TrimSpace(description)
+		e.parseComment(strings.TrimSpace(comment), format)
+	} else if comment, ok := strings.CutPrefix(rest, ";"); ok {
+		rest = ""
+		e.parseComment(strings.TrimSpace(comment), format)
+	}
+	if flag, ok := strings.CutPrefix(rest, "*"); ok {
+		e.cleared = true
+		rest = strings.TrimSpace(flag)
+	} else if flag, ok := strings.CutPrefix(rest, "!"); ok {
+		rest = strings.TrimSpace(flag)
+	}
+	if strings.HasPrefix(rest, "(") {
+		if _, description, ok := strings.Cut(rest, ")"); ok {
+			rest = strings.TrimSpace(description)
+		}
+	}
+	e.name = rest
+	if format == FormatHledger {
+		if payee, name, ok := strings.Cut(rest, "|"); ok {
+			e.payee = strings.TrimSpace(payee)
+			e.name = strings.TrimSpace(name)
+		}
+	}
+	return e, nil
+}
+
+// parseComment picks tags and metadata out of a comment. Ledger writes tags as :one:two: and metadata as
+// "Key: value"; hledger writes both as "name:value" pairs separated by commas, so everything that is not one
+// of the metadata checkout-go writes is read as tags there.
+func (e *entry) parseComment(comment string, format Format) {
+	if format == FormatLedger && len(comment) > 1 && comment[0] == ':' && strings.HasSuffix(comment, ":") && !strings.ContainsAny(comment, " \t") {
+		for _, tag := range strings.Split(strings.Trim(comment, ":"), ":") {
+			if tag != "" {
+				e.tags = append(e.tags, tag)
+			}
+		}
+		return
+	}
+	key, value, ok := strings.Cut(comment, ":")
+	key = strings.ToLower(strings.TrimSpace(key))
+	if !ok || key == "" || strings.ContainsAny(key, " \t,") {
+		if format == FormatHledger {
+			e.parseHledgerTags(comment)
+		}
+		return
+	}
+	value = strings.TrimSpace(strings.TrimPrefix(value, ":"))
+	if format == FormatHledger && key != metadataTime && key != metadataNote && key != metadataTags && key != metadataPayee {
+		e.parseHledgerTags(comment)
+		return
+	}
+	if strings.HasPrefix(value, `"`) {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+	}
+	e.metadata[key] = value
+}
+
+// parseHledgerTags reads "name:" and "name:value" tags, the name being the word right before the colon.
+func (e *entry) parseHledgerTags(comment string) {
+	for _, item := range strings.Split(comment, ",") {
+		before, value, ok := strings.Cut(item, ":")
+		if !ok {
+			continue
+		}
+		words := strings.Fields(before)
+		if len(words) == 0 {
+			continue
+		}
+		tag := words[len(words)-1]
+		if value = strings.TrimSpace(value); value != "" {
+			tag += ":" + value
+		}
+		e.tags = append(e.tags, tag)
+	}
+}
+
+// beancountTokens splits a line into words and quoted strings, dropping a trailing comment.
+func beancountTokens(line string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == ';':
+			return tokens, nil
+		case c == '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, line[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(line) && line[end] != ' ' && line[end] != '\t' {
+				end++
+			}
+			tokens = append(tokens, line[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+func unquoteBeancount(value string) string {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, `"`), `"`)
+	return strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r").Replace(value)
+}
+
+// parseBeancountHeader reads `DATE FLAG ["PAYEE"] "NARRATION" #tag ^link`. It returns nil for the other
+// dated directives.
+func parseBeancountHeader(line string) (*entry, error) {
+	tokens, err := beancountTokens(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) < 2 {
+		return nil, nil
+	}
+	flag := tokens[1]
+	if flag != "txn" && (len(flag) != 1 || unicode.IsLetter(rune(flag[0])) && unicode.IsLower(rune(flag[0]))) {
+		return nil, nil
+	}
+	date, err := parseDate(tokens[0])
+	if err != nil {
+		return nil, err
+	}
+	e := newEntry(date)
+	e.cleared = flag == "*" || flag == "txn"
+	var texts []string
+	for _, token := range tokens[2:] {
+		switch token[0] {
+		case '"':
+			texts = append(texts, unquoteBeancount(token))
+		case '#':
+			e.tags = append(e.tags, token[1:])
+		}
+	}
+	switch len(texts) {
+	case 1:
+		e.name = texts[0]
+	case 2:
+		e.payee, e.name = texts[0], texts[1]
+	}
+	return e, nil
+}
+
+// beancountMetadata reads "key: value" lines, keys start with a lower case letter unlike accounts.
+func beancountMetadata(text string) (string, string, bool) {
+	key, value, ok := strings.Cut(text, ":")
+	if !ok || key == "" || !unicode.IsLower(rune(key[0])) || strings.ContainsAny(key, " \t") {
+		return "", "", false
+	}
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, `"`) {
+		value = unquoteBeancount(value)
+	}
+	return key, value, true
+}
+
+// parsePosting reads "[FLAG] ACCOUNT  AMOUNT [@ PRICE] [= BALANCE]  ; COMMENT". Amounts in another
+// commodity are converted with their price or cost.
+func (e *entry) parsePosting(text string, format Format) error {
+	if body, comment, ok := strings.Cut(text, ";"); ok {
+		text = strings.TrimSpace(body)
+		if format != FormatBeancount {
+			e.parseComment(strings.TrimSpace(comment), format)
+		}
+	}
+	if len(text) > 1 && (text[0] == '*' || text[0] == '!') && (text[1] == ' ' || text[1] == '\t') {
+		text = strings.TrimSpace(text[1:])
+	}
+	var account, amount string
+	if format == FormatBeancount {
+		account, amount, _ = strings.Cut(strings.ReplaceAll(text, "\t", " "), " ")
+	} else {
+		account, amount, _ = strings.Cut(strings.ReplaceAll(text, "\t", "  "), "  ")
+	}
+	if strings.HasPrefix(account, "(") || strings.HasPrefix(account, "[") {
+		return nil
+	}
+	amount, _, _ = strings.Cut(amount, "=")
+	amount = strings.TrimSpace(amount)
+	p := posting{account: account}
+	if amount == "" {
+		p.elided = true
+		e.postings = append(e.postings, p)
+		return nil
+	}
+	var err error
+	p.amount, err = parsePostingAmount(amount)
+	if err != nil {
+		return err
+	}
+	e.postings = append(e.postings, p)
+	return nil
+}
+
+func parsePostingAmount(amount string) (float64, error) {
+	var cost string
+	if start := strings.Index(amount, "{"); start >= 0 {
+		if end := strings.LastIndex(amount, "}"); end > start {
+			cost = strings.Trim(amount[start:end+1], "{}")
+			amount = amount[:start] + amount[end+1:]
+		}
+	}
+	quantityText, price, priced := strings.Cut(amount, "@")
+	quantity, err := parseAmount(quantityText)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case priced && strings.HasPrefix(price, "@"):
+		total, err := parseAmount(price[1:])
+		if err != nil {
+			return 0, err
+		}
+		return math.Copysign(math.Abs(total), quantity), nil
+	case priced:
+		unit, err := parseAmount(price)
+		if err != nil {
+			return 0, err
+		}
+		return quantity * unit, nil
+	case cost != "":
+		unit, err := parseAmount(cost)
+		if err != nil {
+			return 0, err
+		}
+		return quantity * unit, nil
+	}
+	return quantity, nil
+}
+
+// parseAmount finds the number in an amount like "-12.50 EUR", "EUR -12.50", "-€12.50" or "$1,250.00".
+func parseAmount(amount string) (float64, error) {
+	for _, field := range strings.Fields(amount) {
+		negative := false
+		number := strings.TrimLeftFunc(field, func(r rune) bool {
+			if r == '-' {
+				negative = !negative
+			}
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+		})
+		end := strings.IndexFunc(number, func(r rune) bool {
+			return !unicode.IsDigit(r) && r != '.' && r != ','
+		})
+		if end >= 0 {
+			number = number[:end]
+		}
+		if number == "" || !unicode.IsDigit(rune(number[0])) && number[0] != '.' {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.ReplaceAll(number, ",", ""), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount %q", amount)
+		}
+		if negative {
+			value = -value
+		}
+		return value, nil
+	}
+	return 0, fmt.Errorf("invalid amount %q", amount)
+}
@@ -0,0 +1,144 @@
+package journal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"time"
+
+	"checkout-go/anomalies"
+	"checkout-go/transactions"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+// DefaultCommodity is written when the export does not name one, checkout-go does not track currencies.
+const DefaultCommodity = "EUR"
+
+var commodityPattern = regexp.MustCompile(`^[A-Z][A-Z0-9'._-]{0,22}[A-Z0-9]?$`)
+
+type JournalService struct {
+	DB                  *goqu.Database
+	TransactionsService *transactions.TransactionService
+	AnomalyService      *anomalies.AnomalyService
+}
+
+// Export writes the ledger's transactions with a local date between from and to, either of which may be
+// empty, as a journal. When from is set the balance before it is carried over as an opening balance, so the
+// balance asserted at the end is the ledger's.
+func (service *JournalService) Export(ledgerID int64, format Format, commodity string, from string, to string, loc *time.Location) ([]byte, error) {
+	if !format.valid() {
+		return nil, fmt.Errorf("format must be %q, %q or %q", FormatLedger, FormatHledger, FormatBeancount)
+	}
+	if !commodityPattern.MatchString(commodity) {
+		return nil, errors.New("commodity must be a currency code like EUR")
+	}
+	for _, date := range []string{from, to} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("invalid date: %w", err)
+		}
+	}
+	localDate := goqu.L("strftime('%Y-%m-%d', local_time(date, ?))", loc.String())
+	query := service.DB.From("transactions").Where(goqu.C("ledger_id").Eq(ledgerID))
+	if from != "" {
+		query = query.Where(localDate.Gte(from))
+	}
+	if to != "" {
+		query = query.Where(localDate.Lte(to))
+	}
+	var list []transactions.Transaction
+	if err := query.Order(goqu.C("date").Asc(), goqu.C("id").Asc()).ScanStructs(&list); err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	balance := 0.0
+	if from != "" {
+		_, err := service.DB.From("transactions").
+			Select(goqu.COALESCE(goqu.SUM("price"), 0)).
+			Where(goqu.C("ledger_id").Eq(ledgerID), localDate.Lt(from)).
+			ScanVal(&balance)
+		if err != nil {
+			return nil, err
+		}
+		if roundCents(balance) != 0 {
+			entries = append(entries, entry{
+				date:     from,
+				cleared:  true,
+				name:     openingBalances,
+				metadata: map[string]string{},
+				postings: []posting{{account: AssetsAccount, amount: balance}, {account: openingAccount, amount: -balance}},
+			})
+		}
+	}
+	for _, t := range list {
+		entries = append(entries, toEntry(t))
+		balance += t.Price
+	}
+	// Transactions entered in other offsets can fall on a different day than their order in UTC
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].date < entries[j].date })
+
+	var buffer bytes.Buffer
+	if format == FormatBeancount {
+		var closing *float64
+		closingDate := ""
+		if len(entries) > 0 {
+			last, _ := time.Parse(time.DateOnly, entries[len(entries)-1].date)
+			closing, closingDate = &balance, last.AddDate(0, 0, 1).Format(time.DateOnly)
+		}
+		writeBeancount(&buffer, commodity, entries, closing, closingDate)
+		return buffer.Bytes(), nil
+	}
+	if len(entries) > 0 {
+		last := &entries[len(entries)-1]
+		for i := range last.postings {
+			if last.postings[i].account == AssetsAccount {
+				last.postings[i].balance = &balance
+			}
+		}
+	}
+	writeLedger(&buffer, format, commodity, entries)
+	return buffer.Bytes(), nil
+}
+
+// Import books the income and expense postings of every journal transaction in the ledger. Cleared
+// transactions are imported as cleared; reconciling them is left to a reconciliation. Nothing is stored when
+// the journal cannot be read, or with preview.
+func (service *JournalService) Import(userID int64, ledgerID int64, format Format, data io.Reader, preview bool, loc *time.Location) (*transactions.ImportResult, error) {
+	if !format.valid() {
+		return nil, fmt.Errorf("format must be %q, %q or %q", FormatLedger, FormatHledger, FormatBeancount)
+	}
+	entries, err := parse(data, format)
+	if err != nil {
+		return nil, err
+	}
+	var imported []transactions.ImportedTransaction
+	skipped := 0
+	for _, e := range entries {
+		t, ok, err := e.toTransaction(loc)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			skipped++
+			continue
+		}
+		imported = append(imported, *t)
+	}
+	result, err := service.TransactionsService.Import(int(userID), ledgerID, imported, skipped, preview, loc)
+	if err != nil {
+		return nil, err
+	}
+	if result.Imported > 0 && service.AnomalyService != nil {
+		if err := service.AnomalyService.Rescan(ledgerID); err != nil {
+			fmt.Printf("could not score imported transactions: %v\n", err)
+		}
+	}
+	return result, nil
+}
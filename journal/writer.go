@@ -0,0 +1,188 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ledgerTag    = regexp.MustCompile(`^[^\s:]+$`)
+	hledgerTag   = regexp.MustCompile(`^[^\s:,]+$`)
+	beancountTag = regexp.MustCompile(`^[A-Za-z0-9\-_/.]+$`)
+)
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func formatAmount(value float64) string {
+	value = roundCents(value)
+	if value == 0 {
+		value = 0 // no "-0.00"
+	}
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// nativeTags splits tags into the ones the format can write as tags and whether some could not be.
+func nativeTags(format Format, tags []string) ([]string, bool) {
+	var native []string
+	all := true
+	for _, tag := range tags {
+		var ok bool
+		switch format {
+		case FormatLedger:
+			ok = ledgerTag.MatchString(tag)
+		case FormatHledger:
+			// hledger reads a comment starting with one of these as the metadata instead of as a tag
+			reserved := tag == metadataTime || tag == metadataNote || tag == metadataTags || tag == metadataPayee
+			ok = hledgerTag.MatchString(tag) && !reserved
+		case FormatBeancount:
+			ok = beancountTag.MatchString(tag)
+		}
+		if ok {
+			native = append(native, tag)
+		} else {
+			all = false
+		}
+	}
+	return native, all
+}
+
+func tagsJSON(tags []string) string {
+	data, _ := json.Marshal(tags)
+	return string(data)
+}
+
+// accounts lists every account the entries post to, sorted.
+func accounts(entries []entry) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, e := range entries {
+		for _, p := range e.postings {
+			if !seen[p.account] {
+				seen[p.account] = true
+				result = append(result, p.account)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// writePosting aligns amounts the way ledger-mode and bean-format do.
+func writePosting(w io.Writer, indent string, p posting, commodity string) {
+	fmt.Fprintf(w, "%s%-40s %12s %s", indent, p.account, formatAmount(p.amount), commodity)
+	if p.balance != nil {
+		fmt.Fprintf(w, " = %s %s", formatAmount(*p.balance), commodity)
+	}
+	fmt.Fprintln(w)
+}
+
+// metadataValue keeps a value on one comment line, quoting it only when it has to.
+func metadataValue(value string) string {
+	if strings.ContainsAny(value, "\r\n") || strings.HasPrefix(value, `"`) {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// writeLedger writes entries in ledger or hledger syntax. They differ in how payees and tags are written:
+// ledger keeps the payee in a Payee metadata and tags as :tag:, hledger uses "payee | description" and tag:.
+func writeLedger(w io.Writer, format Format, commodity string, entries []entry) {
+	fmt.Fprintln(w, "; Exported from checkout-go")
+	fmt.Fprintf(w, "commodity %s\n", commodity)
+	for _, account := range accounts(entries) {
+		fmt.Fprintf(w, "account %s\n", account)
+	}
+	for _, e := range entries {
+		fmt.Fprintln(w)
+		flag := "!"
+		if e.cleared {
+			flag = "*"
+		}
+		description := e.name
+		if format == FormatHledger && (e.payee != "" || strings.Contains(e.name, "|")) {
+			description = e.payee + " | " + e.name
+		}
+		fmt.Fprintln(w, strings.TrimRight(e.date+" "+flag+" "+description, " "))
+		if format == FormatLedger && e.payee != "" {
+			fmt.Fprintf(w, "    ; Payee: %s\n", e.payee)
+		}
+		for _, key := range []string{metadataTime, metadataNote} {
+			if value, ok := e.metadata[key]; ok {
+				fmt.Fprintf(w, "    ; %s: %s\n", key, metadataValue(value))
+			}
+		}
+		native, all := nativeTags(format, e.tags)
+		if len(native) > 0 {
+			if format == FormatLedger {
+				fmt.Fprintf(w, "    ; :%s:\n", strings.Join(native, ":"))
+			} else {
+				fmt.Fprintf(w, "    ; %s:\n", strings.Join(native, ":, "))
+			}
+		}
+		if !all {
+			fmt.Fprintf(w, "    ; %s: %s\n", metadataTags, tagsJSON(e.tags))
+		}
+		for _, p := range e.postings {
+			writePosting(w, "    ", p, commodity)
+		}
+	}
+}
+
+func beancountString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return `"` + strings.ReplaceAll(value, "\r", `\r`) + `"`
+}
+
+// writeBeancount writes entries in beancount syntax. Accounts are opened on the first day and the balance of the
+// assets account is checked the day after the last entry, since beancount checks balances at the start of a day.
+func writeBeancount(w io.Writer, commodity string, entries []entry, closing *float64, closingDate string) {
+	fmt.Fprintln(w, "; Exported from checkout-go")
+	fmt.Fprintf(w, "option \"operating_currency\" %s\n", beancountString(commodity))
+	if len(entries) > 0 {
+		fmt.Fprintln(w)
+		for _, account := range accounts(entries) {
+			fmt.Fprintf(w, "%s open %s\n", entries[0].date, account)
+		}
+	}
+	for _, e := range entries {
+		fmt.Fprintln(w)
+		flag := "!"
+		if e.cleared {
+			flag = "*"
+		}
+		header := e.date + " " + flag
+		if e.payee != "" {
+			header += " " + beancountString(e.payee)
+		}
+		header += " " + beancountString(e.name)
+		native, all := nativeTags(FormatBeancount, e.tags)
+		for _, tag := range native {
+			header += " #" + tag
+		}
+		fmt.Fprintln(w, header)
+		for _, key := range []string{metadataTime, metadataNote} {
+			if value, ok := e.metadata[key]; ok {
+				fmt.Fprintf(w, "  %s: %s\n", key, beancountString(value))
+			}
+		}
+		if !all {
+			fmt.Fprintf(w, "  %s: %s\n", metadataTags, beancountString(tagsJSON(e.tags)))
+		}
+		for _, p := range e.postings {
+			writePosting(w, "  ", posting{account: p.account, amount: p.amount}, commodity)
+		}
+	}
+	if closing != nil {
+		fmt.Fprintf(w, "\n%s balance %s %s %s\n", closingDate, AssetsAccount, formatAmount(*closing), commodity)
+	}
+}
@@ -0,0 +1,301 @@
+package ledgers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"checkout-go/auth"
+	dto "checkout-go/ledgers/dtos"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// LedgerHeader selects the ledger a request operates on. Without it the user's personal ledger is used.
+// Routes that carry a {ledgerID} URL parameter use that instead.
+const LedgerHeader = "X-Ledger-ID"
+
+type contextKey int
+
+const (
+	ledgerIDKey contextKey = iota
+	roleKey
+)
+
+type LedgerContextReader interface {
+	GetLedgerIDFromRequest(req *http.Request) int64
+	GetRoleFromRequest(req *http.Request) Role
+}
+
+type LedgersController struct {
+	LedgerService *LedgerService
+	AuthService   auth.UserContextReader
+}
+
+// RequireRole resolves the active ledger of the request and rejects users whose role in it is below min.
+// It must run after auth.AuthController.RequireLoginMiddleware.
+func (c *LedgersController) RequireRole(min Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := c.AuthService.GetUserIDFromRequest(r)
+			var ledgerID int64
+			var role Role
+			ledgerIDStr := chi.URLParam(r, "ledgerID")
+			if ledgerIDStr == "" {
+				ledgerIDStr = r.Header.Get(LedgerHeader)
+			}
+			if ledgerIDStr == "" {
+				ledger, err := c.LedgerService.GetPersonalLedger(userID)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				ledgerID = ledger.ID
+				role = RoleOwner
+			} else {
+				id, err := strconv.ParseInt(ledgerIDStr, 10, 64)
+				if err != nil || id < 1 {
+					http.Error(w, "Invalid ledger ID", http.StatusBadRequest)
+					return
+				}
+				role, err = c.LedgerService.GetRole(userID, id)
+				if err != nil {
+					if errors.Is(err, ErrNotMember) {
+						http.Error(w, err.Error(), http.StatusForbidden)
+						return
+					}
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				ledgerID = id
+			}
+			if !role.Allows(min) {
+				http.Error(w, fmt.Sprintf("this action requires the %s role", min), http.StatusForbidden)
+				return
+			}
+			ctx := context.WithValue(r.Context(), ledgerIDKey, ledgerID)
+			ctx = context.WithValue(ctx, roleKey, role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func (c *LedgersController) GetLedgerIDFromRequest(req *http.Request) int64 {
+	ledgerID, ok := req.Context().Value(ledgerIDKey).(int64)
+	if !ok {
+		panic("LedgerID is not an int64")
+	}
+	return ledgerID
+}
+
+// LookupLedgerIDFromRequest is GetLedgerIDFromRequest for handlers that also serve requests without a ledger.
+func (c *LedgersController) LookupLedgerIDFromRequest(req *http.Request) (int64, bool) {
+	ledgerID, ok := req.Context().Value(ledgerIDKey).(int64)
+	return ledgerID, ok
+}
+
+func (c *LedgersController) GetRoleFromRequest(req *http.Request) Role {
+	role, ok := req.Context().Value(roleKey).(Role)
+	if !ok {
+		panic("Role is not a ledgers.Role")
+	}
+	return role
+}
+
+var _ LedgerContextReader = (*LedgersController)(nil) // compile-time check
+
+func (c *LedgersController) CreateLedger(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var ledgerBody dto.CreateLedgerDTO
+	err = json.Unmarshal(body, &ledgerBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	ledger, err := c.LedgerService.Create(userID, ledgerBody.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(ledger)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *LedgersController) ListLedgers(w http.ResponseWriter, req *http.Request) {
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	// Make sure the personal ledger shows up even before the user touched any other endpoint
+	if _, err := c.LedgerService.GetPersonalLedger(userID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ledgers, err := c.LedgerService.List(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(ledgers)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *LedgersController) UpdateLedger(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var ledgerBody dto.UpdateLedgerDTO
+	err = json.Unmarshal(body, &ledgerBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.GetLedgerIDFromRequest(req)
+	ledger, err := c.LedgerService.Rename(ledgerID, ledgerBody.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(ledger)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *LedgersController) ListMembers(w http.ResponseWriter, req *http.Request) {
+	ledgerID := c.GetLedgerIDFromRequest(req)
+	members, err := c.LedgerService.ListMembers(ledgerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(members)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *LedgersController) UpdateMember(w http.ResponseWriter, req *http.Request) {
+	memberID, err := strconv.ParseInt(chi.URLParam(req, "userID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var memberBody dto.UpdateMemberDTO
+	err = json.Unmarshal(body, &memberBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.GetLedgerIDFromRequest(req)
+	err = c.LedgerService.UpdateMemberRole(ledgerID, memberID, Role(memberBody.Role))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *LedgersController) RemoveMember(w http.ResponseWriter, req *http.Request) {
+	memberID, err := strconv.ParseInt(chi.URLParam(req, "userID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.GetLedgerIDFromRequest(req)
+	err = c.LedgerService.RemoveMember(ledgerID, memberID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *LedgersController) CreateInvitation(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var invitationBody dto.CreateInvitationDTO
+	err = json.Unmarshal(body, &invitationBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.GetLedgerIDFromRequest(req)
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	invitation, err := c.LedgerService.CreateInvitation(ledgerID, userID, Role(invitationBody.Role))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(invitation)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *LedgersController) JoinLedger(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var joinBody dto.JoinLedgerDTO
+	err = json.Unmarshal(body, &joinBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	ledger, err := c.LedgerService.Join(userID, joinBody.Code)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(ledger)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,19 @@
+package ledgers
+
+type CreateLedgerDTO struct {
+	Name string `json:"name"`
+}
+
+type UpdateLedgerDTO CreateLedgerDTO
+
+type CreateInvitationDTO struct {
+	Role string `json:"role"`
+}
+
+type JoinLedgerDTO struct {
+	Code string `json:"code"`
+}
+
+type UpdateMemberDTO struct {
+	Role string `json:"role"`
+}
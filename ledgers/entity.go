@@ -0,0 +1,61 @@
+package ledgers
+
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleOwner  Role = "owner"
+)
+
+// rank orders roles so that a higher role includes every permission of a lower one.
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleOwner:
+		return 3
+	}
+	return 0
+}
+
+func (r Role) Valid() bool {
+	return r.rank() > 0
+}
+
+// Allows reports whether a member with role r may do something that requires role min.
+func (r Role) Allows(min Role) bool {
+	return r.rank() >= min.rank()
+}
+
+type Ledger struct {
+	ID       int64  `db:"id" goqu:"skipinsert" json:"id"`
+	Name     string `db:"name" json:"name"`
+	OwnerID  int64  `db:"owner_id" json:"ownerId"`
+	Personal bool   `db:"personal" json:"personal"`
+	Date     string `db:"date" json:"date"`
+}
+
+type LedgerWithRole struct {
+	Ledger
+	Role Role `db:"role" json:"role"`
+}
+
+type Member struct {
+	LedgerID int64  `db:"ledger_id" json:"ledgerId"`
+	UserID   int64  `db:"user_id" json:"userId"`
+	Username string `db:"username" goqu:"skipinsert" json:"username"`
+	Role     Role   `db:"role" json:"role"`
+	Date     string `db:"date" json:"date"`
+}
+
+type Invitation struct {
+	Code      string `db:"code" json:"code"`
+	LedgerID  int64  `db:"ledger_id" json:"ledgerId"`
+	Role      Role   `db:"role" json:"role"`
+	CreatedBy int64  `db:"created_by" json:"createdBy"`
+	UsedBy    *int64 `db:"used_by" json:"usedBy,omitempty"`
+	Date      string `db:"date" json:"date"`
+}
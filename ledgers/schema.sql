@@ -0,0 +1,31 @@
+CREATE TABLE ledgers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    owner_id INTEGER NOT NULL,
+    personal INTEGER NOT NULL DEFAULT 0,
+    date TEXT NOT NULL
+);
+
+-- Every user has one personal ledger
+CREATE UNIQUE INDEX ledgers_personal_owner ON ledgers (owner_id) WHERE personal = 1;
+
+CREATE TABLE ledger_members (
+    ledger_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    date TEXT NOT NULL,
+    PRIMARY KEY (ledger_id, user_id)
+);
+
+CREATE TABLE ledger_invitations (
+    code TEXT PRIMARY KEY,
+    ledger_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    created_by INTEGER NOT NULL,
+    used_by INTEGER,
+    date TEXT NOT NULL
+);
+
+ALTER TABLE transactions ADD COLUMN ledger_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE monthly_budgets ADD COLUMN ledger_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tagged_budgets ADD COLUMN ledger_id INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,322 @@
+package ledgers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	goqu "github.com/doug-martin/goqu/v9"
+	"github.com/mattn/go-sqlite3"
+)
+
+var ErrNotMember = errors.New("you are not a member of this ledger")
+
+type LedgerService struct {
+	DB *goqu.Database
+}
+
+func (service *LedgerService) Create(userID int64, name string) (*Ledger, error) {
+	if name == "" {
+		return nil, errors.New("ledger name cannot be empty")
+	}
+	var ledger *Ledger
+	err := service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		var err error
+		ledger, err = createLedger(tx, userID, name, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ledger, nil
+}
+
+// CreateInTx creates a shared ledger owned by userID as part of a larger transaction.
+func (service *LedgerService) CreateInTx(tx *goqu.TxDatabase, userID int64, name string) (*Ledger, error) {
+	if name == "" {
+		return nil, errors.New("ledger name cannot be empty")
+	}
+	return createLedger(tx, userID, name, false)
+}
+
+func createLedger(tx *goqu.TxDatabase, userID int64, name string, personal bool) (*Ledger, error) {
+	now := time.Now().Format(time.RFC3339)
+	result, err := tx.Insert("ledgers").Rows(goqu.Record{
+		"name":     name,
+		"owner_id": userID,
+		"personal": personal,
+		"date":     now,
+	}).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting ledger: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Insert("ledger_members").Rows(goqu.Record{
+		"ledger_id": id,
+		"user_id":   userID,
+		"role":      RoleOwner,
+		"date":      now,
+	}).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting ledger owner: %w", err)
+	}
+	return &Ledger{ID: id, Name: name, OwnerID: userID, Personal: personal, Date: now}, nil
+}
+
+// GetPersonalLedger returns the ledger every user implicitly owns, creating it on first use.
+// Rows written before ledgers existed only carry a user_id, so they are adopted into it.
+func (service *LedgerService) GetPersonalLedger(userID int64) (*Ledger, error) {
+	ledger, err := service.personalLedger(userID)
+	if err != nil || ledger != nil {
+		return ledger, err
+	}
+	var created *Ledger
+	err = service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		var err error
+		created, err = createLedger(tx, userID, "Personal", true)
+		if err != nil {
+			return err
+		}
+		for _, table := range []string{"transactions", "monthly_budgets", "tagged_budgets"} {
+			_, err = tx.Update(table).
+				Set(goqu.Record{"ledger_id": created.ID}).
+				Where(goqu.Ex{"user_id": userID, "ledger_id": 0}).
+				Executor().Exec()
+			if err != nil {
+				return fmt.Errorf("failed to adopt %s into personal ledger: %w", table, err)
+			}
+		}
+		return nil
+	})
+	// A concurrent request created the personal ledger first
+	if isUniqueConstraintError(err) {
+		ledger, err = service.personalLedger(userID)
+		if err == nil && ledger == nil {
+			err = errors.New("personal ledger not found")
+		}
+		return ledger, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// personalLedger returns nil when the user has no personal ledger yet.
+func (service *LedgerService) personalLedger(userID int64) (*Ledger, error) {
+	var ledger Ledger
+	found, err := service.DB.From("ledgers").Where(goqu.Ex{"owner_id": userID, "personal": true}).ScanStruct(&ledger)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+	return &ledger, nil
+}
+
+func isUniqueConstraintError(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+func (service *LedgerService) List(userID int64) ([]LedgerWithRole, error) {
+	ledgers := []LedgerWithRole{}
+	err := service.DB.From(goqu.T("ledgers").As("l")).
+		Join(goqu.T("ledger_members").As("m"), goqu.On(goqu.I("m.ledger_id").Eq(goqu.I("l.id")))).
+		Select("l.id", "l.name", "l.owner_id", "l.personal", "l.date", "m.role").
+		Where(goqu.I("m.user_id").Eq(userID)).
+		Order(goqu.I("l.id").Asc()).
+		ScanStructs(&ledgers)
+	if err != nil {
+		return nil, err
+	}
+	return ledgers, nil
+}
+
+// GetRole returns the role userID holds in ledgerID, or ErrNotMember.
+func (service *LedgerService) GetRole(userID, ledgerID int64) (Role, error) {
+	var role string
+	found, err := service.DB.From("ledger_members").
+		Select("role").
+		Where(goqu.Ex{"ledger_id": ledgerID, "user_id": userID}).
+		ScanVal(&role)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", ErrNotMember
+	}
+	return Role(role), nil
+}
+
+func (service *LedgerService) Rename(ledgerID int64, name string) (*Ledger, error) {
+	if name == "" {
+		return nil, errors.New("ledger name cannot be empty")
+	}
+	_, err := service.DB.Update("ledgers").Set(goqu.Record{"name": name}).Where(goqu.Ex{"id": ledgerID}).Executor().Exec()
+	if err != nil {
+		return nil, err
+	}
+	var ledger Ledger
+	found, err := service.DB.From("ledgers").Where(goqu.Ex{"id": ledgerID}).ScanStruct(&ledger)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("ledger not found")
+	}
+	return &ledger, nil
+}
+
+func (service *LedgerService) ListMembers(ledgerID int64) ([]Member, error) {
+	members := []Member{}
+	err := service.DB.From(goqu.T("ledger_members").As("m")).
+		Join(goqu.T("users").As("u"), goqu.On(goqu.I("u.id").Eq(goqu.I("m.user_id")))).
+		Select("m.ledger_id", "m.user_id", "u.username", "m.role", "m.date").
+		Where(goqu.I("m.ledger_id").Eq(ledgerID)).
+		Order(goqu.I("m.date").Asc()).
+		ScanStructs(&members)
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (service *LedgerService) UpdateMemberRole(ledgerID, userID int64, role Role) error {
+	if !role.Valid() {
+		return fmt.Errorf("invalid role: %s", role)
+	}
+	if err := service.ensureNotLastOwner(ledgerID, userID, role); err != nil {
+		return err
+	}
+	res, err := service.DB.Update("ledger_members").
+		Set(goqu.Record{"role": role}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "user_id": userID}).
+		Executor().Exec()
+	if err != nil {
+		return err
+	}
+	numRowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if numRowsAffected == 0 {
+		return errors.New("member not found")
+	}
+	return nil
+}
+
+func (service *LedgerService) RemoveMember(ledgerID, userID int64) error {
+	if err := service.ensureNotLastOwner(ledgerID, userID, ""); err != nil {
+		return err
+	}
+	_, err := service.DB.Delete("ledger_members").
+		Where(goqu.Ex{"ledger_id": ledgerID, "user_id": userID}).
+		Executor().Exec()
+	return err
+}
+
+// ensureNotLastOwner prevents a ledger from being left without anyone able to manage it.
+func (service *LedgerService) ensureNotLastOwner(ledgerID, userID int64, newRole Role) error {
+	if newRole == RoleOwner {
+		return nil
+	}
+	currentRole, err := service.GetRole(userID, ledgerID)
+	if err != nil {
+		return err
+	}
+	if currentRole != RoleOwner {
+		return nil
+	}
+	owners, err := service.DB.From("ledger_members").
+		Where(goqu.Ex{"ledger_id": ledgerID, "role": RoleOwner}).
+		Count()
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return errors.New("a ledger must keep at least one owner")
+	}
+	return nil
+}
+
+func (service *LedgerService) CreateInvitation(ledgerID, createdBy int64, role Role) (*Invitation, error) {
+	if !role.Valid() {
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+	codeBytes := make([]byte, 8)
+	if _, err := rand.Read(codeBytes); err != nil {
+		return nil, err
+	}
+	invitation := Invitation{
+		Code:      hex.EncodeToString(codeBytes),
+		LedgerID:  ledgerID,
+		Role:      role,
+		CreatedBy: createdBy,
+		Date:      time.Now().Format(time.RFC3339),
+	}
+	_, err := service.DB.Insert("ledger_invitations").Rows(invitation).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting invitation: %w", err)
+	}
+	return &invitation, nil
+}
+
+// Join redeems a single-use invitation code and makes userID a member of its ledger.
+func (service *LedgerService) Join(userID int64, code string) (*LedgerWithRole, error) {
+	var joined *LedgerWithRole
+	err := service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		var invitation Invitation
+		found, err := tx.From("ledger_invitations").Where(goqu.Ex{"code": code, "used_by": nil}).ScanStruct(&invitation)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("invalid or already used invitation code")
+		}
+		var existing int64
+		isMember, err := tx.From("ledger_members").
+			Select("user_id").
+			Where(goqu.Ex{"ledger_id": invitation.LedgerID, "user_id": userID}).
+			ScanVal(&existing)
+		if err != nil {
+			return err
+		}
+		if isMember {
+			return errors.New("you are already a member of this ledger")
+		}
+		_, err = tx.Insert("ledger_members").Rows(goqu.Record{
+			"ledger_id": invitation.LedgerID,
+			"user_id":   userID,
+			"role":      invitation.Role,
+			"date":      time.Now().Format(time.RFC3339),
+		}).Executor().Exec()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Update("ledger_invitations").
+			Set(goqu.Record{"used_by": userID}).
+			Where(goqu.Ex{"code": code}).
+			Executor().Exec()
+		if err != nil {
+			return err
+		}
+		var ledger Ledger
+		_, err = tx.From("ledgers").Where(goqu.Ex{"id": invitation.LedgerID}).ScanStruct(&ledger)
+		if err != nil {
+			return err
+		}
+		joined = &LedgerWithRole{Ledger: ledger, Role: invitation.Role}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return joined, nil
+}
@@ -0,0 +1,92 @@
+package loans
+
+import (
+	"math"
+	"time"
+)
+
+// maxExtraInstallments stops a schedule that does not converge, which can only happen with inconsistent data.
+const maxExtraInstallments = 12
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// MonthlyPayment is the installment that pays off principal in termMonths equal payments at annualRate percent.
+func MonthlyPayment(principal float64, annualRate float64, termMonths int) float64 {
+	rate := annualRate / 100 / 12
+	if rate == 0 {
+		return roundCents(principal / float64(termMonths))
+	}
+	return roundCents(principal * rate / (1 - math.Pow(1+rate, -float64(termMonths))))
+}
+
+// dueDate is the day of the n-th payment after start, moved to the last day of shorter months.
+func dueDate(start time.Time, n int) string {
+	lastDay := time.Date(start.Year(), start.Month()+time.Month(n)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return time.Date(start.Year(), start.Month()+time.Month(n), min(start.Day(), lastDay), 0, 0, 0, 0, time.UTC).Format(time.DateOnly)
+}
+
+// installments lays out the monthly payments of loan. Booked extra payments lower the balance before the
+// next installment is due; extraMonthly is paid on top of every installment after the first paid ones.
+// The installment stays the same, so extra payments shorten the loan instead of lowering the payment.
+func installments(loan Loan, extras []Payment, paid int, extraMonthly float64) []Installment {
+	start, err := time.Parse(time.DateOnly, loan.StartDate)
+	if err != nil {
+		return nil
+	}
+	rate := loan.AnnualRate / 100 / 12
+	payment := MonthlyPayment(loan.Principal, loan.AnnualRate, loan.TermMonths)
+	balance := loan.Principal
+	result := []Installment{}
+	next := 0
+	for number := 1; balance > 0 && number <= loan.TermMonths+maxExtraInstallments; number++ {
+		installment := Installment{Number: number, Date: dueDate(start, number), Paid: number <= paid}
+		for next < len(extras) && extras[next].Date <= installment.Date {
+			installment.Extra += extras[next].Principal
+			next++
+		}
+		installment.Extra = min(installment.Extra, balance)
+		balance = roundCents(balance - installment.Extra)
+		if balance > 0 {
+			installment.Interest = roundCents(balance * rate)
+			installment.Principal = roundCents(payment - installment.Interest)
+			// The last installment also settles what rounding the payment to cents left over
+			if installment.Principal > balance || number >= loan.TermMonths {
+				installment.Principal = balance
+			}
+			installment.Payment = roundCents(installment.Interest + installment.Principal)
+			balance = roundCents(balance - installment.Principal)
+		}
+		if number > paid && extraMonthly > 0 {
+			extra := min(extraMonthly, balance)
+			installment.Extra = roundCents(installment.Extra + extra)
+			balance = roundCents(balance - extra)
+		}
+		installment.Balance = balance
+		result = append(result, installment)
+	}
+	return result
+}
+
+// amortize builds the schedule of loan given its booked extra payments and the number of installments already
+// paid, comparing it with paying exactly as agreed.
+func amortize(loan Loan, extras []Payment, paid int, extraMonthly float64) Schedule {
+	schedule := Schedule{
+		MonthlyPayment: MonthlyPayment(loan.Principal, loan.AnnualRate, loan.TermMonths),
+		Installments:   installments(loan, extras, paid, extraMonthly),
+	}
+	for _, installment := range schedule.Installments {
+		schedule.TotalInterest += installment.Interest
+		schedule.PayoffDate = installment.Date
+	}
+	original := installments(loan, nil, 0, 0)
+	originalInterest := 0.0
+	for _, installment := range original {
+		originalInterest += installment.Interest
+		schedule.OriginalPayoffDate = installment.Date
+	}
+	schedule.TotalInterest = roundCents(schedule.TotalInterest)
+	schedule.InterestSaved = roundCents(originalInterest - schedule.TotalInterest)
+	return schedule
+}
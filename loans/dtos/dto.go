@@ -0,0 +1,43 @@
+package loans
+
+type CreateLoanDTO struct {
+	Name      string  `json:"name"`
+	Principal float64 `json:"principal"`
+	// AnnualRate is the nominal yearly interest rate in percent
+	AnnualRate float64 `json:"annualRate"`
+	TermMonths int     `json:"termMonths"`
+	// StartDate is when the money was borrowed, the first payment is due a month later
+	StartDate string `json:"startDate"`
+}
+
+type CreatePaymentDTO struct {
+	Kind string `json:"kind"`
+	// Amount is only used for extra payments, scheduled ones pay the installment that is due
+	Amount float64 `json:"amount"`
+	Date   string  `json:"date"`
+}
+
+type DebtDTO struct {
+	Name    string  `json:"name"`
+	Balance float64 `json:"balance"`
+	// AnnualRate is the nominal yearly interest rate in percent
+	AnnualRate     float64 `json:"annualRate"`
+	MinimumPayment float64 `json:"minimumPayment"`
+}
+
+type PayoffPlanDTO struct {
+	Debts []DebtDTO `json:"debts"`
+	// LoanIDs adds loans of the ledger at their current balance, with the installment as minimum payment
+	LoanIDs []int64 `json:"loanIds"`
+	// MonthlyAmount is what goes to all debts together every month
+	MonthlyAmount float64 `json:"monthlyAmount"`
+	// StartDate is when the first payment is made, today when empty
+	StartDate string `json:"startDate"`
+	// CustomOrder lists debt names in the order a custom plan pays them off
+	CustomOrder []string `json:"customOrder"`
+}
+
+type CreatePlanRecurringDTO struct {
+	PayoffPlanDTO
+	Strategy string `json:"strategy"`
+}
@@ -0,0 +1,137 @@
+package loans
+
+// Loan is money borrowed at a fixed rate and paid back in equal monthly installments. It is mirrored by a
+// liability in the net worth, valued at the balance still owed.
+type Loan struct {
+	ID              int64   `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID        int64   `db:"ledger_id" json:"ledgerId"`
+	UserID          int64   `db:"user_id" json:"userId"`
+	Name            string  `db:"name" json:"name"`
+	Principal       float64 `db:"principal" json:"principal"`
+	AnnualRate      float64 `db:"annual_rate" json:"annualRate"`
+	TermMonths      int     `db:"term_months" json:"termMonths"`
+	StartDate       string  `db:"start_date" json:"startDate"`
+	LiabilityItemID int64   `db:"liability_item_id" json:"liabilityItemId"`
+	Date            string  `db:"date" json:"date"`
+}
+
+type PaymentKind string
+
+const (
+	// PaymentScheduled pays the next installment of the schedule.
+	PaymentScheduled PaymentKind = "scheduled"
+	// PaymentExtra goes to the principal only and shortens the loan.
+	PaymentExtra PaymentKind = "extra"
+)
+
+// Payment is a booked payment. The interest part is booked as an expense, the principal part lowers the
+// liability. Balance is what is owed after it.
+type Payment struct {
+	ID            int64       `db:"id" goqu:"skipinsert" json:"id"`
+	LoanID        int64       `db:"loan_id" json:"loanId"`
+	UserID        int64       `db:"user_id" json:"userId"`
+	Kind          PaymentKind `db:"kind" json:"kind"`
+	Date          string      `db:"payment_date" json:"date"`
+	Amount        float64     `db:"amount" json:"amount"`
+	Interest      float64     `db:"interest" json:"interest"`
+	Principal     float64     `db:"principal" json:"principal"`
+	Balance       float64     `db:"balance" json:"balance"`
+	TransactionID *int64      `db:"transaction_id" json:"transactionId"`
+	ValuationID   int64       `db:"valuation_id" json:"-"`
+	Created       string      `db:"date" json:"-"`
+}
+
+type Installment struct {
+	Number    int     `json:"number"`
+	Date      string  `json:"date"`
+	Payment   float64 `json:"payment"`
+	Interest  float64 `json:"interest"`
+	Principal float64 `json:"principal"`
+	// Extra is principal paid on top of the installment since the one before
+	Extra   float64 `json:"extra"`
+	Balance float64 `json:"balance"`
+	Paid    bool    `json:"paid"`
+}
+
+type Schedule struct {
+	MonthlyPayment float64 `json:"monthlyPayment"`
+	TotalInterest  float64 `json:"totalInterest"`
+	PayoffDate     string  `json:"payoffDate"`
+	// OriginalPayoffDate and InterestSaved compare with paying exactly as agreed, without any extra payments
+	OriginalPayoffDate string        `json:"originalPayoffDate"`
+	InterestSaved      float64       `json:"interestSaved"`
+	Installments       []Installment `json:"installments"`
+}
+
+type LoanSummary struct {
+	Loan
+	MonthlyPayment  float64 `json:"monthlyPayment"`
+	Balance         float64 `json:"balance"`
+	PrincipalPaid   float64 `json:"principalPaid"`
+	InterestPaid    float64 `json:"interestPaid"`
+	NextPaymentDate *string `json:"nextPaymentDate"`
+	PayoffDate      string  `json:"payoffDate"`
+}
+
+type Strategy string
+
+const (
+	// StrategySnowball pays off the smallest balance first.
+	StrategySnowball Strategy = "snowball"
+	// StrategyAvalanche pays off the highest rate first, which costs the least interest.
+	StrategyAvalanche Strategy = "avalanche"
+	// StrategyCustom pays off the debts in an order of the user's choosing.
+	StrategyCustom Strategy = "custom"
+)
+
+// Debt is something owed that a payoff plan pays down, a loan of the ledger or one described by the user.
+type Debt struct {
+	Name           string  `json:"name"`
+	LoanID         *int64  `json:"loanId,omitempty"`
+	Balance        float64 `json:"balance"`
+	AnnualRate     float64 `json:"annualRate"`
+	MinimumPayment float64 `json:"minimumPayment"`
+}
+
+type DebtPayoff struct {
+	Debt
+	PayoffDate string  `json:"payoffDate"`
+	Months     int     `json:"months"`
+	Interest   float64 `json:"interest"`
+	Paid       float64 `json:"paid"`
+}
+
+type PlanPayment struct {
+	Name      string  `json:"name"`
+	Payment   float64 `json:"payment"`
+	Interest  float64 `json:"interest"`
+	Principal float64 `json:"principal"`
+	Balance   float64 `json:"balance"`
+}
+
+type PlanMonth struct {
+	Number   int           `json:"number"`
+	Date     string        `json:"date"`
+	Payments []PlanPayment `json:"payments"`
+}
+
+// PayoffPlan pays the debts down month by month with a fixed monthly amount. Every debt gets its minimum
+// payment and what is left goes to the first debt of the strategy's order that is not paid off yet.
+type PayoffPlan struct {
+	Strategy      Strategy     `json:"strategy"`
+	Order         []string     `json:"order"`
+	Months        int          `json:"months"`
+	PayoffDate    string       `json:"payoffDate"`
+	TotalInterest float64      `json:"totalInterest"`
+	TotalPaid     float64      `json:"totalPaid"`
+	Debts         []DebtPayoff `json:"debts"`
+	Schedule      []PlanMonth  `json:"schedule"`
+}
+
+type PayoffComparison struct {
+	MonthlyAmount  float64 `json:"monthlyAmount"`
+	MinimumPayment float64 `json:"minimumPayment"`
+	// Best is the strategy with the least interest, the quickest one on a tie
+	Best  Strategy     `json:"best"`
+	Plans []PayoffPlan `json:"plans"`
+}
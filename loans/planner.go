@@ -0,0 +1,304 @@
+package loans
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	dtos "checkout-go/loans/dtos"
+	"checkout-go/recurring"
+	recurringdtos "checkout-go/recurring/dtos"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+// maxPlanMonths stops a plan whose monthly amount does not get ahead of the interest.
+const maxPlanMonths = 600
+
+// debts collects the debts of a plan, the ledger's loans with their balance and installment included.
+func (service *LoanService) debts(ledgerID int64, body dtos.PayoffPlanDTO) ([]Debt, error) {
+	debts := []Debt{}
+	names := map[string]bool{}
+	add := func(debt Debt) error {
+		debt.Name = strings.TrimSpace(debt.Name)
+		if debt.Name == "" {
+			return errors.New("debt name cannot be empty")
+		}
+		if names[debt.Name] {
+			return fmt.Errorf("debt %q is listed twice", debt.Name)
+		}
+		if debt.Balance < 0 || debt.AnnualRate < 0 || debt.MinimumPayment < 0 {
+			return fmt.Errorf("debt %q cannot have a negative balance, rate or minimum payment", debt.Name)
+		}
+		names[debt.Name] = true
+		if debt.Balance > 0 {
+			debts = append(debts, debt)
+		}
+		return nil
+	}
+	for _, debt := range body.Debts {
+		err := add(Debt{Name: debt.Name, Balance: debt.Balance, AnnualRate: debt.AnnualRate, MinimumPayment: debt.MinimumPayment})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range body.LoanIDs {
+		loan, err := service.Get(ledgerID, id)
+		if err != nil {
+			return nil, err
+		}
+		err = add(Debt{
+			Name:           loan.Name,
+			LoanID:         &loan.ID,
+			Balance:        loan.Balance,
+			AnnualRate:     loan.AnnualRate,
+			MinimumPayment: loan.MonthlyPayment,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(debts) == 0 {
+		return nil, errors.New("there are no debts to pay off")
+	}
+	return debts, nil
+}
+
+// order sorts the debts the way strategy pays them off.
+func order(debts []Debt, strategy Strategy, custom []string) ([]Debt, error) {
+	ordered := append([]Debt(nil), debts...)
+	switch strategy {
+	case StrategySnowball:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			if ordered[i].Balance != ordered[j].Balance {
+				return ordered[i].Balance < ordered[j].Balance
+			}
+			return ordered[i].AnnualRate > ordered[j].AnnualRate
+		})
+	case StrategyAvalanche:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			if ordered[i].AnnualRate != ordered[j].AnnualRate {
+				return ordered[i].AnnualRate > ordered[j].AnnualRate
+			}
+			return ordered[i].Balance < ordered[j].Balance
+		})
+	case StrategyCustom:
+		position := map[string]int{}
+		for i, name := range custom {
+			position[name] = i + 1
+		}
+		for _, debt := range debts {
+			if position[debt.Name] == 0 {
+				return nil, fmt.Errorf("custom order is missing debt %q", debt.Name)
+			}
+		}
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return position[ordered[i].Name] < position[ordered[j].Name]
+		})
+	default:
+		return nil, fmt.Errorf("strategy must be %q, %q or %q", StrategySnowball, StrategyAvalanche, StrategyCustom)
+	}
+	return ordered, nil
+}
+
+// simulate pays down debts, already in payoff order, with monthly every month from start. Interest accrues on
+// the balance before the payment; once a debt is paid off its minimum payment rolls over to the next one.
+func simulate(debts []Debt, strategy Strategy, monthly float64, start time.Time) (PayoffPlan, error) {
+	plan := PayoffPlan{Strategy: strategy, Order: []string{}, Debts: []DebtPayoff{}, Schedule: []PlanMonth{}}
+	payoffs := make([]DebtPayoff, len(debts))
+	balances := make([]float64, len(debts))
+	for i, debt := range debts {
+		plan.Order = append(plan.Order, debt.Name)
+		payoffs[i] = DebtPayoff{Debt: debt}
+		balances[i] = debt.Balance
+	}
+	remaining := len(debts)
+	for number := 1; remaining > 0; number++ {
+		if number > maxPlanMonths {
+			return PayoffPlan{}, fmt.Errorf("the debts are not paid off within %d years, the monthly amount does not get ahead of the interest", maxPlanMonths/12)
+		}
+		month := PlanMonth{Number: number, Date: dueDate(start, number-1), Payments: []PlanPayment{}}
+		available := monthly
+		payments := make([]PlanPayment, len(debts))
+		for i, debt := range debts {
+			if balances[i] <= 0 {
+				continue
+			}
+			interest := roundCents(balances[i] * debt.AnnualRate / 100 / 12)
+			balances[i] = roundCents(balances[i] + interest)
+			payment := min(debt.MinimumPayment, balances[i], available)
+			available = roundCents(available - payment)
+			payments[i] = PlanPayment{Name: debt.Name, Payment: payment, Interest: interest}
+		}
+		for i := range debts {
+			if balances[i] <= 0 || available <= 0 {
+				continue
+			}
+			extra := min(available, roundCents(balances[i]-payments[i].Payment))
+			payments[i].Payment = roundCents(payments[i].Payment + extra)
+			available = roundCents(available - extra)
+		}
+		for i := range debts {
+			if balances[i] <= 0 {
+				continue
+			}
+			payment := &payments[i]
+			payment.Principal = roundCents(payment.Payment - payment.Interest)
+			balances[i] = roundCents(balances[i] - payment.Payment)
+			payment.Balance = balances[i]
+			payoffs[i].Interest += payment.Interest
+			payoffs[i].Paid += payment.Payment
+			if balances[i] <= 0 {
+				payoffs[i].PayoffDate = month.Date
+				payoffs[i].Months = number
+				remaining--
+			}
+			month.Payments = append(month.Payments, *payment)
+		}
+		plan.Schedule = append(plan.Schedule, month)
+		plan.Months = number
+		plan.PayoffDate = month.Date
+	}
+	for _, payoff := range payoffs {
+		payoff.Interest = roundCents(payoff.Interest)
+		payoff.Paid = roundCents(payoff.Paid)
+		plan.TotalInterest += payoff.Interest
+		plan.TotalPaid += payoff.Paid
+		plan.Debts = append(plan.Debts, payoff)
+	}
+	plan.TotalInterest = roundCents(plan.TotalInterest)
+	plan.TotalPaid = roundCents(plan.TotalPaid)
+	return plan, nil
+}
+
+func planStart(body dtos.PayoffPlanDTO, loc *time.Location) (time.Time, error) {
+	if body.StartDate == "" {
+		now := time.Now().In(loc)
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	start, err := time.Parse(time.DateOnly, body.StartDate)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid start date: %w", err)
+	}
+	return start, nil
+}
+
+// Plan compares paying the debts off with the snowball and the avalanche strategy, and with the custom order
+// when one is given, side by side.
+func (service *LoanService) Plan(ledgerID int64, body dtos.PayoffPlanDTO, loc *time.Location) (*PayoffComparison, error) {
+	debts, err := service.debts(ledgerID, body)
+	if err != nil {
+		return nil, err
+	}
+	start, err := planStart(body, loc)
+	if err != nil {
+		return nil, err
+	}
+	comparison := PayoffComparison{MonthlyAmount: body.MonthlyAmount, Plans: []PayoffPlan{}}
+	for _, debt := range debts {
+		comparison.MinimumPayment += min(debt.MinimumPayment, debt.Balance)
+	}
+	comparison.MinimumPayment = roundCents(comparison.MinimumPayment)
+	if body.MonthlyAmount < comparison.MinimumPayment {
+		return nil, fmt.Errorf("the monthly amount does not cover the minimum payments of %.2f", comparison.MinimumPayment)
+	}
+	strategies := []Strategy{StrategySnowball, StrategyAvalanche}
+	if len(body.CustomOrder) > 0 {
+		strategies = append(strategies, StrategyCustom)
+	}
+	for _, strategy := range strategies {
+		ordered, err := order(debts, strategy, body.CustomOrder)
+		if err != nil {
+			return nil, err
+		}
+		plan, err := simulate(ordered, strategy, body.MonthlyAmount, start)
+		if err != nil {
+			return nil, err
+		}
+		comparison.Plans = append(comparison.Plans, plan)
+	}
+	best := comparison.Plans[0]
+	for _, plan := range comparison.Plans[1:] {
+		if plan.TotalInterest < best.TotalInterest || plan.TotalInterest == best.TotalInterest && plan.Months < best.Months {
+			best = plan
+		}
+	}
+	comparison.Best = best.Strategy
+	return &comparison, nil
+}
+
+// CreatePlanRecurring books the chosen plan as recurring expenses, so upcoming payments show up in forecasts.
+// A debt's payment changes when another debt is paid off, so every stretch of equal payments becomes a recurring
+// expense of its own.
+func (service *LoanService) CreatePlanRecurring(userID int64, ledgerID int64, body dtos.CreatePlanRecurringDTO, loc *time.Location) ([]recurring.RecurringExpense, error) {
+	comparison, err := service.Plan(ledgerID, body.PayoffPlanDTO, loc)
+	if err != nil {
+		return nil, err
+	}
+	var plan *PayoffPlan
+	for i := range comparison.Plans {
+		if comparison.Plans[i].Strategy == Strategy(body.Strategy) {
+			plan = &comparison.Plans[i]
+		}
+	}
+	if plan == nil {
+		if body.Strategy == string(StrategyCustom) {
+			return nil, errors.New("a custom plan needs a custom order")
+		}
+		return nil, fmt.Errorf("strategy must be %q, %q or %q", StrategySnowball, StrategyAvalanche, StrategyCustom)
+	}
+
+	type stretch struct {
+		name    string
+		payment float64
+		start   string
+		end     string
+	}
+	var stretches []stretch
+	open := map[string]int{}
+	for _, month := range plan.Schedule {
+		for _, payment := range month.Payments {
+			i, ok := open[payment.Name]
+			if ok && stretches[i].payment == payment.Payment {
+				stretches[i].end = month.Date
+				continue
+			}
+			open[payment.Name] = len(stretches)
+			stretches = append(stretches, stretch{name: payment.Name, payment: payment.Payment, start: month.Date, end: month.Date})
+		}
+	}
+	sort.SliceStable(stretches, func(i, j int) bool {
+		return stretches[i].name < stretches[j].name
+	})
+
+	start, err := planStart(body.PayoffPlanDTO, loc)
+	if err != nil {
+		return nil, err
+	}
+	created := []recurring.RecurringExpense{}
+	err = service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		for _, s := range stretches {
+			end := s.end
+			expense, err := service.RecurringService.CreateInTx(tx, userID, ledgerID, recurringdtos.CreateRecurringExpenseDTO{
+				Name:           "Debt payment: " + s.name,
+				Price:          s.payment,
+				Tags:           []string{LoansTag},
+				DayOfMonth:     start.Day(),
+				IntervalMonths: 1,
+				StartDate:      s.start,
+				EndDate:        &end,
+			})
+			if err != nil {
+				return err
+			}
+			created = append(created, *expense)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
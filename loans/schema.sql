@@ -0,0 +1,27 @@
+CREATE TABLE loans (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ledger_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    principal REAL NOT NULL CHECK (principal > 0),
+    annual_rate REAL NOT NULL CHECK (annual_rate >= 0),
+    term_months INTEGER NOT NULL CHECK (term_months > 0),
+    start_date TEXT NOT NULL,
+    liability_item_id INTEGER NOT NULL,
+    date TEXT NOT NULL
+);
+
+CREATE TABLE loan_payments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('scheduled', 'extra')),
+    payment_date TEXT NOT NULL,
+    amount REAL NOT NULL,
+    interest REAL NOT NULL,
+    principal REAL NOT NULL,
+    balance REAL NOT NULL,
+    transaction_id INTEGER,
+    valuation_id INTEGER NOT NULL,
+    date TEXT NOT NULL
+);
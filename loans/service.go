@@ -0,0 +1,374 @@
+package loans
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	dtos "checkout-go/loans/dtos"
+	"checkout-go/networth"
+	networthdtos "checkout-go/networth/dtos"
+	"checkout-go/recurring"
+	"checkout-go/transactions"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+var (
+	ErrNotFound        = errors.New("loan not found")
+	ErrPaymentNotFound = errors.New("payment not found")
+)
+
+// LoansTag is put on the interest expenses booked for loan payments.
+const LoansTag = "loans"
+
+type LoanService struct {
+	DB                  *goqu.Database
+	TransactionsService *transactions.TransactionService
+	NetWorthService     *networth.NetWorthService
+	RecurringService    *recurring.RecurringService
+}
+
+func validate(body dtos.CreateLoanDTO) error {
+	if body.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if body.Principal <= 0 {
+		return errors.New("principal must be greater than 0")
+	}
+	if body.AnnualRate < 0 {
+		return errors.New("rate cannot be negative")
+	}
+	if body.TermMonths <= 0 {
+		return errors.New("term must be at least one month")
+	}
+	if _, err := time.Parse(time.DateOnly, body.StartDate); err != nil {
+		return fmt.Errorf("invalid start date: %w", err)
+	}
+	return nil
+}
+
+// Create adds a loan together with the liability that tracks its balance in the net worth.
+func (service *LoanService) Create(userID int64, ledgerID int64, body dtos.CreateLoanDTO, loc *time.Location) (*Loan, error) {
+	if err := validate(body); err != nil {
+		return nil, err
+	}
+	loan := Loan{
+		LedgerID:   ledgerID,
+		UserID:     userID,
+		Name:       body.Name,
+		Principal:  body.Principal,
+		AnnualRate: body.AnnualRate,
+		TermMonths: body.TermMonths,
+		StartDate:  body.StartDate,
+		Date:       time.Now().Format(time.RFC3339),
+	}
+	err := service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		item, err := service.NetWorthService.CreateItemInTx(tx, userID, ledgerID, networthdtos.CreateItemDTO{
+			Name:     body.Name,
+			Kind:     string(networth.KindLiability),
+			Category: "loan",
+		})
+		if err != nil {
+			return err
+		}
+		_, err = service.NetWorthService.AddValuationInTx(tx, userID, ledgerID, item.ID, networthdtos.CreateValuationDTO{
+			Value: body.Principal,
+			Date:  body.StartDate,
+		}, loc)
+		if err != nil {
+			return err
+		}
+		loan.LiabilityItemID = item.ID
+		result, err := tx.Insert("loans").Rows(loan).Executor().Exec()
+		if err != nil {
+			return fmt.Errorf("err in inserting loan: %w", err)
+		}
+		loan.ID, err = result.LastInsertId()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+func (service *LoanService) get(ledgerID int64, id int64) (*Loan, error) {
+	var loan Loan
+	found, err := service.DB.From("loans").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).ScanStruct(&loan)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &loan, nil
+}
+
+func (service *LoanService) payments(loanID int64) ([]Payment, error) {
+	payments := []Payment{}
+	err := service.DB.From("loan_payments").
+		Where(goqu.Ex{"loan_id": loanID}).
+		Order(goqu.C("payment_date").Asc(), goqu.C("id").Asc()).
+		ScanStructs(&payments)
+	return payments, err
+}
+
+// split separates the booked extra payments from the number of scheduled installments paid.
+func split(payments []Payment) ([]Payment, int) {
+	var extras []Payment
+	paid := 0
+	for _, payment := range payments {
+		if payment.Kind == PaymentExtra {
+			extras = append(extras, payment)
+		} else {
+			paid++
+		}
+	}
+	return extras, paid
+}
+
+func summarize(loan Loan, payments []Payment) LoanSummary {
+	extras, paid := split(payments)
+	schedule := amortize(loan, extras, paid, 0)
+	summary := LoanSummary{
+		Loan:           loan,
+		MonthlyPayment: schedule.MonthlyPayment,
+		Balance:        loan.Principal,
+		PayoffDate:     schedule.PayoffDate,
+	}
+	for _, payment := range payments {
+		summary.PrincipalPaid += payment.Principal
+		summary.InterestPaid += payment.Interest
+		summary.Balance = payment.Balance
+	}
+	if summary.Balance > 0 && paid < len(schedule.Installments) {
+		summary.NextPaymentDate = &schedule.Installments[paid].Date
+	}
+	return summary
+}
+
+func (service *LoanService) Get(ledgerID int64, id int64) (*LoanSummary, error) {
+	loan, err := service.get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := service.payments(loan.ID)
+	if err != nil {
+		return nil, err
+	}
+	summary := summarize(*loan, payments)
+	return &summary, nil
+}
+
+func (service *LoanService) List(ledgerID int64) ([]LoanSummary, error) {
+	var loans []Loan
+	err := service.DB.From("loans").Where(goqu.Ex{"ledger_id": ledgerID}).Order(goqu.C("start_date").Asc()).ScanStructs(&loans)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]LoanSummary, 0, len(loans))
+	for _, loan := range loans {
+		payments, err := service.payments(loan.ID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, summarize(loan, payments))
+	}
+	return result, nil
+}
+
+// Delete removes a loan, its payments and its liability. The interest expenses stay, they were spent.
+func (service *LoanService) Delete(ledgerID int64, id int64) error {
+	loan, err := service.get(ledgerID, id)
+	if err != nil {
+		return err
+	}
+	return service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		if _, err := tx.Delete("loan_payments").Where(goqu.Ex{"loan_id": id}).Executor().Exec(); err != nil {
+			return err
+		}
+		if _, err := tx.Delete("loans").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec(); err != nil {
+			return err
+		}
+		err := service.NetWorthService.DeleteItemInTx(tx, ledgerID, loan.LiabilityItemID)
+		if errors.Is(err, networth.ErrNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+// Schedule returns the amortization table of a loan. extraMonthly shows what paying that much more with every
+// future installment would do to the payoff date and the interest.
+func (service *LoanService) Schedule(ledgerID int64, id int64, extraMonthly float64) (*Schedule, error) {
+	if extraMonthly < 0 {
+		return nil, errors.New("extra payment cannot be negative")
+	}
+	loan, err := service.get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := service.payments(loan.ID)
+	if err != nil {
+		return nil, err
+	}
+	extras, paid := split(payments)
+	schedule := amortize(*loan, extras, paid, extraMonthly)
+	return &schedule, nil
+}
+
+func (service *LoanService) ListPayments(ledgerID int64, loanID int64) ([]Payment, error) {
+	if _, err := service.get(ledgerID, loanID); err != nil {
+		return nil, err
+	}
+	return service.payments(loanID)
+}
+
+// AddPayment books a payment. A scheduled payment pays the next installment and books its interest as an
+// expense, an extra payment goes to the principal only. Either way the liability is valued at the new balance.
+func (service *LoanService) AddPayment(userID int64, ledgerID int64, loanID int64, body dtos.CreatePaymentDTO, loc *time.Location) (*Payment, error) {
+	loan, err := service.get(ledgerID, loanID)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := service.payments(loan.ID)
+	if err != nil {
+		return nil, err
+	}
+	balance := loan.Principal
+	lastDate := loan.StartDate
+	if len(payments) > 0 {
+		balance = payments[len(payments)-1].Balance
+		lastDate = payments[len(payments)-1].Date
+	}
+	if balance <= 0 {
+		return nil, errors.New("loan is paid off")
+	}
+	if body.Date != "" {
+		if _, err := time.Parse(time.DateOnly, body.Date); err != nil {
+			return nil, fmt.Errorf("invalid date: %w", err)
+		}
+	}
+
+	payment := Payment{
+		LoanID:  loan.ID,
+		UserID:  userID,
+		Kind:    PaymentKind(body.Kind),
+		Created: time.Now().Format(time.RFC3339),
+	}
+	switch payment.Kind {
+	case PaymentScheduled, "":
+		payment.Kind = PaymentScheduled
+		extras, paid := split(payments)
+		schedule := amortize(*loan, extras, paid, 0)
+		if paid >= len(schedule.Installments) {
+			return nil, errors.New("loan is paid off")
+		}
+		installment := schedule.Installments[paid]
+		payment.Date = installment.Date
+		payment.Interest = installment.Interest
+		payment.Principal = min(installment.Principal, balance)
+		payment.Amount = roundCents(payment.Interest + payment.Principal)
+	case PaymentExtra:
+		if body.Amount <= 0 {
+			return nil, errors.New("amount must be greater than 0")
+		}
+		if body.Amount > balance {
+			return nil, fmt.Errorf("amount is more than the balance of %.2f", balance)
+		}
+		payment.Date = time.Now().In(loc).Format(time.DateOnly)
+		payment.Amount = body.Amount
+		payment.Principal = body.Amount
+	default:
+		return nil, fmt.Errorf("kind must be %q or %q", PaymentScheduled, PaymentExtra)
+	}
+	if body.Date != "" {
+		payment.Date = body.Date
+	}
+	if payment.Date < lastDate {
+		return nil, fmt.Errorf("payment cannot be dated before the last one on %s", lastDate)
+	}
+	payment.Balance = roundCents(balance - payment.Principal)
+
+	date, err := time.ParseInLocation(time.DateOnly, payment.Date, loc)
+	if err != nil {
+		return nil, err
+	}
+	err = service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		if payment.Interest > 0 {
+			transaction, err := service.TransactionsService.CreateInTx(tx, transactions.NewTransaction{
+				UserID:   int(userID),
+				LedgerID: ledgerID,
+				Name:     "Interest " + loan.Name,
+				Price:    -payment.Interest,
+				Date:     date,
+				Tags:     []string{LoansTag},
+			}, loc)
+			if err != nil {
+				return err
+			}
+			transactionID := int64(transaction.ID)
+			payment.TransactionID = &transactionID
+		}
+		valuation, err := service.NetWorthService.AddValuationInTx(tx, userID, ledgerID, loan.LiabilityItemID, networthdtos.CreateValuationDTO{
+			Value: payment.Balance,
+			Date:  payment.Date,
+		}, loc)
+		if err != nil {
+			return err
+		}
+		payment.ValuationID = valuation.ID
+		result, err := tx.Insert("loan_payments").Rows(payment).Executor().Exec()
+		if err != nil {
+			return fmt.Errorf("err in inserting payment: %w", err)
+		}
+		payment.ID, err = result.LastInsertId()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// DeletePayment undoes the latest payment of a loan with its interest expense and liability valuation.
+// Older payments cannot be deleted since every later one builds on them.
+func (service *LoanService) DeletePayment(ledgerID int64, loanID int64, paymentID int64) error {
+	loan, err := service.get(ledgerID, loanID)
+	if err != nil {
+		return err
+	}
+	payments, err := service.payments(loan.ID)
+	if err != nil {
+		return err
+	}
+	if len(payments) == 0 {
+		return ErrPaymentNotFound
+	}
+	last := payments[len(payments)-1]
+	if last.ID != paymentID {
+		for _, payment := range payments {
+			if payment.ID == paymentID {
+				return errors.New("only the latest payment can be deleted")
+			}
+		}
+		return ErrPaymentNotFound
+	}
+	return service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		_, err := tx.Delete("loan_payments").Where(goqu.Ex{"loan_id": loanID, "id": paymentID}).Executor().Exec()
+		if err != nil {
+			return err
+		}
+		if last.TransactionID != nil {
+			if err := service.TransactionsService.DeleteInTx(tx, ledgerID, int(*last.TransactionID)); err != nil {
+				return err
+			}
+		}
+		err = service.NetWorthService.DeleteValuationInTx(tx, ledgerID, loan.LiabilityItemID, last.ValuationID)
+		if errors.Is(err, networth.ErrNotFound) || errors.Is(err, networth.ErrValuationNotFound) {
+			return nil
+		}
+		return err
+	})
+}
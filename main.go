@@ -8,8 +8,29 @@ import (
 	"path/filepath"
 
 	// migration "checkout-go/migrations"
+	"checkout-go/anomalies"
 	"checkout-go/auth"
+	"checkout-go/backup"
 	"checkout-go/budgets"
+	"checkout-go/cards"
+	"checkout-go/customfields"
+	"checkout-go/forecast"
+	"checkout-go/goals"
+	"checkout-go/investments"
+	"checkout-go/invoices"
+	"checkout-go/journal"
+	"checkout-go/ledgers"
+	"checkout-go/loans"
+	"checkout-go/networth"
+	"checkout-go/priceindex"
+	"checkout-go/projects"
+	"checkout-go/qif"
+	"checkout-go/reconciliation"
+	"checkout-go/recurring"
+	"checkout-go/reimbursements"
+	"checkout-go/settings"
+	"checkout-go/sqlitefuncs"
+	"checkout-go/tax"
 	"checkout-go/transactions"
 	"checkout-go/users"
 
@@ -26,7 +47,7 @@ var content embed.FS
 
 func main() {
 	// Initialize SQLite database
-	db, err := sqlx.Open("sqlite3", "./sqlite3.db")
+	db, err := sqlx.Open(sqlitefuncs.DriverName, "./sqlite3.db")
 	if err != nil {
 		fmt.Printf("err: %v\n", err)
 		return
@@ -47,9 +68,13 @@ CREATE TABLE IF NOT EXISTS transactions (
 		fmt.Printf("err: %v\n", err)
 		return
 	}
-	transactionsService := transactions.TransactionService{
+	customFieldService := customfields.CustomFieldService{
 		DB: goquDB,
 	}
+	transactionsService := transactions.TransactionService{
+		DB:           goquDB,
+		CustomFields: &customFieldService,
+	}
 
 	// migration.MigrateExpensesFromMongoToSql(&transactionsService)
 	usersService := users.UsersService{
@@ -61,9 +86,56 @@ CREATE TABLE IF NOT EXISTS transactions (
 		UserService: &usersService,
 		HmacSecret:  hmacSecret,
 	}
+	ledgersController := ledgers.LedgersController{
+		LedgerService: &ledgers.LedgerService{
+			DB: goquDB,
+		},
+		AuthService: &authService,
+	}
+	settingsController := settings.SettingsController{
+		SettingsService: &settings.SettingsService{
+			DB: goquDB,
+		},
+		AuthService:   &authService,
+		LedgerContext: &ledgersController,
+	}
+	anomalyService := anomalies.AnomalyService{
+		DB: goquDB,
+	}
+	anomaliesController := anomalies.AnomaliesController{
+		AnomalyService: &anomalyService,
+		LedgerContext:  &ledgersController,
+	}
+	reconciliationService := reconciliation.ReconciliationService{
+		DB: goquDB,
+	}
+	reconciliationsController := reconciliation.ReconciliationsController{
+		ReconciliationService: &reconciliationService,
+		AuthService:           &authService,
+		LedgerContext:         &ledgersController,
+		SettingsContext:       &settingsController,
+	}
+	priceIndexService := priceindex.PriceIndexService{
+		DB: goquDB,
+	}
+	priceIndexController := priceindex.PriceIndexController{
+		PriceIndexService: &priceIndexService,
+		AuthService:       &authService,
+	}
+	customFieldsController := customfields.CustomFieldsController{
+		CustomFieldService: &customFieldService,
+		AuthService:        &authService,
+		LedgerContext:      &ledgersController,
+	}
 	transactionController := transactions.TransactionController{
 		TransactionsService: transactionsService,
 		AuthService:         &authService,
+		LedgerContext:       &ledgersController,
+		SettingsContext:     &settingsController,
+		AnomalyScorer:       &anomalyService,
+		AssertionChecker:    &reconciliationService,
+		PriceIndex:          &priceIndexService,
+		Books:               ledgersController.LedgerService,
 	}
 
 	budgetsService := budgets.BudgetService{
@@ -71,8 +143,155 @@ CREATE TABLE IF NOT EXISTS transactions (
 	}
 
 	budgetsController := budgets.BudgetsController{
-		BudgetService: budgetsService,
-		AuthService:   &authService,
+		BudgetService:   budgetsService,
+		AuthService:     &authService,
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	recurringService := recurring.RecurringService{
+		DB: goquDB,
+	}
+	recurringController := recurring.RecurringController{
+		RecurringService: &recurringService,
+		AuthService:      &authService,
+		LedgerContext:    &ledgersController,
+	}
+
+	goalService := goals.GoalService{
+		DB: goquDB,
+	}
+
+	forecastController := forecast.ForecastController{
+		ForecastService: &forecast.ForecastService{
+			TransactionsService: &transactionsService,
+			BudgetService:       &budgetsService,
+			RecurringService:    &recurringService,
+			GoalService:         &goalService,
+		},
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	goalsController := goals.GoalsController{
+		GoalService:     &goalService,
+		AuthService:     &authService,
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	netWorthService := networth.NetWorthService{
+		DB:                  goquDB,
+		TransactionsService: &transactionsService,
+	}
+	netWorthController := networth.NetWorthController{
+		NetWorthService: &netWorthService,
+		AuthService:     &authService,
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	investmentsController := investments.InvestmentsController{
+		InvestmentService: &investments.InvestmentService{
+			DB:                  goquDB,
+			TransactionsService: &transactionsService,
+		},
+		AuthService:     &authService,
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	loansController := loans.LoansController{
+		LoanService: &loans.LoanService{
+			DB:                  goquDB,
+			TransactionsService: &transactionsService,
+			NetWorthService:     &netWorthService,
+			RecurringService:    &recurringService,
+		},
+		AuthService:     &authService,
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	cardsController := cards.CardsController{
+		CardService: &cards.CardService{
+			DB: goquDB,
+		},
+		AuthService:     &authService,
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	journalController := journal.JournalController{
+		JournalService: &journal.JournalService{
+			DB:                  goquDB,
+			TransactionsService: &transactionsService,
+			AnomalyService:      &anomalyService,
+		},
+		AuthService:     &authService,
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	qifController := qif.QIFController{
+		QIFService: &qif.QIFService{
+			TransactionsService: &transactionsService,
+			AnomalyService:      &anomalyService,
+		},
+		AuthService:     &authService,
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	taxController := tax.TaxController{
+		TaxService: &tax.TaxService{
+			DB: goquDB,
+		},
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	reimbursementController := reimbursements.ReimbursementController{
+		ReimbursementService: &reimbursements.ReimbursementService{
+			DB:                  goquDB,
+			TransactionsService: &transactionsService,
+		},
+		AuthService:     &authService,
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	invoicesController := invoices.InvoicesController{
+		InvoiceService: &invoices.InvoiceService{
+			DB:                  goquDB,
+			TransactionsService: &transactionsService,
+		},
+		AuthService:     &authService,
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	projectsController := projects.ProjectsController{
+		ProjectService: &projects.ProjectService{
+			DB:                  goquDB,
+			TransactionsService: &transactionsService,
+			LedgerService:       ledgersController.LedgerService,
+		},
+		AuthService:     &authService,
+		LedgerContext:   &ledgersController,
+		SettingsContext: &settingsController,
+	}
+
+	backupController := backup.BackupController{
+		BackupService: &backup.BackupService{
+			DB:                 goquDB,
+			LedgerService:      ledgersController.LedgerService,
+			SettingsService:    settingsController.SettingsService,
+			AnomalyService:     &anomalyService,
+			CustomFieldService: &customFieldService,
+		},
+		AuthService:     &authService,
+		SettingsContext: &settingsController,
 	}
 
 	authController := auth.AuthController{
@@ -140,30 +359,156 @@ CREATE TABLE IF NOT EXISTS transactions (
 	// r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(CORS)
-	r.With(authController.RequireLoginMiddleware).Post("/expenses", transactionController.CreateExpense)
-	r.With(authController.RequireLoginMiddleware).Put("/expenses/{id}", transactionController.UpdateExpense)
-	r.With(authController.RequireLoginMiddleware).Delete("/expenses/{id}", transactionController.DeleteExpense)
-	r.With(authController.RequireLoginMiddleware).Get("/expenses/statistics/yearly/{year}", transactionController.GetExpensesMonthlyStatisticsForAYear)
-	r.With(authController.RequireLoginMiddleware).Get("/expenses/statistics/{year}/{month}", transactionController.GetExpensesDailyStatisticsForMonthInYear)
-	r.With(authController.RequireLoginMiddleware).Get("/expenses/current-month-sum", transactionController.GetExpensesSumForCurrentMonth)
-	r.With(authController.RequireLoginMiddleware).Get("/transactions/income-spent-percentage", transactionController.GetIncomeSpentPercentage)
-	r.With(authController.RequireLoginMiddleware).Get("/transactions/cumulative-balance", transactionController.GetCumulativeBalancePerMonth)
-	r.With(authController.RequireLoginMiddleware).Get("/transactions/{id}", transactionController.GetTransactionByID)
-	r.With(authController.RequireLoginMiddleware).Get("/expenses/statistics", transactionController.GetTagsStatistics)
-	r.With(authController.RequireLoginMiddleware).With(authController.RequireLoginMiddleware).Get("/expenses", transactionController.ListExpenses)
-	r.With(authController.RequireLoginMiddleware).Get("/balance", transactionController.GetBalance)
-	r.With(authController.RequireLoginMiddleware).Post("/payments", transactionController.CreatePayment)
-	r.With(authController.RequireLoginMiddleware).Get("/payments", transactionController.ListPayments)
-	r.With(authController.RequireLoginMiddleware).Put("/payments/{id}", transactionController.UpdatePayment)
-	r.With(authController.RequireLoginMiddleware).Post("/budgets/monthly", budgetsController.CreateMonthlyBudget)
-	r.With(authController.RequireLoginMiddleware).With(authController.RequireLoginMiddleware).Get("/budgets/monthly", budgetsController.GetMonthlyBudget)
-	r.With(authController.RequireLoginMiddleware).Put("/budgets/monthly", budgetsController.UpdateMonthlyBudget)
-	r.With(authController.RequireLoginMiddleware).Delete("/budgets/monthly", budgetsController.DeleteMonthlyBudget)
-	r.With(authController.RequireLoginMiddleware).Get("/budgets/tagged", budgetsController.GetTaggedBudgets)
-	r.With(authController.RequireLoginMiddleware).Post("/budgets/tagged", budgetsController.CreateTaggedBudget)
-	r.With(authController.RequireLoginMiddleware).Put("/budgets/tagged/{id}", budgetsController.UpdateTaggedBudget)
-	r.With(authController.RequireLoginMiddleware).Delete("/budgets/tagged/{id}", budgetsController.DeleteTaggedBudget)
-	r.With(authController.RequireLoginMiddleware).Get("/budgets/tagged/stats", budgetsController.GetTaggedBudgetStats)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/expenses", transactionController.CreateExpense)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/expenses/{id}", transactionController.UpdateExpense)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/expenses/{id}", transactionController.DeleteExpense)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/expenses/statistics/yearly", transactionController.GetExpensesMonthlyStatisticsForYears)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/expenses/statistics/yearly/{year}", transactionController.GetExpensesMonthlyStatisticsForAYear)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/expenses/comparison", transactionController.CompareExpenses)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/expenses/statistics/{year}/{month}", transactionController.GetExpensesDailyStatisticsForMonthInYear)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/expenses/current-month-sum", transactionController.GetExpensesSumForCurrentMonth)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/transactions/income-spent-percentage", transactionController.GetIncomeSpentPercentage)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/transactions/cumulative-balance", transactionController.GetCumulativeBalancePerMonth)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Post("/transactions/aggregate", transactionController.Aggregate)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/transactions/{id}", transactionController.GetTransactionByID)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/transactions/{id}/status", transactionController.UpdateTransactionStatus)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/transactions/{id}/tax", transactionController.UpdateTransactionTax)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/expenses/statistics", transactionController.GetTagsStatistics)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/expenses", transactionController.ListExpenses)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/balance", transactionController.GetBalance)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/payments", transactionController.CreatePayment)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/payments", transactionController.ListPayments)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/payments/{id}", transactionController.UpdatePayment)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/budgets/monthly", budgetsController.CreateMonthlyBudget)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/budgets/monthly", budgetsController.GetMonthlyBudget)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/budgets/monthly", budgetsController.UpdateMonthlyBudget)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/budgets/monthly", budgetsController.DeleteMonthlyBudget)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/budgets/tagged", budgetsController.GetTaggedBudgets)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/budgets/tagged", budgetsController.CreateTaggedBudget)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/budgets/tagged/{id}", budgetsController.UpdateTaggedBudget)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/budgets/tagged/{id}", budgetsController.DeleteTaggedBudget)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/budgets/tagged/stats", budgetsController.GetTaggedBudgetStats)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/expenses/forecast", forecastController.GetForecast)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/expenses/safe-to-spend", forecastController.GetSafeToSpend)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor)).Post("/recurring", recurringController.CreateRecurringExpense)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer)).Get("/recurring", recurringController.ListRecurringExpenses)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor)).Put("/recurring/{id}", recurringController.UpdateRecurringExpense)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor)).Delete("/recurring/{id}", recurringController.DeleteRecurringExpense)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer)).Get("/anomalies", anomaliesController.ListAnomalies)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor)).Post("/anomalies/rescan", anomaliesController.RescanAnomalies)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor)).Post("/anomalies/{id}/dismiss", anomaliesController.DismissAnomaly)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/goals", goalsController.CreateGoal)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/goals", goalsController.ListGoals)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/goals/{id}/progress", goalsController.GetGoalProgress)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/goals/{id}", goalsController.UpdateGoal)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/goals/{id}", goalsController.DeleteGoal)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/goals/{id}/contributions", goalsController.AddContribution)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/goals/{id}/contributions/{contributionID}", goalsController.DeleteContribution)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/net-worth", netWorthController.GetNetWorthPerMonth)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/net-worth/items", netWorthController.CreateItem)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/net-worth/items", netWorthController.ListItems)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/net-worth/items/{id}", netWorthController.UpdateItem)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/net-worth/items/{id}", netWorthController.DeleteItem)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/net-worth/items/{id}/valuations", netWorthController.AddValuation)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/net-worth/items/{id}/valuations", netWorthController.ListValuations)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/net-worth/items/{id}/valuations/{valuationID}", netWorthController.DeleteValuation)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/investments/portfolio", investmentsController.GetPortfolio)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/investments/prices", investmentsController.ImportPrices)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/investments/securities", investmentsController.CreateSecurity)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/investments/securities", investmentsController.ListSecurities)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/investments/securities/{id}", investmentsController.UpdateSecurity)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/investments/securities/{id}", investmentsController.DeleteSecurity)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/investments/securities/{id}/prices", investmentsController.ListPrices)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/investments/securities/{id}/trades", investmentsController.AddTrade)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/investments/securities/{id}/trades", investmentsController.ListTrades)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/investments/securities/{id}/trades/{tradeID}", investmentsController.DeleteTrade)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/loans", loansController.CreateLoan)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/loans", loansController.ListLoans)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/loans/{id}", loansController.GetLoan)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/loans/{id}", loansController.DeleteLoan)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/loans/{id}/schedule", loansController.GetSchedule)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/loans/{id}/payments", loansController.AddPayment)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/loans/{id}/payments", loansController.ListPayments)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/loans/{id}/payments/{paymentID}", loansController.DeletePayment)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Post("/loans/payoff-plan", loansController.PayoffPlan)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/loans/payoff-plan/recurring", loansController.CreatePlanRecurring)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/cards", cardsController.CreateCard)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/cards", cardsController.ListCards)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/cards/{id}", cardsController.GetCardStatus)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/cards/{id}", cardsController.UpdateCard)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/cards/{id}", cardsController.DeleteCard)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/cards/{id}/statements", cardsController.ListStatements)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/cards/{id}/payments", cardsController.AddPayment)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/cards/{id}/payments", cardsController.ListPayments)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/cards/{id}/payments/{paymentID}", cardsController.DeletePayment)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/reconciliations", reconciliationsController.StartReconciliation)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/reconciliations", reconciliationsController.ListReconciliations)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/reconciliations/assertions", reconciliationsController.ListAssertions)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/reconciliations/{id}", reconciliationsController.GetReconciliation)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/reconciliations/{id}", reconciliationsController.DeleteReconciliation)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/reconciliations/{id}/transactions/{transactionID}", reconciliationsController.ClearTransaction)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/reconciliations/{id}/finish", reconciliationsController.FinishReconciliation)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/journal/export", journalController.ExportJournal)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/journal/import", journalController.ImportJournal)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/qif/export", qifController.ExportQIF)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/qif/import", qifController.ImportQIF)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/tax/categories", taxController.ListCategories)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/tax/categories", taxController.CreateCategory)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/tax/categories/{id}", taxController.UpdateCategory)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/tax/categories/{id}", taxController.DeleteCategory)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/tax/report", taxController.GetReport)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/expenses/{id}/reimbursable", reimbursementController.MarkReimbursable)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/reimbursements/pending", reimbursementController.ListPending)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/expense-reports", reimbursementController.ListReports)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/expense-reports", reimbursementController.CreateReport)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/expense-reports/{id}", reimbursementController.GetReport)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/expense-reports/{id}", reimbursementController.DeleteReport)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/expense-reports/{id}/export", reimbursementController.ExportReport)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/expense-reports/{id}/reimburse", reimbursementController.Reimburse)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/clients", invoicesController.ListClients)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/clients", invoicesController.CreateClient)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/clients/{id}", invoicesController.UpdateClient)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/clients/{id}", invoicesController.DeleteClient)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/invoices", invoicesController.ListInvoices)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/invoices", invoicesController.CreateInvoice)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/invoices/receivables", invoicesController.GetReceivables)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/invoices/aging", invoicesController.GetAging)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/invoices/{id}", invoicesController.GetInvoice)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/invoices/{id}", invoicesController.UpdateInvoice)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/invoices/{id}", invoicesController.DeleteInvoice)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/invoices/{id}/export", invoicesController.ExportInvoice)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/invoices/{id}/send", invoicesController.SendInvoice)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/invoices/{id}/pay", invoicesController.PayInvoice)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/projects", projectsController.ListProjects)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/projects", projectsController.CreateProject)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer), settingsController.LoadSettings).Get("/projects/{id}/summary", projectsController.GetProjectSummary)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/projects/{id}", projectsController.UpdateProject)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Delete("/projects/{id}", projectsController.DeleteProject)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Post("/projects/{id}/transactions", projectsController.AssignTransactions)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor), settingsController.LoadSettings).Put("/transactions/{id}/project", projectsController.SetTransactionProject)
+	r.With(authController.RequireLoginMiddleware).Get("/settings", settingsController.GetSettings)
+	r.With(authController.RequireLoginMiddleware).Put("/settings", settingsController.UpdateSettings)
+	r.With(authController.RequireLoginMiddleware).Get("/price-index", priceIndexController.ListPriceIndex)
+	r.With(authController.RequireLoginMiddleware).Post("/price-index", priceIndexController.ImportPriceIndex)
+	r.With(authController.RequireLoginMiddleware).Delete("/price-index", priceIndexController.DeletePriceIndex)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer)).Get("/custom-fields", customFieldsController.ListFields)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor)).Post("/custom-fields", customFieldsController.CreateField)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor)).Put("/custom-fields/{id}", customFieldsController.UpdateField)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleEditor)).Delete("/custom-fields/{id}", customFieldsController.DeleteField)
+	r.With(authController.RequireLoginMiddleware, settingsController.LoadSettings).Get("/backup/export", backupController.ExportBackup)
+	r.With(authController.RequireLoginMiddleware, settingsController.LoadSettings).Post("/backup/import", backupController.ImportBackup)
+	r.With(authController.RequireLoginMiddleware, settingsController.LoadSettings).Get("/ledgers/combined", transactionController.GetCombined)
+	r.With(authController.RequireLoginMiddleware).Post("/ledgers", ledgersController.CreateLedger)
+	r.With(authController.RequireLoginMiddleware).Get("/ledgers", ledgersController.ListLedgers)
+	r.With(authController.RequireLoginMiddleware).Post("/ledgers/join", ledgersController.JoinLedger)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleOwner)).Put("/ledgers/{ledgerID}", ledgersController.UpdateLedger)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer)).Get("/ledgers/{ledgerID}/members", ledgersController.ListMembers)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleOwner)).Put("/ledgers/{ledgerID}/members/{userID}", ledgersController.UpdateMember)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleOwner)).Delete("/ledgers/{ledgerID}/members/{userID}", ledgersController.RemoveMember)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleOwner)).Post("/ledgers/{ledgerID}/invitations", ledgersController.CreateInvitation)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleViewer)).Get("/ledgers/{ledgerID}/settings", settingsController.GetLedgerSettings)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleOwner)).Put("/ledgers/{ledgerID}/settings", settingsController.UpdateLedgerSettings)
+	r.With(authController.RequireLoginMiddleware, ledgersController.RequireRole(ledgers.RoleOwner)).Delete("/ledgers/{ledgerID}/settings", settingsController.DeleteLedgerSettings)
 	r.Post("/auth/signup", authController.Signup)
 	r.Post("/auth/login", authController.Login)
 	// Start the server
@@ -174,7 +519,7 @@ func CORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*") // Allow all origins
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+ledgers.LedgerHeader)
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
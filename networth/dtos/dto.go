@@ -0,0 +1,14 @@
+package networth
+
+type CreateItemDTO struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Category string `json:"category"`
+}
+
+type UpdateItemDTO CreateItemDTO
+
+type CreateValuationDTO struct {
+	Value float64 `json:"value"`
+	Date  string  `json:"date"`
+}
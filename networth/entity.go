@@ -0,0 +1,49 @@
+package networth
+
+type Kind string
+
+const (
+	KindAsset     Kind = "asset"
+	KindLiability Kind = "liability"
+)
+
+// Item is something the ledger owns or owes outside of its transactions, like a house, a car, a pension
+// or a mortgage. Its worth is only known through the valuations entered for it.
+type Item struct {
+	ID       int64  `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID int64  `db:"ledger_id" json:"ledgerId"`
+	UserID   int64  `db:"user_id" json:"userId"`
+	Name     string `db:"name" json:"name"`
+	Kind     Kind   `db:"kind" json:"kind"`
+	Category string `db:"category" json:"category"`
+	Date     string `db:"date" json:"date"`
+}
+
+// Valuation is what an item was worth on a day. Liabilities are valued by the positive amount still owed.
+type Valuation struct {
+	ID      int64   `db:"id" goqu:"skipinsert" json:"id"`
+	ItemID  int64   `db:"item_id" json:"itemId"`
+	UserID  int64   `db:"user_id" json:"userId"`
+	Value   float64 `db:"value" json:"value"`
+	Date    string  `db:"valuation_date" json:"date"`
+	Created string  `db:"date" json:"-"`
+}
+
+// ValuedItem is an item with its latest valuation, ValuedAt being nil when it was never valued.
+type ValuedItem struct {
+	Item
+	Value    float64 `json:"value"`
+	ValuedAt *string `json:"valuedAt"`
+}
+
+// NetWorthPoint is the net worth at the end of a financial month. Cash is the balance of all transactions
+// up to then, assets and liabilities the latest valuation of every item on or before the last day.
+type NetWorthPoint struct {
+	YearMonth   string  `json:"year_month"`
+	PeriodStart string  `json:"period_start"`
+	PeriodEnd   string  `json:"period_end"`
+	Cash        float64 `json:"cash"`
+	Assets      float64 `json:"assets"`
+	Liabilities float64 `json:"liabilities"`
+	NetWorth    float64 `json:"net_worth"`
+}
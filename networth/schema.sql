@@ -0,0 +1,20 @@
+CREATE TABLE net_worth_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ledger_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('asset', 'liability')),
+    category TEXT NOT NULL DEFAULT '',
+    date TEXT NOT NULL
+);
+
+CREATE TABLE net_worth_valuations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    value REAL NOT NULL,
+    valuation_date TEXT NOT NULL,
+    date TEXT NOT NULL
+);
+
+CREATE INDEX net_worth_valuations_item_id ON net_worth_valuations (item_id, valuation_date);
@@ -0,0 +1,340 @@
+package networth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	dtos "checkout-go/networth/dtos"
+	"checkout-go/settings"
+	"checkout-go/transactions"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+var (
+	ErrNotFound          = errors.New("item not found")
+	ErrValuationNotFound = errors.New("valuation not found")
+)
+
+type NetWorthService struct {
+	DB                  *goqu.Database
+	TransactionsService *transactions.TransactionService
+}
+
+func validateItem(body dtos.CreateItemDTO) error {
+	if body.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if Kind(body.Kind) != KindAsset && Kind(body.Kind) != KindLiability {
+		return fmt.Errorf("kind must be %q or %q", KindAsset, KindLiability)
+	}
+	return nil
+}
+
+func (service *NetWorthService) CreateItem(userID int64, ledgerID int64, body dtos.CreateItemDTO) (*Item, error) {
+	var item *Item
+	err := service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		var err error
+		item, err = service.CreateItemInTx(tx, userID, ledgerID, body)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// CreateItemInTx adds an item as part of a larger transaction.
+func (service *NetWorthService) CreateItemInTx(tx *goqu.TxDatabase, userID int64, ledgerID int64, body dtos.CreateItemDTO) (*Item, error) {
+	if err := validateItem(body); err != nil {
+		return nil, err
+	}
+	item := Item{
+		LedgerID: ledgerID,
+		UserID:   userID,
+		Name:     body.Name,
+		Kind:     Kind(body.Kind),
+		Category: body.Category,
+		Date:     time.Now().Format(time.RFC3339),
+	}
+	result, err := tx.Insert("net_worth_items").Rows(item).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting item: %w", err)
+	}
+	item.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (service *NetWorthService) GetItem(ledgerID int64, id int64) (*Item, error) {
+	return getItem(service.DB.From("net_worth_items"), ledgerID, id)
+}
+
+func getItem(from *goqu.SelectDataset, ledgerID int64, id int64) (*Item, error) {
+	var item Item
+	found, err := from.Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).ScanStruct(&item)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &item, nil
+}
+
+// ListItems returns the ledger's items with their latest valuation, assets first.
+func (service *NetWorthService) ListItems(ledgerID int64) ([]ValuedItem, error) {
+	var items []Item
+	err := service.DB.From("net_worth_items").
+		Where(goqu.Ex{"ledger_id": ledgerID}).
+		Order(goqu.C("kind").Asc(), goqu.C("category").Asc(), goqu.C("name").Asc()).
+		ScanStructs(&items)
+	if err != nil {
+		return nil, err
+	}
+	valuations, err := service.ledgerValuations(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[int64]Valuation)
+	for _, valuation := range valuations {
+		latest[valuation.ItemID] = valuation.Valuation
+	}
+	result := make([]ValuedItem, 0, len(items))
+	for _, item := range items {
+		valued := ValuedItem{Item: item}
+		if valuation, ok := latest[item.ID]; ok {
+			valued.Value = valuation.Value
+			valued.ValuedAt = &valuation.Date
+		}
+		result = append(result, valued)
+	}
+	return result, nil
+}
+
+func (service *NetWorthService) UpdateItem(ledgerID int64, id int64, body dtos.UpdateItemDTO) (*Item, error) {
+	if err := validateItem(dtos.CreateItemDTO(body)); err != nil {
+		return nil, err
+	}
+	item, err := service.GetItem(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	item.Name = body.Name
+	item.Kind = Kind(body.Kind)
+	item.Category = body.Category
+	_, err = service.DB.Update("net_worth_items").
+		Set(goqu.Record{"name": item.Name, "kind": item.Kind, "category": item.Category}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).
+		Executor().Exec()
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (service *NetWorthService) DeleteItem(ledgerID int64, id int64) error {
+	return service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		return service.DeleteItemInTx(tx, ledgerID, id)
+	})
+}
+
+// DeleteItemInTx removes an item with its valuations as part of a larger transaction.
+func (service *NetWorthService) DeleteItemInTx(tx *goqu.TxDatabase, ledgerID int64, id int64) error {
+	if _, err := getItem(tx.From("net_worth_items"), ledgerID, id); err != nil {
+		return err
+	}
+	if _, err := tx.Delete("net_worth_valuations").Where(goqu.Ex{"item_id": id}).Executor().Exec(); err != nil {
+		return err
+	}
+	_, err := tx.Delete("net_worth_items").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+	return err
+}
+
+func (service *NetWorthService) AddValuation(userID int64, ledgerID int64, itemID int64, body dtos.CreateValuationDTO, loc *time.Location) (*Valuation, error) {
+	var valuation *Valuation
+	err := service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		var err error
+		valuation, err = service.AddValuationInTx(tx, userID, ledgerID, itemID, body, loc)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return valuation, nil
+}
+
+// AddValuationInTx values an item as part of a larger transaction.
+func (service *NetWorthService) AddValuationInTx(tx *goqu.TxDatabase, userID int64, ledgerID int64, itemID int64, body dtos.CreateValuationDTO, loc *time.Location) (*Valuation, error) {
+	if _, err := getItem(tx.From("net_worth_items"), ledgerID, itemID); err != nil {
+		return nil, err
+	}
+	if body.Value < 0 {
+		return nil, errors.New("value cannot be negative")
+	}
+	if body.Date == "" {
+		body.Date = time.Now().In(loc).Format(time.DateOnly)
+	} else if _, err := time.Parse(time.DateOnly, body.Date); err != nil {
+		return nil, fmt.Errorf("invalid date: %w", err)
+	}
+	valuation := Valuation{
+		ItemID:  itemID,
+		UserID:  userID,
+		Value:   body.Value,
+		Date:    body.Date,
+		Created: time.Now().Format(time.RFC3339),
+	}
+	result, err := tx.Insert("net_worth_valuations").Rows(valuation).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting valuation: %w", err)
+	}
+	valuation.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &valuation, nil
+}
+
+func (service *NetWorthService) ListValuations(ledgerID int64, itemID int64) ([]Valuation, error) {
+	if _, err := service.GetItem(ledgerID, itemID); err != nil {
+		return nil, err
+	}
+	valuations := []Valuation{}
+	err := service.DB.From("net_worth_valuations").
+		Where(goqu.Ex{"item_id": itemID}).
+		Order(goqu.C("valuation_date").Asc(), goqu.C("id").Asc()).
+		ScanStructs(&valuations)
+	if err != nil {
+		return nil, err
+	}
+	return valuations, nil
+}
+
+func (service *NetWorthService) DeleteValuation(ledgerID int64, itemID int64, valuationID int64) error {
+	return service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		return service.DeleteValuationInTx(tx, ledgerID, itemID, valuationID)
+	})
+}
+
+// DeleteValuationInTx removes a valuation as part of a larger transaction.
+func (service *NetWorthService) DeleteValuationInTx(tx *goqu.TxDatabase, ledgerID int64, itemID int64, valuationID int64) error {
+	if _, err := getItem(tx.From("net_worth_items"), ledgerID, itemID); err != nil {
+		return err
+	}
+	result, err := tx.Delete("net_worth_valuations").Where(goqu.Ex{"item_id": itemID, "id": valuationID}).Executor().Exec()
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrValuationNotFound
+	}
+	return nil
+}
+
+type ledgerValuation struct {
+	Valuation
+	Kind Kind `db:"kind"`
+}
+
+// ledgerValuations returns every valuation of the ledger's items, oldest first. Valuations of the same day
+// are ordered by when they were entered, so the last one wins.
+func (service *NetWorthService) ledgerValuations(ledgerID int64) ([]ledgerValuation, error) {
+	var valuations []ledgerValuation
+	err := service.DB.From("net_worth_valuations").
+		Join(goqu.T("net_worth_items"), goqu.On(goqu.I("net_worth_items.id").Eq(goqu.I("net_worth_valuations.item_id")))).
+		Select(
+			"net_worth_valuations.id",
+			"net_worth_valuations.item_id",
+			"net_worth_valuations.user_id",
+			"net_worth_valuations.value",
+			"net_worth_valuations.valuation_date",
+			"net_worth_valuations.date",
+			"net_worth_items.kind",
+		).
+		Where(goqu.Ex{"net_worth_items.ledger_id": ledgerID}).
+		Order(goqu.I("net_worth_valuations.valuation_date").Asc(), goqu.I("net_worth_valuations.id").Asc()).
+		ScanStructs(&valuations)
+	return valuations, err
+}
+
+// History returns the net worth at the end of every financial month, from the first month with a transaction
+// or a valuation up to the current one. Months without transactions keep the cash balance of the month before,
+// items keep their latest valuation until a newer one is entered.
+func (service *NetWorthService) History(ledgerID int64, userSettings settings.Settings) ([]NetWorthPoint, error) {
+	balances, err := service.TransactionsService.GetCumulativeBalancePerMonth(ledgerID, userSettings)
+	if err != nil {
+		return nil, err
+	}
+	valuations, err := service.ledgerValuations(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	cash := make(map[string]float64, len(balances))
+	first := ""
+	for _, balance := range balances {
+		cash[balance.YearMonth] = balance.CumulativeBalance
+		if first == "" || balance.YearMonth < first {
+			first = balance.YearMonth
+		}
+	}
+	if len(valuations) > 0 {
+		date, err := time.ParseInLocation(time.DateOnly, valuations[0].Date, userSettings.Location())
+		if err != nil {
+			return nil, err
+		}
+		if label := userSettings.PeriodContaining(date).Label; first == "" || label < first {
+			first = label
+		}
+	}
+	points := []NetWorthPoint{}
+	if first == "" {
+		return points, nil
+	}
+	month, err := time.Parse("2006-01", first)
+	if err != nil {
+		return nil, err
+	}
+	last := userSettings.CurrentPeriod().Label
+
+	latest := make(map[int64]ledgerValuation)
+	next := 0
+	balance := 0.0
+	for {
+		period := userSettings.Period(month.Year(), month.Month())
+		if period.Label > last {
+			break
+		}
+		lastDay := period.LastDay().Format(time.DateOnly)
+		for next < len(valuations) && valuations[next].Date <= lastDay {
+			latest[valuations[next].ItemID] = valuations[next]
+			next++
+		}
+		if value, ok := cash[period.Label]; ok {
+			balance = value
+		}
+		point := NetWorthPoint{
+			YearMonth:   period.Label,
+			PeriodStart: period.Start.Format(time.DateOnly),
+			PeriodEnd:   lastDay,
+			Cash:        balance,
+		}
+		for _, valuation := range latest {
+			if valuation.Kind == KindLiability {
+				point.Liabilities += valuation.Value
+			} else {
+				point.Assets += valuation.Value
+			}
+		}
+		point.NetWorth = point.Cash + point.Assets - point.Liabilities
+		points = append(points, point)
+		month = month.AddDate(0, 1, 0)
+	}
+	return points, nil
+}
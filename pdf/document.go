@@ -0,0 +1,167 @@
+// Package pdf writes simple text documents as PDF, using the standard fonts every reader has so nothing needs
+// to be embedded.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 in points, with the margins every page keeps.
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 50
+)
+
+type font struct {
+	name string // resource name in the content stream
+	size float64
+	// leading is the distance to the next line
+	leading float64
+}
+
+var (
+	headingFont = font{name: "F2", size: 16, leading: 24}
+	boldFont    = font{name: "F2", size: 10, leading: 14}
+	textFont    = font{name: "F1", size: 10, leading: 14}
+	// monoFont lines up columns, its glyphs are all 0.6 em wide
+	monoFont = font{name: "F3", size: 9, leading: 12}
+)
+
+// MonoColumns is how many characters of Mono text fit on a line.
+const MonoColumns = (pageWidth - 2*margin) * 5 / (3 * 9)
+
+// Fit pads or cuts text to a column width of Mono text.
+func Fit(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width-1]) + "~"
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
+
+type line struct {
+	font font
+	text string
+}
+
+// Document collects lines and breaks them into pages.
+type Document struct {
+	pages [][]line
+	y     float64
+}
+
+func New() *Document {
+	return &Document{}
+}
+
+func (d *Document) add(f font, text string) {
+	if len(d.pages) == 0 || d.y-f.leading < margin {
+		d.pages = append(d.pages, nil)
+		d.y = pageHeight - margin
+	}
+	d.y -= f.leading
+	page := &d.pages[len(d.pages)-1]
+	*page = append(*page, line{font: f, text: text})
+}
+
+func (d *Document) Heading(text string) {
+	d.add(headingFont, text)
+}
+
+func (d *Document) Bold(text string) {
+	d.add(boldFont, text)
+}
+
+func (d *Document) Text(text string) {
+	d.add(textFont, text)
+}
+
+// Mono writes a line in a fixed width font, for tables padded with spaces.
+func (d *Document) Mono(text string) {
+	d.add(monoFont, text)
+}
+
+// Space leaves an empty line.
+func (d *Document) Space() {
+	d.add(textFont, "")
+}
+
+// encode turns text into a PDF string in WinAnsiEncoding. Characters it cannot show become "?".
+func encode(text string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r == '€':
+			b.WriteString("\\200")
+		case r == '\t':
+			b.WriteString("    ")
+		case r < 0x20 || r > 0xff || r >= 0x7f && r < 0xa0:
+			b.WriteByte('?')
+		case r > 0x7f:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func (d *Document) content(page []line) []byte {
+	var b bytes.Buffer
+	y := float64(pageHeight - margin)
+	for _, l := range page {
+		y -= l.font.leading
+		if l.text == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "BT /%s %.1f Tf %d %.1f Td %s Tj ET\n", l.font.name, l.font.size, margin, y, encode(l.text))
+	}
+	return b.Bytes()
+}
+
+// WriteTo writes the document, an empty one as a single blank page.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	pages := d.pages
+	if len(pages) == 0 {
+		pages = [][]line{nil}
+	}
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// Objects 1 to 5 are the catalog, the page tree and the fonts; every page adds itself and its content
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	for _, name := range []string{"Helvetica", "Helvetica-Bold", "Courier"} {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 7+2*i))
+		content := d.content(page)
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.WriteTo(w)
+}
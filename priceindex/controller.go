@@ -0,0 +1,56 @@
+package priceindex
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"checkout-go/auth"
+)
+
+type PriceIndexController struct {
+	PriceIndexService *PriceIndexService
+	AuthService       auth.UserContextReader
+}
+
+func (c *PriceIndexController) ListPriceIndex(w http.ResponseWriter, req *http.Request) {
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	points, err := c.PriceIndexService.List(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(points)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ImportPriceIndex takes a CSV file as the request body.
+func (c *PriceIndexController) ImportPriceIndex(w http.ResponseWriter, req *http.Request) {
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	imported, err := c.PriceIndexService.Import(userID, req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(map[string]int{"imported": imported})
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *PriceIndexController) DeletePriceIndex(w http.ResponseWriter, req *http.Request) {
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	err := c.PriceIndexService.Delete(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
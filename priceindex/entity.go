@@ -0,0 +1,8 @@
+package priceindex
+
+// Point is the value of a user's price index, like the CPI, in a calendar month ("2024-01").
+type Point struct {
+	UserID int64   `db:"user_id" json:"-"`
+	Month  string  `db:"month" json:"month"`
+	Value  float64 `db:"value" json:"value"`
+}
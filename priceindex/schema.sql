@@ -0,0 +1,6 @@
+CREATE TABLE price_index (
+    user_id INTEGER NOT NULL,
+    month TEXT NOT NULL,
+    value REAL NOT NULL,
+    PRIMARY KEY (user_id, month)
+);
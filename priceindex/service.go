@@ -0,0 +1,137 @@
+package priceindex
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"checkout-go/transactions"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+type PriceIndexService struct {
+	DB *goqu.Database
+}
+
+func (service *PriceIndexService) List(userID int64) ([]Point, error) {
+	points := []Point{}
+	err := service.DB.From("price_index").
+		Where(goqu.C("user_id").Eq(userID)).
+		Order(goqu.C("month").Asc()).
+		ScanStructs(&points)
+	if err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// column finds the first of names in a CSV header.
+func column(columns map[string]int, names ...string) (int, bool) {
+	for _, name := range names {
+		if i, ok := columns[name]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Import reads a CSV file with a month or date column and a value or index column. Dates are taken as the month
+// they fall in, and months already in the series are overwritten.
+func (service *PriceIndexService) Import(userID int64, data io.Reader) (int, error) {
+	reader := csv.NewReader(data)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("could not read header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	monthColumn, ok := column(columns, "month", "date")
+	if !ok {
+		return 0, errors.New(`missing column "month"`)
+	}
+	valueColumn, ok := column(columns, "value", "index")
+	if !ok {
+		return 0, errors.New(`missing column "value"`)
+	}
+	byMonth := map[string]float64{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		month := strings.TrimSpace(record[monthColumn])
+		if len(month) > len("2006-01") {
+			month = month[:len("2006-01")]
+		}
+		if _, err := time.Parse("2006-01", month); err != nil {
+			return 0, fmt.Errorf("line %d: invalid month %q", line, record[monthColumn])
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[valueColumn]), 64)
+		if err != nil || value <= 0 {
+			return 0, fmt.Errorf("line %d: invalid value %q", line, record[valueColumn])
+		}
+		byMonth[month] = value
+	}
+	if len(byMonth) == 0 {
+		return 0, nil
+	}
+	err = service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		for month, value := range byMonth {
+			_, err := tx.Insert("price_index").
+				Rows(Point{UserID: userID, Month: month, Value: value}).
+				OnConflict(goqu.DoUpdate("user_id, month", goqu.Record{"value": value})).
+				Executor().Exec()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(byMonth), nil
+}
+
+func (service *PriceIndexService) Delete(userID int64) error {
+	_, err := service.DB.Delete("price_index").Where(goqu.C("user_id").Eq(userID)).Executor().Exec()
+	return err
+}
+
+// Deflator converts amounts of any month to prices of the base month, the latest month of the series when base
+// is empty. A month missing from the series takes the value of the latest month before it, and months before
+// the series begins take its first value.
+func (service *PriceIndexService) Deflator(userID int64, base string) (transactions.Deflator, string, error) {
+	points, err := service.List(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(points) == 0 {
+		return nil, "", errors.New("no price index loaded")
+	}
+	if base == "" {
+		base = points[len(points)-1].Month
+	} else if _, err := time.Parse("2006-01", base); err != nil {
+		return nil, "", fmt.Errorf("invalid base month %q", base)
+	}
+	value := func(month string) float64 {
+		i := sort.Search(len(points), func(i int) bool { return points[i].Month > month })
+		return points[max(i-1, 0)].Value
+	}
+	baseValue := value(base)
+	return func(month string) float64 {
+		return baseValue / value(month)
+	}, base, nil
+}
@@ -0,0 +1,22 @@
+package projects
+
+type CreateProjectDTO struct {
+	Name      string   `json:"name"`
+	StartDate string   `json:"startDate"`
+	EndDate   *string  `json:"endDate"`
+	Budget    *float64 `json:"budget"`
+	Currency  *string  `json:"currency"`
+	// Participants splits the cost, the ledger's members when left out
+	Participants *int `json:"participants"`
+}
+
+type UpdateProjectDTO CreateProjectDTO
+
+type AssignDTO struct {
+	TransactionIDs []int `json:"transactionIds"`
+}
+
+type SetProjectDTO struct {
+	// ProjectID moves a transaction into a project, or out of its project when null
+	ProjectID *int64 `json:"projectId"`
+}
@@ -0,0 +1,72 @@
+package projects
+
+// Project groups transactions across tags and months, like a trip or a renovation. Without an end date it is
+// still going on.
+type Project struct {
+	ID           int64    `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID     int64    `db:"ledger_id" json:"ledgerId"`
+	UserID       int64    `db:"user_id" json:"userId"`
+	Name         string   `db:"name" json:"name"`
+	StartDate    string   `db:"start_date" json:"startDate"`
+	EndDate      *string  `db:"end_date" json:"endDate"`
+	Budget       *float64 `db:"budget" json:"budget"`
+	Currency     *string  `db:"currency" json:"currency"`
+	Participants *int     `db:"participants" json:"participants"`
+	Date         string   `db:"date" json:"date"`
+}
+
+// Totals sums a project's transactions. Payments, like refunds or deposits returned, lower the cost.
+type Totals struct {
+	Count    int     `json:"count"`
+	Spent    float64 `json:"spent"`
+	Received float64 `json:"received"`
+	Cost     float64 `json:"cost"`
+}
+
+type ProjectWithTotals struct {
+	Project
+	Totals
+}
+
+// TagTotal sums the transactions carrying a tag. One with several tags counts for each of them, untagged ones
+// are summed under an empty tag.
+type TagTotal struct {
+	Tag string `json:"tag"`
+	Totals
+}
+
+type DayTotal struct {
+	Date string `json:"date"`
+	Totals
+}
+
+// Burn compares the cost with the budget and, for a project with an end date, with the time gone by.
+type Burn struct {
+	Budget     float64 `json:"budget"`
+	Remaining  float64 `json:"remaining"`
+	Percentage float64 `json:"percentage"`
+	OverBudget bool    `json:"overBudget"`
+	// ElapsedPercentage is how much of the project's days have passed, burning faster than that is overspending
+	ElapsedPercentage *float64 `json:"elapsedPercentage,omitempty"`
+	// Projected is the cost at the end date if spending goes on at the pace so far, while the project runs
+	Projected *float64 `json:"projected,omitempty"`
+}
+
+// MemberShare is what one member of a shared ledger paid for a project against an even share of its cost.
+type MemberShare struct {
+	UserID   int64   `json:"userId"`
+	Username string  `json:"username"`
+	Paid     float64 `json:"paid"`
+	Balance  float64 `json:"balance"`
+}
+
+type Summary struct {
+	Project
+	Totals
+	Burn      *Burn         `json:"burn,omitempty"`
+	People    int           `json:"people"`
+	PerPerson float64       `json:"perPerson"`
+	Members   []MemberShare `json:"members"`
+	Tags      []TagTotal    `json:"tags"`
+	Days      []DayTotal    `json:"days"`
+}
@@ -0,0 +1,14 @@
+ALTER TABLE transactions ADD COLUMN project_id INTEGER;
+
+CREATE TABLE projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ledger_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    start_date TEXT NOT NULL,
+    end_date TEXT,
+    budget REAL,
+    currency TEXT,
+    participants INTEGER,
+    date TEXT NOT NULL
+);
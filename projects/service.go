@@ -0,0 +1,386 @@
+package projects
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"checkout-go/ledgers"
+	dtos "checkout-go/projects/dtos"
+	"checkout-go/transactions"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+var (
+	ErrNotFound            = errors.New("project not found")
+	ErrTransactionNotFound = errors.New("transaction not found")
+)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type ProjectService struct {
+	DB                  *goqu.Database
+	TransactionsService *transactions.TransactionService
+	LedgerService       *ledgers.LedgerService
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func validateProject(body *dtos.CreateProjectDTO) error {
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" {
+		return errors.New("project name cannot be empty")
+	}
+	start, err := time.Parse(time.DateOnly, body.StartDate)
+	if err != nil {
+		return fmt.Errorf("invalid start date: %w", err)
+	}
+	if body.EndDate != nil {
+		end, err := time.Parse(time.DateOnly, *body.EndDate)
+		if err != nil {
+			return fmt.Errorf("invalid end date: %w", err)
+		}
+		if end.Before(start) {
+			return errors.New("end date cannot be before the start date")
+		}
+	}
+	if body.Budget != nil && *body.Budget <= 0 {
+		return errors.New("budget must be greater than 0")
+	}
+	if body.Currency != nil && !currencyPattern.MatchString(*body.Currency) {
+		return errors.New("currency must be a currency code like EUR")
+	}
+	if body.Participants != nil && *body.Participants < 1 {
+		return errors.New("a project needs at least one participant")
+	}
+	return nil
+}
+
+func (service *ProjectService) Create(userID int64, ledgerID int64, body dtos.CreateProjectDTO) (*Project, error) {
+	if err := validateProject(&body); err != nil {
+		return nil, err
+	}
+	project := Project{
+		LedgerID:     ledgerID,
+		UserID:       userID,
+		Name:         body.Name,
+		StartDate:    body.StartDate,
+		EndDate:      body.EndDate,
+		Budget:       body.Budget,
+		Currency:     body.Currency,
+		Participants: body.Participants,
+		Date:         time.Now().Format(time.RFC3339),
+	}
+	result, err := service.DB.Insert("projects").Rows(project).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting project: %w", err)
+	}
+	project.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (service *ProjectService) Get(ledgerID int64, id int64) (*Project, error) {
+	var project Project
+	found, err := service.DB.From("projects").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).ScanStruct(&project)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &project, nil
+}
+
+// List returns the ledger's projects with their totals, the latest first.
+func (service *ProjectService) List(ledgerID int64) ([]ProjectWithTotals, error) {
+	projects := []Project{}
+	err := service.DB.From("projects").
+		Where(goqu.Ex{"ledger_id": ledgerID}).
+		Order(goqu.C("start_date").Desc(), goqu.C("id").Desc()).
+		ScanStructs(&projects)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ProjectID int64   `db:"project_id"`
+		Count     int     `db:"count"`
+		Spent     float64 `db:"spent"`
+		Received  float64 `db:"received"`
+	}
+	err = service.DB.From("transactions").
+		Select(
+			goqu.C("project_id"),
+			goqu.COUNT("*").As("count"),
+			goqu.L("COALESCE(SUM(CASE WHEN price < 0 THEN -price ELSE 0 END), 0)").As("spent"),
+			goqu.L("COALESCE(SUM(CASE WHEN price > 0 THEN price ELSE 0 END), 0)").As("received"),
+		).
+		Where(goqu.C("ledger_id").Eq(ledgerID), goqu.C("project_id").IsNotNull()).
+		GroupBy(goqu.C("project_id")).
+		ScanStructs(&rows)
+	if err != nil {
+		return nil, err
+	}
+	totals := map[int64]Totals{}
+	for _, row := range rows {
+		totals[row.ProjectID] = Totals{Count: row.Count, Spent: row.Spent, Received: row.Received}
+	}
+	list := make([]ProjectWithTotals, len(projects))
+	for i, project := range projects {
+		t := totals[project.ID]
+		t.round()
+		list[i] = ProjectWithTotals{Project: project, Totals: t}
+	}
+	return list, nil
+}
+
+func (service *ProjectService) Update(ledgerID int64, id int64, body dtos.UpdateProjectDTO) (*Project, error) {
+	data := dtos.CreateProjectDTO(body)
+	if err := validateProject(&data); err != nil {
+		return nil, err
+	}
+	project, err := service.Get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	project.Name = data.Name
+	project.StartDate = data.StartDate
+	project.EndDate = data.EndDate
+	project.Budget = data.Budget
+	project.Currency = data.Currency
+	project.Participants = data.Participants
+	_, err = service.DB.Update("projects").
+		Set(goqu.Record{
+			"name":         project.Name,
+			"start_date":   project.StartDate,
+			"end_date":     project.EndDate,
+			"budget":       project.Budget,
+			"currency":     project.Currency,
+			"participants": project.Participants,
+		}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).
+		Executor().Exec()
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// Delete removes a project, its transactions stay without one.
+func (service *ProjectService) Delete(ledgerID int64, id int64) error {
+	if _, err := service.Get(ledgerID, id); err != nil {
+		return err
+	}
+	return service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		_, err := tx.Update("transactions").
+			Set(goqu.Record{"project_id": nil}).
+			Where(goqu.Ex{"ledger_id": ledgerID, "project_id": id}).
+			Executor().Exec()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Delete("projects").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+		return err
+	})
+}
+
+// Assign moves transactions into a project, out of the one they were in before.
+func (service *ProjectService) Assign(ledgerID int64, id int64, transactionIDs []int) (int64, error) {
+	if len(transactionIDs) == 0 {
+		return 0, errors.New("no transactions to assign")
+	}
+	if _, err := service.Get(ledgerID, id); err != nil {
+		return 0, err
+	}
+	var found []int
+	err := service.DB.From("transactions").
+		Select("id").
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": transactionIDs}).
+		ScanVals(&found)
+	if err != nil {
+		return 0, err
+	}
+	exists := map[int]bool{}
+	for _, transactionID := range found {
+		exists[transactionID] = true
+	}
+	for _, transactionID := range transactionIDs {
+		if !exists[transactionID] {
+			return 0, fmt.Errorf("transaction %d not found", transactionID)
+		}
+	}
+	result, err := service.DB.Update("transactions").
+		Set(goqu.Record{"project_id": id}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": transactionIDs}).
+		Executor().Exec()
+	if err != nil {
+		return 0, fmt.Errorf("failed to assign transactions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// SetProject moves one transaction into a project, or out of its project when projectID is nil.
+func (service *ProjectService) SetProject(ledgerID int64, transactionID int, projectID *int64) (*transactions.Transaction, error) {
+	if projectID != nil {
+		if _, err := service.Get(ledgerID, *projectID); err != nil {
+			return nil, err
+		}
+	}
+	result, err := service.DB.Update("transactions").
+		Set(goqu.Record{"project_id": projectID}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": transactionID}).
+		Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update transaction: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if updated == 0 {
+		return nil, ErrTransactionNotFound
+	}
+	list, err := service.TransactionsService.List(ledgerID, transactions.TransactionList{IDs: &[]int{transactionID}})
+	if err != nil {
+		return nil, err
+	}
+	if len(*list) == 0 {
+		return nil, ErrTransactionNotFound
+	}
+	return &(*list)[0], nil
+}
+
+func (t *Totals) add(price float64) {
+	t.Count++
+	if price < 0 {
+		t.Spent -= price
+	} else {
+		t.Received += price
+	}
+}
+
+func (t *Totals) round() {
+	t.Spent = roundCents(t.Spent)
+	t.Received = roundCents(t.Received)
+	t.Cost = roundCents(t.Spent - t.Received)
+}
+
+// burn measures the cost against the budget, and against the days gone by when the project has an end date.
+func burn(project Project, cost float64, loc *time.Location) *Burn {
+	if project.Budget == nil {
+		return nil
+	}
+	b := Burn{
+		Budget:     *project.Budget,
+		Remaining:  roundCents(*project.Budget - cost),
+		Percentage: roundCents(cost / *project.Budget * 100),
+		OverBudget: cost > *project.Budget,
+	}
+	if project.EndDate == nil {
+		return &b
+	}
+	start, _ := time.Parse(time.DateOnly, project.StartDate)
+	end, _ := time.Parse(time.DateOnly, *project.EndDate)
+	today, _ := time.Parse(time.DateOnly, time.Now().In(loc).Format(time.DateOnly))
+	days := int(end.Sub(start).Hours()/24) + 1
+	elapsed := int(today.Sub(start).Hours()/24) + 1
+	elapsed = max(0, min(elapsed, days))
+	elapsedPercentage := roundCents(float64(elapsed) / float64(days) * 100)
+	b.ElapsedPercentage = &elapsedPercentage
+	if elapsed > 0 && elapsed < days {
+		projected := roundCents(cost / float64(elapsed) * float64(days))
+		b.Projected = &projected
+	}
+	return &b
+}
+
+// Summary totals a project by tag and by day in the user's time zone, measures its budget burn and splits its
+// cost between the participants. On a shared ledger every member's payments are set against their share.
+func (service *ProjectService) Summary(ledgerID int64, id int64, loc *time.Location) (*Summary, error) {
+	project, err := service.Get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	list, err := service.TransactionsService.List(ledgerID, transactions.TransactionList{ProjectID: &id})
+	if err != nil {
+		return nil, err
+	}
+	members, err := service.LedgerService.ListMembers(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := Summary{Project: *project, Members: []MemberShare{}, Tags: []TagTotal{}, Days: []DayTotal{}}
+	byTag := map[string]*TagTotal{}
+	byDay := map[string]*DayTotal{}
+	paid := map[int64]float64{}
+	for _, t := range *list {
+		summary.add(t.Price)
+		paid[int64(t.UserID)] -= t.Price
+		tags := []string(t.Tags)
+		if len(tags) == 0 {
+			tags = []string{""}
+		}
+		for _, tag := range tags {
+			total, ok := byTag[tag]
+			if !ok {
+				total = &TagTotal{Tag: tag}
+				byTag[tag] = total
+			}
+			total.add(t.Price)
+		}
+		day := t.Date.Time().In(loc).Format(time.DateOnly)
+		total, ok := byDay[day]
+		if !ok {
+			total = &DayTotal{Date: day}
+			byDay[day] = total
+		}
+		total.add(t.Price)
+	}
+	summary.round()
+	summary.Burn = burn(*project, summary.Cost, loc)
+
+	summary.People = len(members)
+	if project.Participants != nil {
+		summary.People = *project.Participants
+	}
+	if summary.People > 0 {
+		summary.PerPerson = roundCents(summary.Cost / float64(summary.People))
+	}
+	for _, member := range members {
+		summary.Members = append(summary.Members, MemberShare{
+			UserID:   member.UserID,
+			Username: member.Username,
+			Paid:     roundCents(paid[member.UserID]),
+			Balance:  roundCents(paid[member.UserID] - summary.PerPerson),
+		})
+	}
+
+	for _, total := range byTag {
+		total.round()
+		summary.Tags = append(summary.Tags, *total)
+	}
+	sort.Slice(summary.Tags, func(i, j int) bool {
+		if summary.Tags[i].Cost != summary.Tags[j].Cost {
+			return summary.Tags[i].Cost > summary.Tags[j].Cost
+		}
+		return summary.Tags[i].Tag < summary.Tags[j].Tag
+	})
+	for _, total := range byDay {
+		total.round()
+		summary.Days = append(summary.Days, *total)
+	}
+	sort.Slice(summary.Days, func(i, j int) bool {
+		return summary.Days[i].Date < summary.Days[j].Date
+	})
+	return &summary, nil
+}
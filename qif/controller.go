@@ -0,0 +1,90 @@
+package qif
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"checkout-go/auth"
+	"checkout-go/customtypes"
+	"checkout-go/ledgers"
+	"checkout-go/settings"
+	"checkout-go/transactions"
+)
+
+type QIFController struct {
+	QIFService      *QIFService
+	AuthService     auth.UserContextReader
+	LedgerContext   ledgers.LedgerContextReader
+	SettingsContext settings.SettingsContextReader
+}
+
+// ExportQIF takes the filters of the transaction lists, ?startDate=, ?endDate= and ?tags=, the account ?type=
+// and the ?dateFormat= to write.
+func (c *QIFController) ExportQIF(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	var filters transactions.TransactionList
+	if tags := query["tags"]; len(tags) > 0 {
+		filters.Tags = &tags
+	}
+	for param, filter := range map[string]**time.Time{"startDate": &filters.DateGte, "endDate": &filters.DateLte} {
+		if value := query.Get(param); value != "" {
+			var date customtypes.TimeWrapper
+			if err := date.Scan(value); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid %s", param), http.StatusBadRequest)
+				return
+			}
+			*filter = (*time.Time)(&date)
+		}
+	}
+	account := AccountBank
+	if accountStr := query.Get("type"); accountStr != "" {
+		account = AccountType(accountStr)
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	data, err := c.QIFService.Export(ledgerID, filters, account, DateOrder(strings.ToLower(query.Get("dateFormat"))), loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/qif")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"checkout.qif\"")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		fmt.Printf("could not write qif: %s\n", err)
+	}
+}
+
+// ImportQIF takes the QIF file as the request body. ?dateFormat=mdy or dmy overrides how dates are read, with
+// ?preview=true it only returns what would be imported.
+func (c *QIFController) ImportQIF(w http.ResponseWriter, req *http.Request) {
+	preview := false
+	if previewStr := req.URL.Query().Get("preview"); previewStr != "" {
+		var err error
+		preview, err = strconv.ParseBool(previewStr)
+		if err != nil {
+			http.Error(w, "Invalid preview", http.StatusBadRequest)
+			return
+		}
+	}
+	order := DateOrder(strings.ToLower(req.URL.Query().Get("dateFormat")))
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	result, err := c.QIFService.Import(userID, ledgerID, req.Body, order, preview, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
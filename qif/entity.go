@@ -0,0 +1,32 @@
+package qif
+
+import "strings"
+
+// AccountType is the kind of account a QIF section holds transactions of.
+type AccountType string
+
+const (
+	AccountBank  AccountType = "Bank"
+	AccountCash  AccountType = "Cash"
+	AccountCCard AccountType = "CCard"
+)
+
+// accountType reads the type of a !Type: header. Investment and asset accounts are not supported.
+func accountType(name string) (AccountType, bool) {
+	for _, t := range []AccountType{AccountBank, AccountCash, AccountCCard} {
+		if strings.EqualFold(name, string(t)) {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// DateOrder tells how to read dates like 01/02/2026.
+type DateOrder string
+
+const (
+	// DateOrderAuto picks the order the dates of the file are valid in, month first if they fit both
+	DateOrderAuto DateOrder = ""
+	DateOrderMDY  DateOrder = "mdy"
+	DateOrderDMY  DateOrder = "dmy"
+)
@@ -0,0 +1,238 @@
+package qif
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type split struct {
+	category string
+	memo     string
+	amount   string
+}
+
+// record is one transaction of a QIF file, with its fields as written.
+type record struct {
+	line     int
+	account  string
+	date     string
+	amount   string
+	payee    string
+	memo     string
+	category string
+	cleared  string
+	splits   []split
+}
+
+// file is what parse read: the transactions of the supported account types and the names of all accounts.
+type file struct {
+	records  []record
+	accounts map[string]bool
+	// skipped counts the transactions of unsupported account types
+	skipped int
+}
+
+// parse reads a QIF file. Accounts are announced with !Account blocks, each followed by a !Type: section with
+// its transactions; files exported from a single account often have only the !Type: header.
+func parse(data io.Reader) (*file, error) {
+	scanner := bufio.NewScanner(data)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	result := file{accounts: map[string]bool{}}
+	var (
+		section string // "account", "transactions", "unsupported" or "" for lists like categories
+		account string
+		pending string
+		current *record
+	)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), " \t\r")
+		if line == "" {
+			continue
+		}
+		if line[0] == '!' {
+			header := strings.ToLower(line)
+			switch {
+			case header == "!account":
+				section = "account"
+			case strings.HasPrefix(header, "!type:"):
+				section = ""
+				name := strings.TrimSpace(line[len("!type:"):])
+				if _, ok := accountType(name); ok {
+					section = "transactions"
+				} else if strings.EqualFold(name, "Invst") || strings.HasPrefix(strings.ToLower(name), "oth") {
+					section = "unsupported"
+				}
+			}
+			continue
+		}
+		code, value := line[0], strings.TrimSpace(line[1:])
+		switch section {
+		case "account":
+			switch code {
+			case 'N':
+				pending = value
+			case '^':
+				if pending != "" {
+					account = pending
+					result.accounts[pending] = true
+				}
+				pending = ""
+			}
+		case "unsupported":
+			if code == '^' {
+				result.skipped++
+			}
+		case "transactions":
+			if current == nil {
+				current = &record{line: number, account: account}
+			}
+			switch code {
+			case 'D':
+				current.date = value
+			case 'T', 'U':
+				current.amount = value
+			case 'P':
+				current.payee = value
+			case 'M':
+				current.memo = value
+			case 'L':
+				current.category = value
+			case 'C':
+				current.cleared = value
+			case 'S':
+				current.splits = append(current.splits, split{category: value})
+			case 'E', '$':
+				if len(current.splits) == 0 {
+					return nil, fmt.Errorf("line %d: split field without a split category", number)
+				}
+				last := &current.splits[len(current.splits)-1]
+				if code == 'E' {
+					last.memo = value
+				} else {
+					last.amount = value
+				}
+			case '^':
+				result.records = append(result.records, *current)
+				current = nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if current != nil && current.date != "" {
+		result.records = append(result.records, *current)
+	}
+	return &result, nil
+}
+
+var dateNumbers = regexp.MustCompile(`\d+`)
+
+// dateParts splits a date like 12/25/2025, 12/25'05, 25.12.2025 or 2025-12-25 into its numbers. ymd is true
+// when the year comes first.
+func dateParts(value string) ([]int, bool, error) {
+	groups := dateNumbers.FindAllString(value, -1)
+	if len(groups) != 3 {
+		return nil, false, fmt.Errorf("invalid date %q", value)
+	}
+	parts := make([]int, 3)
+	for i, group := range groups {
+		parts[i], _ = strconv.Atoi(group)
+	}
+	return parts, len(groups[0]) == 4, nil
+}
+
+// detectOrder finds the order the dates are written in: a day after the 12th settles it, otherwise dates with
+// dots are read day first like in Europe and the rest month first like Quicken writes them.
+func detectOrder(records []record) (DateOrder, error) {
+	dmy, mdy, dots := false, false, false
+	for _, r := range records {
+		parts, ymd, err := dateParts(r.date)
+		if err != nil {
+			return "", fmt.Errorf("line %d: %w", r.line, err)
+		}
+		if ymd {
+			continue
+		}
+		dmy = dmy || parts[0] > 12
+		mdy = mdy || parts[1] > 12
+		dots = dots || strings.Contains(r.date, ".")
+	}
+	switch {
+	case dmy && mdy:
+		return "", fmt.Errorf("dates are written both day and month first")
+	case dmy || !mdy && dots:
+		return DateOrderDMY, nil
+	default:
+		return DateOrderMDY, nil
+	}
+}
+
+// parseDate reads a date in order. Two digit years after an apostrophe are in this century, as Quicken writes
+// them; other two digit years before 70 are too.
+func parseDate(value string, order DateOrder, loc *time.Location) (time.Time, error) {
+	parts, ymd, err := dateParts(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var year, month, day int
+	switch {
+	case ymd:
+		year, month, day = parts[0], parts[1], parts[2]
+	case order == DateOrderDMY:
+		day, month, year = parts[0], parts[1], parts[2]
+	default:
+		month, day, year = parts[0], parts[1], parts[2]
+	}
+	if year < 100 {
+		if strings.Contains(value, "'") || year < 70 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+	if month < 1 || month > 12 || date.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return date, nil
+}
+
+// parseAmount reads amounts like -1,234.56, 1.234,56 or 1 234,56. With a single separator a comma followed
+// by three digits groups thousands, anything else is the decimal separator.
+func parseAmount(value string) (float64, error) {
+	cleaned := strings.NewReplacer(" ", "", "\u00a0", "", "'", "").Replace(value)
+	decimal := strings.LastIndexAny(cleaned, ".,")
+	if decimal >= 0 {
+		separator, other := cleaned[decimal:decimal+1], ","
+		if separator == "," {
+			other = "."
+		}
+		switch {
+		case strings.Count(cleaned, separator) > 1 && !strings.Contains(cleaned, other):
+			decimal = -1
+		case separator == "," && !strings.Contains(cleaned, other) && len(cleaned)-decimal-1 == 3:
+			decimal = -1
+		}
+	}
+	var b strings.Builder
+	for i, r := range cleaned {
+		switch {
+		case i == decimal:
+			b.WriteRune('.')
+		case r == '.' || r == ',':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	amount, err := strconv.ParseFloat(b.String(), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	return amount, nil
+}
@@ -0,0 +1,199 @@
+package qif
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"checkout-go/anomalies"
+	"checkout-go/transactions"
+)
+
+// TransferTag is put on transfers to accounts that are not part of the imported file.
+const TransferTag = "transfer"
+
+type QIFService struct {
+	TransactionsService *transactions.TransactionService
+	AnomalyService      *anomalies.AnomalyService
+}
+
+// categoryTags maps a category like "Auto:Fuel/Vacation" to the tags auto, fuel and vacation. A transfer like
+// "[Savings]" names an account instead.
+func categoryTags(category string) ([]string, string) {
+	category = strings.TrimSpace(category)
+	if strings.HasPrefix(category, "[") {
+		account, _, _ := strings.Cut(strings.TrimPrefix(category, "["), "]")
+		return nil, account
+	}
+	category, class, _ := strings.Cut(category, "/")
+	var tags []string
+	for _, part := range append(strings.Split(category, ":"), strings.Split(class, ":")...) {
+		if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+			tags = append(tags, part)
+		}
+	}
+	return tags, ""
+}
+
+// toTransactions turns a record into a transaction per split, or a single one without splits. Transfers to
+// accounts of the same file are skipped since their other side is imported with that account.
+func toTransactions(r record, accounts map[string]bool, order DateOrder, loc *time.Location) ([]transactions.ImportedTransaction, int, error) {
+	date, err := parseDate(r.date, order, loc)
+	if err != nil {
+		return nil, 0, fmt.Errorf("line %d: %w", r.line, err)
+	}
+	total, err := parseAmount(r.amount)
+	if err != nil {
+		return nil, 0, fmt.Errorf("line %d: %w", r.line, err)
+	}
+	splits := r.splits
+	remaining := total
+	for i := range splits {
+		amount, err := parseAmount(splits[i].amount)
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		remaining -= amount
+	}
+	// What the splits leave over is booked to the category of the transaction
+	if len(splits) == 0 || math.Abs(remaining) >= 0.005 {
+		splits = append(splits, split{category: r.category, memo: r.memo, amount: fmt.Sprintf("%.2f", remaining)})
+	}
+	status := transactions.StatusPending
+	switch strings.ToUpper(r.cleared) {
+	case "*", "C", "X", "R":
+		status = transactions.StatusCleared
+	}
+	var result []transactions.ImportedTransaction
+	skipped := 0
+	for _, s := range splits {
+		amount, _ := parseAmount(s.amount)
+		tags, transfer := categoryTags(s.category)
+		if transfer != "" {
+			if accounts[transfer] {
+				skipped++
+				continue
+			}
+			tags = []string{TransferTag}
+		}
+		if r.account != "" {
+			tags = append(tags, strings.ToLower(r.account))
+		}
+		if tags == nil {
+			tags = []string{}
+		}
+		memo := s.memo
+		if memo == "" {
+			memo = r.memo
+		}
+		name := r.payee
+		if name == "" {
+			name = memo
+		}
+		if name == "" {
+			name = s.category
+		}
+		result = append(result, transactions.ImportedTransaction{
+			Line:   r.line,
+			Name:   name,
+			Price:  math.Round(amount*100) / 100,
+			Note:   memo,
+			Date:   date,
+			Tags:   tags,
+			Status: status,
+		})
+	}
+	return result, skipped, nil
+}
+
+// Import books the transactions of the bank, cash and credit card accounts of a QIF file. Categories become
+// tags and every split its own transaction. Nothing is stored when the file cannot be read, or with preview.
+func (service *QIFService) Import(userID int64, ledgerID int64, data io.Reader, order DateOrder, preview bool, loc *time.Location) (*transactions.ImportResult, error) {
+	if order != DateOrderAuto && order != DateOrderMDY && order != DateOrderDMY {
+		return nil, fmt.Errorf("date format must be %q or %q", DateOrderMDY, DateOrderDMY)
+	}
+	qif, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if order == DateOrderAuto {
+		order, err = detectOrder(qif.records)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var imported []transactions.ImportedTransaction
+	skipped := qif.skipped
+	for _, r := range qif.records {
+		result, skippedSplits, err := toTransactions(r, qif.accounts, order, loc)
+		if err != nil {
+			return nil, err
+		}
+		imported = append(imported, result...)
+		skipped += skippedSplits
+	}
+	result, err := service.TransactionsService.Import(int(userID), ledgerID, imported, skipped, preview, loc)
+	if err != nil {
+		return nil, err
+	}
+	if result.Imported > 0 && service.AnomalyService != nil {
+		if err := service.AnomalyService.Rescan(ledgerID); err != nil {
+			fmt.Printf("could not score imported transactions: %v\n", err)
+		}
+	}
+	return result, nil
+}
+
+// Export writes the transactions matching filters as one QIF account of the given type, oldest first. Tags are
+// written as the category, so they import back as the same tags.
+func (service *QIFService) Export(ledgerID int64, filters transactions.TransactionList, account AccountType, order DateOrder, loc *time.Location) ([]byte, error) {
+	account, ok := accountType(string(account))
+	if !ok {
+		return nil, fmt.Errorf("type must be %q, %q or %q", AccountBank, AccountCash, AccountCCard)
+	}
+	layout := "01/02/2006"
+	switch order {
+	case DateOrderAuto, DateOrderMDY:
+	case DateOrderDMY:
+		// Dots tell the importer the day comes first
+		layout = "02.01.2006"
+	default:
+		return nil, fmt.Errorf("date format must be %q or %q", DateOrderMDY, DateOrderDMY)
+	}
+	list, err := service.TransactionsService.List(ledgerID, filters)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "!Type:%s\n", account)
+	for i := len(*list) - 1; i >= 0; i-- {
+		t := (*list)[i]
+		fmt.Fprintf(&buffer, "D%s\n", t.Date.Time().In(loc).Format(layout))
+		fmt.Fprintf(&buffer, "T%.2f\n", t.Price)
+		switch t.Status {
+		case transactions.StatusCleared:
+			buffer.WriteString("C*\n")
+		case transactions.StatusReconciled:
+			buffer.WriteString("CX\n")
+		}
+		if name := qifLine(t.Name); name != "" {
+			fmt.Fprintf(&buffer, "P%s\n", name)
+		}
+		if note := qifLine(t.Note); note != "" {
+			fmt.Fprintf(&buffer, "M%s\n", note)
+		}
+		if len(t.Tags) > 0 {
+			fmt.Fprintf(&buffer, "L%s\n", qifLine(strings.Join(t.Tags, ":")))
+		}
+		buffer.WriteString("^\n")
+	}
+	return buffer.Bytes(), nil
+}
+
+// qifLine keeps a value on its line, QIF has no way to escape a line break.
+func qifLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,12 @@
+package reconciliation
+
+type CreateReconciliationDTO struct {
+	// Tag limits the reconciliation to the transactions of one account, like a card's tag
+	Tag              string  `json:"tag"`
+	StatementDate    string  `json:"statementDate"`
+	StatementBalance float64 `json:"statementBalance"`
+}
+
+type ClearTransactionDTO struct {
+	Cleared bool `json:"cleared"`
+}
@@ -0,0 +1,61 @@
+package reconciliation
+
+type Status string
+
+const (
+	StatusOpen     Status = "open"
+	StatusFinished Status = "finished"
+)
+
+// Reconciliation matches the transactions up to StatementDate against a bank statement. Tag limits it to the
+// transactions with that tag, an empty Tag covers the whole ledger.
+type Reconciliation struct {
+	ID               int64   `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID         int64   `db:"ledger_id" json:"ledgerId"`
+	UserID           int64   `db:"user_id" json:"userId"`
+	Tag              string  `db:"tag" json:"tag"`
+	StatementDate    string  `db:"statement_date" json:"statementDate"`
+	StatementBalance float64 `db:"statement_balance" json:"statementBalance"`
+	Status           Status  `db:"status" json:"status"`
+	FinishedAt       *string `db:"finished_at" json:"finishedAt"`
+	Date             string  `db:"date" json:"date"`
+}
+
+type StatementTransaction struct {
+	ID     int64   `db:"id" json:"id"`
+	Name   string  `db:"name" json:"name"`
+	Price  float64 `db:"price" json:"price"`
+	Date   string  `db:"local_date" json:"date"`
+	Status string  `db:"status" json:"status"`
+}
+
+// ReconciliationDetails shows how far the ticked off transactions are from the statement. OpeningBalance is
+// what earlier reconciliations of the same scope matched, ClearedBalance adds the cleared transactions to it.
+type ReconciliationDetails struct {
+	Reconciliation
+	OpeningBalance float64                `json:"openingBalance"`
+	ClearedBalance float64                `json:"clearedBalance"`
+	Difference     float64                `json:"difference"`
+	Transactions   []StatementTransaction `json:"transactions"`
+}
+
+// Assertion is the balance a finished reconciliation agreed on with the bank.
+type Assertion struct {
+	ID               int64   `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID         int64   `db:"ledger_id" json:"ledgerId"`
+	ReconciliationID int64   `db:"reconciliation_id" json:"reconciliationId"`
+	Tag              string  `db:"tag" json:"tag"`
+	Date             string  `db:"assertion_date" json:"date"`
+	Balance          float64 `db:"balance" json:"balance"`
+	TimeZone         string  `db:"time_zone" json:"timeZone"` // Zone the statement dates were read in
+	Created          string  `db:"date" json:"-"`
+}
+
+// AssertionCheck compares an assertion with what its reconciled transactions add up to today. An edit, delete or
+// status change of one of them breaks it.
+type AssertionCheck struct {
+	Assertion
+	Actual     float64 `json:"actual"`
+	Difference float64 `json:"difference"`
+	Broken     bool    `json:"broken"`
+}
@@ -0,0 +1,25 @@
+ALTER TABLE transactions ADD COLUMN status TEXT NOT NULL DEFAULT 'pending';
+ALTER TABLE transactions ADD COLUMN reconciliation_id INTEGER;
+
+CREATE TABLE reconciliations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ledger_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    tag TEXT NOT NULL DEFAULT '',
+    statement_date TEXT NOT NULL,
+    statement_balance REAL NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('open', 'finished')),
+    finished_at TEXT,
+    date TEXT NOT NULL
+);
+
+CREATE TABLE balance_assertions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ledger_id INTEGER NOT NULL,
+    reconciliation_id INTEGER NOT NULL,
+    tag TEXT NOT NULL DEFAULT '',
+    assertion_date TEXT NOT NULL,
+    balance REAL NOT NULL,
+    time_zone TEXT NOT NULL,
+    date TEXT NOT NULL
+);
@@ -0,0 +1,349 @@
+package reconciliation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	dtos "checkout-go/reconciliation/dtos"
+	"checkout-go/transactions"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+var (
+	ErrNotFound            = errors.New("reconciliation not found")
+	ErrTransactionNotFound = errors.New("transaction is not on this statement")
+)
+
+// tolerance is how far two balances may differ and still count as equal, float sums are not exact to the cent.
+const tolerance = 0.005
+
+type ReconciliationService struct {
+	DB *goqu.Database
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// inScope limits transactions to the ledger and, when the reconciliation has one, its tag.
+func inScope(ledgerID int64, tag string) []goqu.Expression {
+	expressions := []goqu.Expression{goqu.C("ledger_id").Eq(ledgerID)}
+	if tag != "" {
+		expressions = append(expressions, goqu.L("EXISTS (SELECT 1 FROM json_each(tags) WHERE value = ?)", tag))
+	}
+	return expressions
+}
+
+// finishedIDs selects the finished reconciliations of a scope up to and including maxID.
+func (service *ReconciliationService) finishedIDs(ledgerID int64, tag string, maxID int64) *goqu.SelectDataset {
+	return service.DB.From("reconciliations").
+		Select("id").
+		Where(goqu.Ex{"ledger_id": ledgerID, "tag": tag, "status": StatusFinished}, goqu.C("id").Lte(maxID))
+}
+
+// Start opens a reconciliation for a bank statement. Only one can be open per scope at a time, and statements
+// are reconciled in order.
+func (service *ReconciliationService) Start(userID int64, ledgerID int64, body dtos.CreateReconciliationDTO) (*Reconciliation, error) {
+	if _, err := time.Parse(time.DateOnly, body.StatementDate); err != nil {
+		return nil, fmt.Errorf("invalid statement date: %w", err)
+	}
+	open, err := service.DB.From("reconciliations").
+		Where(goqu.Ex{"ledger_id": ledgerID, "tag": body.Tag, "status": StatusOpen}).
+		Count()
+	if err != nil {
+		return nil, err
+	}
+	if open > 0 {
+		return nil, errors.New("finish or delete the open reconciliation first")
+	}
+	var lastDate string
+	found, err := service.DB.From("reconciliations").
+		Select(goqu.MAX("statement_date")).
+		Where(goqu.Ex{"ledger_id": ledgerID, "tag": body.Tag, "status": StatusFinished}).
+		Having(goqu.COUNT("*").Gt(0)).
+		ScanVal(&lastDate)
+	if err != nil {
+		return nil, err
+	}
+	if found && body.StatementDate < lastDate {
+		return nil, fmt.Errorf("statement date cannot be before the last reconciled statement on %s", lastDate)
+	}
+	reconciliation := Reconciliation{
+		LedgerID:         ledgerID,
+		UserID:           userID,
+		Tag:              body.Tag,
+		StatementDate:    body.StatementDate,
+		StatementBalance: body.StatementBalance,
+		Status:           StatusOpen,
+		Date:             time.Now().Format(time.RFC3339),
+	}
+	result, err := service.DB.Insert("reconciliations").Rows(reconciliation).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting reconciliation: %w", err)
+	}
+	reconciliation.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &reconciliation, nil
+}
+
+func (service *ReconciliationService) get(ledgerID int64, id int64) (*Reconciliation, error) {
+	var reconciliation Reconciliation
+	found, err := service.DB.From("reconciliations").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).ScanStruct(&reconciliation)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &reconciliation, nil
+}
+
+func (service *ReconciliationService) List(ledgerID int64) ([]Reconciliation, error) {
+	reconciliations := []Reconciliation{}
+	err := service.DB.From("reconciliations").
+		Where(goqu.Ex{"ledger_id": ledgerID}).
+		Order(goqu.C("statement_date").Desc(), goqu.C("id").Desc()).
+		ScanStructs(&reconciliations)
+	if err != nil {
+		return nil, err
+	}
+	return reconciliations, nil
+}
+
+// Get returns a reconciliation with its balances. An open one lists the transactions that can still be ticked
+// off, a finished one the transactions it matched.
+func (service *ReconciliationService) Get(ledgerID int64, id int64, loc *time.Location) (*ReconciliationDetails, error) {
+	reconciliation, err := service.get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	details := ReconciliationDetails{Reconciliation: *reconciliation}
+	details.OpeningBalance, err = service.openingBalance(*reconciliation)
+	if err != nil {
+		return nil, err
+	}
+	if reconciliation.Status == StatusOpen {
+		details.Transactions, err = service.candidates(*reconciliation, loc)
+	} else {
+		details.Transactions, err = service.matched(*reconciliation, loc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	details.ClearedBalance = details.OpeningBalance
+	for _, transaction := range details.Transactions {
+		if transaction.Status != string(transactions.StatusPending) {
+			details.ClearedBalance += transaction.Price
+		}
+	}
+	details.ClearedBalance = roundCents(details.ClearedBalance)
+	details.Difference = roundCents(reconciliation.StatementBalance - details.ClearedBalance)
+	return &details, nil
+}
+
+// openingBalance is what the earlier finished reconciliations of the same scope matched. Only one reconciliation
+// of a scope can be open, so all finished ones before an open one are earlier.
+func (service *ReconciliationService) openingBalance(reconciliation Reconciliation) (float64, error) {
+	var balance float64
+	_, err := service.DB.From("transactions").
+		Select(goqu.COALESCE(goqu.SUM("price"), 0)).
+		Where(inScope(reconciliation.LedgerID, reconciliation.Tag)...).
+		Where(
+			goqu.C("reconciliation_id").In(service.finishedIDs(reconciliation.LedgerID, reconciliation.Tag, reconciliation.ID)),
+			goqu.C("reconciliation_id").Neq(reconciliation.ID),
+		).
+		ScanVal(&balance)
+	return balance, err
+}
+
+// candidates are the transactions up to the statement date that no reconciliation matched yet.
+func (service *ReconciliationService) candidates(reconciliation Reconciliation, loc *time.Location) ([]StatementTransaction, error) {
+	result := []StatementTransaction{}
+	localDate := goqu.L("strftime('%Y-%m-%d', local_time(date, ?))", loc.String())
+	err := service.DB.From("transactions").
+		Select("id", "name", "price", "status", localDate.As("local_date")).
+		Where(inScope(reconciliation.LedgerID, reconciliation.Tag)...).
+		Where(goqu.C("reconciliation_id").IsNull(), localDate.Lte(reconciliation.StatementDate)).
+		Order(goqu.C("date").Asc(), goqu.C("id").Asc()).
+		ScanStructs(&result)
+	return result, err
+}
+
+func (service *ReconciliationService) matched(reconciliation Reconciliation, loc *time.Location) ([]StatementTransaction, error) {
+	result := []StatementTransaction{}
+	localDate := goqu.L("strftime('%Y-%m-%d', local_time(date, ?))", loc.String())
+	err := service.DB.From("transactions").
+		Select("id", "name", "price", "status", localDate.As("local_date")).
+		Where(goqu.Ex{"ledger_id": reconciliation.LedgerID, "reconciliation_id": reconciliation.ID}).
+		Order(goqu.C("date").Asc(), goqu.C("id").Asc()).
+		ScanStructs(&result)
+	return result, err
+}
+
+func (service *ReconciliationService) getOpen(ledgerID int64, id int64) (*Reconciliation, error) {
+	reconciliation, err := service.get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if reconciliation.Status != StatusOpen {
+		return nil, errors.New("reconciliation is already finished")
+	}
+	return reconciliation, nil
+}
+
+// ClearTransaction ticks a transaction off against the statement, or unticks it.
+func (service *ReconciliationService) ClearTransaction(ledgerID int64, id int64, transactionID int64, body dtos.ClearTransactionDTO, loc *time.Location) (*ReconciliationDetails, error) {
+	reconciliation, err := service.getOpen(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := service.candidates(*reconciliation, loc)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, candidate := range candidates {
+		found = found || candidate.ID == transactionID
+	}
+	if !found {
+		return nil, ErrTransactionNotFound
+	}
+	status := transactions.StatusPending
+	if body.Cleared {
+		status = transactions.StatusCleared
+	}
+	_, err = service.DB.Update("transactions").
+		Set(goqu.Record{"status": status}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": transactionID}).
+		Executor().Exec()
+	if err != nil {
+		return nil, err
+	}
+	return service.Get(ledgerID, id, loc)
+}
+
+// Finish closes a reconciliation once the cleared transactions add up to the statement balance. They become
+// reconciled, and the statement balance is kept as an assertion that later edits are checked against.
+func (service *ReconciliationService) Finish(ledgerID int64, id int64, loc *time.Location) (*ReconciliationDetails, error) {
+	reconciliation, err := service.getOpen(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	details, err := service.Get(ledgerID, id, loc)
+	if err != nil {
+		return nil, err
+	}
+	if math.Abs(details.Difference) >= tolerance {
+		return nil, fmt.Errorf("cleared balance is %.2f off the statement", details.Difference)
+	}
+	var cleared []int64
+	for _, transaction := range details.Transactions {
+		if transaction.Status == string(transactions.StatusCleared) {
+			cleared = append(cleared, transaction.ID)
+		}
+	}
+	now := time.Now().Format(time.RFC3339)
+	err = service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		if len(cleared) > 0 {
+			_, err := tx.Update("transactions").
+				Set(goqu.Record{"status": transactions.StatusReconciled, "reconciliation_id": id}).
+				Where(goqu.Ex{"ledger_id": ledgerID, "id": cleared}).
+				Executor().Exec()
+			if err != nil {
+				return err
+			}
+		}
+		assertion := Assertion{
+			LedgerID:         ledgerID,
+			ReconciliationID: id,
+			Tag:              reconciliation.Tag,
+			Date:             reconciliation.StatementDate,
+			Balance:          reconciliation.StatementBalance,
+			TimeZone:         loc.String(),
+			Created:          now,
+		}
+		if _, err := tx.Insert("balance_assertions").Rows(assertion).Executor().Exec(); err != nil {
+			return err
+		}
+		_, err := tx.Update("reconciliations").
+			Set(goqu.Record{"status": StatusFinished, "finished_at": now}).
+			Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).
+			Executor().Exec()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return service.Get(ledgerID, id, loc)
+}
+
+// Delete abandons an open reconciliation, the transactions ticked off in it stay cleared.
+func (service *ReconciliationService) Delete(ledgerID int64, id int64) error {
+	if _, err := service.getOpen(ledgerID, id); err != nil {
+		return err
+	}
+	_, err := service.DB.Delete("reconciliations").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+	return err
+}
+
+// check recomputes an assertion from the transactions reconciled up to its reconciliation that are still dated
+// on or before the statement.
+func (service *ReconciliationService) check(assertion Assertion) (*AssertionCheck, error) {
+	var actual float64
+	_, err := service.DB.From("transactions").
+		Select(goqu.COALESCE(goqu.SUM("price"), 0)).
+		Where(inScope(assertion.LedgerID, assertion.Tag)...).
+		Where(
+			goqu.C("reconciliation_id").In(service.finishedIDs(assertion.LedgerID, assertion.Tag, assertion.ReconciliationID)),
+			goqu.L("strftime('%Y-%m-%d', local_time(date, ?))", assertion.TimeZone).Lte(assertion.Date),
+		).
+		ScanVal(&actual)
+	if err != nil {
+		return nil, err
+	}
+	check := AssertionCheck{
+		Assertion:  assertion,
+		Actual:     roundCents(actual),
+		Difference: roundCents(actual - assertion.Balance),
+	}
+	check.Broken = math.Abs(actual-assertion.Balance) >= tolerance
+	return &check, nil
+}
+
+// CheckAssertions returns the balance assertions of a ledger, newest first, or only the broken ones.
+func (service *ReconciliationService) CheckAssertions(ledgerID int64, brokenOnly bool) ([]AssertionCheck, error) {
+	var assertions []Assertion
+	err := service.DB.From("balance_assertions").
+		Where(goqu.Ex{"ledger_id": ledgerID}).
+		Order(goqu.C("assertion_date").Desc(), goqu.C("id").Desc()).
+		ScanStructs(&assertions)
+	if err != nil {
+		return nil, err
+	}
+	result := []AssertionCheck{}
+	for _, assertion := range assertions {
+		check, err := service.check(assertion)
+		if err != nil {
+			return nil, err
+		}
+		if brokenOnly && !check.Broken {
+			continue
+		}
+		result = append(result, *check)
+	}
+	return result, nil
+}
+
+// CountBrokenAssertions implements transactions.AssertionChecker.
+func (service *ReconciliationService) CountBrokenAssertions(ledgerID int64) (int, error) {
+	broken, err := service.CheckAssertions(ledgerID, true)
+	if err != nil {
+		return 0, err
+	}
+	return len(broken), nil
+}
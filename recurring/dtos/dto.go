@@ -0,0 +1,14 @@
+package recurring
+
+type CreateRecurringExpenseDTO struct {
+	Name           string   `json:"name"`
+	Price          float64  `json:"price"`
+	Seller         string   `json:"sellerName"`
+	Tags           []string `json:"tags"`
+	DayOfMonth     int      `json:"dayOfMonth"`
+	IntervalMonths int      `json:"intervalMonths"`
+	StartDate      string   `json:"startDate"`
+	EndDate        *string  `json:"endDate"`
+}
+
+type UpdateRecurringExpenseDTO CreateRecurringExpenseDTO
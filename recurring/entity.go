@@ -0,0 +1,73 @@
+package recurring
+
+import (
+	"time"
+
+	"checkout-go/customtypes"
+)
+
+// RecurringExpense is a charge that comes back every IntervalMonths months on DayOfMonth, like rent or
+// a subscription. Price is the positive amount charged.
+type RecurringExpense struct {
+	ID             int64                   `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID       int64                   `db:"ledger_id" json:"ledgerId"`
+	UserID         int64                   `db:"user_id" json:"userId"`
+	Name           string                  `db:"name" json:"name"`
+	Price          float64                 `db:"price" json:"price"`
+	Seller         string                  `db:"seller" json:"sellerName"`
+	Tags           customtypes.StringSlice `db:"tags" json:"tags"`
+	DayOfMonth     int                     `db:"day_of_month" json:"dayOfMonth"`
+	IntervalMonths int                     `db:"interval_months" json:"intervalMonths"`
+	StartDate      string                  `db:"start_date" json:"startDate"`
+	EndDate        *string                 `db:"end_date" json:"endDate"`
+	Date           string                  `db:"date" json:"date"`
+}
+
+// Occurrence is a single charge of a recurring expense.
+type Occurrence struct {
+	RecurringExpenseID int64    `json:"recurringExpenseId"`
+	Name               string   `json:"name"`
+	Price              float64  `json:"price"`
+	Tags               []string `json:"tags"`
+	Date               string   `json:"date"`
+}
+
+// Occurrences returns the charges due in [from, to), with days counted in loc.
+func (r RecurringExpense) Occurrences(from time.Time, to time.Time, loc *time.Location) []Occurrence {
+	start, err := time.ParseInLocation(time.DateOnly, r.StartDate, loc)
+	if err != nil {
+		return nil
+	}
+	var end time.Time
+	if r.EndDate != nil {
+		end, err = time.ParseInLocation(time.DateOnly, *r.EndDate, loc)
+		if err != nil {
+			return nil
+		}
+	}
+	interval := max(r.IntervalMonths, 1)
+	var occurrences []Occurrence
+	for i := 0; ; i += interval {
+		due := dueDate(start.Year(), start.Month()+time.Month(i), r.DayOfMonth, loc)
+		if !due.Before(to) || (!end.IsZero() && due.After(end)) {
+			break
+		}
+		if due.Before(start) || due.Before(from) {
+			continue
+		}
+		occurrences = append(occurrences, Occurrence{
+			RecurringExpenseID: r.ID,
+			Name:               r.Name,
+			Price:              r.Price,
+			Tags:               r.Tags,
+			Date:               due.Format(time.DateOnly),
+		})
+	}
+	return occurrences
+}
+
+// dueDate is day of the given month, moved to the last day of shorter months.
+func dueDate(year int, month time.Month, day int, loc *time.Location) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	return time.Date(year, month, min(day, lastDay), 0, 0, 0, 0, loc)
+}
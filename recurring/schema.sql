@@ -0,0 +1,14 @@
+CREATE TABLE recurring_expenses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ledger_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    price REAL NOT NULL,
+    seller TEXT NOT NULL DEFAULT '',
+    tags JSONB,
+    day_of_month INTEGER NOT NULL,
+    interval_months INTEGER NOT NULL DEFAULT 1,
+    start_date TEXT NOT NULL,
+    end_date TEXT,
+    date TEXT NOT NULL
+);
@@ -0,0 +1,179 @@
+package recurring
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	dtos "checkout-go/recurring/dtos"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+var ErrNotFound = errors.New("recurring expense not found")
+
+type RecurringService struct {
+	DB *goqu.Database
+}
+
+func validate(body dtos.CreateRecurringExpenseDTO) error {
+	if body.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if body.Price <= 0 {
+		return errors.New("price must be greater than 0")
+	}
+	if body.DayOfMonth < 1 || body.DayOfMonth > 31 {
+		return errors.New("day of month must be between 1 and 31")
+	}
+	if body.IntervalMonths < 0 {
+		return errors.New("interval cannot be negative")
+	}
+	start, err := time.Parse(time.DateOnly, body.StartDate)
+	if err != nil {
+		return fmt.Errorf("invalid start date: %w", err)
+	}
+	if body.EndDate != nil {
+		end, err := time.Parse(time.DateOnly, *body.EndDate)
+		if err != nil {
+			return fmt.Errorf("invalid end date: %w", err)
+		}
+		if end.Before(start) {
+			return errors.New("end date cannot be before start date")
+		}
+	}
+	return nil
+}
+
+func (service *RecurringService) Create(userID int64, ledgerID int64, body dtos.CreateRecurringExpenseDTO) (*RecurringExpense, error) {
+	var expense *RecurringExpense
+	err := service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		var err error
+		expense, err = service.CreateInTx(tx, userID, ledgerID, body)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expense, nil
+}
+
+// CreateInTx adds a recurring expense as part of a larger transaction.
+func (service *RecurringService) CreateInTx(tx *goqu.TxDatabase, userID int64, ledgerID int64, body dtos.CreateRecurringExpenseDTO) (*RecurringExpense, error) {
+	if err := validate(body); err != nil {
+		return nil, err
+	}
+	expense := RecurringExpense{
+		LedgerID:       ledgerID,
+		UserID:         userID,
+		Name:           body.Name,
+		Price:          body.Price,
+		Seller:         body.Seller,
+		Tags:           body.Tags,
+		DayOfMonth:     body.DayOfMonth,
+		IntervalMonths: max(body.IntervalMonths, 1),
+		StartDate:      body.StartDate,
+		EndDate:        body.EndDate,
+		Date:           time.Now().Format(time.RFC3339),
+	}
+	result, err := tx.Insert("recurring_expenses").Rows(expense).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting recurring expense: %w", err)
+	}
+	expense.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &expense, nil
+}
+
+func (service *RecurringService) Get(ledgerID int64, id int64) (*RecurringExpense, error) {
+	var expense RecurringExpense
+	found, err := service.DB.From("recurring_expenses").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).ScanStruct(&expense)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &expense, nil
+}
+
+func (service *RecurringService) List(ledgerID int64) ([]RecurringExpense, error) {
+	expenses := []RecurringExpense{}
+	err := service.DB.From("recurring_expenses").
+		Where(goqu.Ex{"ledger_id": ledgerID}).
+		Order(goqu.C("day_of_month").Asc(), goqu.C("name").Asc()).
+		ScanStructs(&expenses)
+	if err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+func (service *RecurringService) Update(ledgerID int64, id int64, body dtos.UpdateRecurringExpenseDTO) (*RecurringExpense, error) {
+	if err := validate(dtos.CreateRecurringExpenseDTO(body)); err != nil {
+		return nil, err
+	}
+	expense, err := service.Get(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	expense.Name = body.Name
+	expense.Price = body.Price
+	expense.Seller = body.Seller
+	expense.Tags = body.Tags
+	expense.DayOfMonth = body.DayOfMonth
+	expense.IntervalMonths = max(body.IntervalMonths, 1)
+	expense.StartDate = body.StartDate
+	expense.EndDate = body.EndDate
+	_, err = service.DB.Update("recurring_expenses").
+		Set(goqu.Record{
+			"name":            expense.Name,
+			"price":           expense.Price,
+			"seller":          expense.Seller,
+			"tags":            expense.Tags,
+			"day_of_month":    expense.DayOfMonth,
+			"interval_months": expense.IntervalMonths,
+			"start_date":      expense.StartDate,
+			"end_date":        expense.EndDate,
+		}).
+		Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).
+		Executor().Exec()
+	if err != nil {
+		return nil, err
+	}
+	return expense, nil
+}
+
+func (service *RecurringService) Delete(ledgerID int64, id int64) error {
+	result, err := service.DB.Delete("recurring_expenses").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Upcoming lists every charge of the ledger's recurring expenses due in [from, to), ordered by date.
+func (service *RecurringService) Upcoming(ledgerID int64, from time.Time, to time.Time, loc *time.Location) ([]Occurrence, error) {
+	expenses, err := service.List(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	occurrences := []Occurrence{}
+	for _, expense := range expenses {
+		occurrences = append(occurrences, expense.Occurrences(from, to, loc)...)
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].Date < occurrences[j].Date
+	})
+	return occurrences, nil
+}
@@ -0,0 +1,16 @@
+package reimbursements
+
+type MarkReimbursableDTO struct {
+	Reimbursable bool `json:"reimbursable"`
+}
+
+type CreateReportDTO struct {
+	Name string `json:"name"`
+	// TransactionIDs picks the pending expenses to submit, all of them when empty
+	TransactionIDs []int `json:"transactionIds"`
+}
+
+type ReimburseDTO struct {
+	// PaymentID is the incoming payment that paid the report back
+	PaymentID int `json:"paymentId"`
+}
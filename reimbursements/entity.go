@@ -0,0 +1,38 @@
+package reimbursements
+
+import "checkout-go/transactions"
+
+type Status string
+
+const (
+	StatusSubmitted  Status = "submitted"
+	StatusReimbursed Status = "reimbursed"
+)
+
+// ExpenseReport bundles reimbursable expenses handed in together. Total is what was submitted.
+type ExpenseReport struct {
+	ID           int64   `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID     int64   `db:"ledger_id" json:"ledgerId"`
+	UserID       int64   `db:"user_id" json:"userId"`
+	Name         string  `db:"name" json:"name"`
+	Status       Status  `db:"status" json:"status"`
+	Total        float64 `db:"total" json:"total"`
+	PaymentID    *int64  `db:"payment_id" json:"paymentId,omitempty"`
+	SubmittedAt  string  `db:"submitted_at" json:"submittedAt"`
+	ReimbursedAt *string `db:"reimbursed_at" json:"reimbursedAt,omitempty"`
+}
+
+// TagTotal sums the expenses of a report carrying a tag. An expense with several tags counts for each of them,
+// untagged ones are summed under an empty tag.
+type TagTotal struct {
+	Tag   string  `json:"tag"`
+	Count int     `json:"count"`
+	Total float64 `json:"total"`
+}
+
+type ReportDetails struct {
+	ExpenseReport
+	Expenses []transactions.Transaction `json:"expenses"`
+	Tags     []TagTotal                 `json:"tags"`
+	Payment  *transactions.Transaction  `json:"payment,omitempty"`
+}
@@ -0,0 +1,91 @@
+package reimbursements
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"checkout-go/pdf"
+)
+
+func formatAmount(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+func tagName(tag string) string {
+	if tag == "" {
+		return "untagged"
+	}
+	return tag
+}
+
+// WriteCSV writes a report with a row per expense and a last row with the total, then after an empty line the
+// totals per tag.
+func WriteCSV(w io.Writer, report *ReportDetails, loc *time.Location) error {
+	writer := csv.NewWriter(w)
+	rows := [][]string{{"date", "name", "seller", "tags", "note", "amount"}}
+	for _, expense := range report.Expenses {
+		rows = append(rows, []string{
+			expense.Date.Time().In(loc).Format(time.DateOnly),
+			expense.Name,
+			expense.Seller,
+			strings.Join(expense.Tags, ";"),
+			expense.Note,
+			formatAmount(-expense.Price),
+		})
+	}
+	rows = append(rows, []string{"", "total", "", "", "", formatAmount(report.Total)}, []string{})
+	rows = append(rows, []string{"tag", "count", "total"})
+	for _, total := range report.Tags {
+		rows = append(rows, []string{tagName(total.Tag), strconv.Itoa(total.Count), formatAmount(total.Total)})
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// WritePDF writes a report as a printable document, with the expenses in a table followed by the totals per tag.
+func WritePDF(w io.Writer, report *ReportDetails, loc *time.Location) error {
+	const dateWidth, amountWidth = 12, 12
+	nameWidth := (pdf.MonoColumns - dateWidth - amountWidth) * 3 / 5
+	tagsWidth := pdf.MonoColumns - dateWidth - amountWidth - nameWidth
+	row := func(date, name, tags, amount string) string {
+		return pdf.Fit(date, dateWidth) + pdf.Fit(name, nameWidth-1) + " " + pdf.Fit(tags, tagsWidth) + fmt.Sprintf("%*s", amountWidth, amount)
+	}
+
+	doc := pdf.New()
+	doc.Heading(report.Name)
+	submitted, err := time.Parse(time.RFC3339, report.SubmittedAt)
+	if err == nil {
+		doc.Text("Submitted on " + submitted.In(loc).Format(time.DateOnly))
+	}
+	if report.Status == StatusReimbursed && report.ReimbursedAt != nil {
+		if reimbursed, err := time.Parse(time.RFC3339, *report.ReimbursedAt); err == nil {
+			doc.Text("Reimbursed on " + reimbursed.In(loc).Format(time.DateOnly))
+		}
+	}
+	doc.Space()
+	doc.Bold("Expenses")
+	doc.Mono(row("Date", "Name", "Tags", "Amount"))
+	doc.Mono(strings.Repeat("-", pdf.MonoColumns))
+	for _, expense := range report.Expenses {
+		name := expense.Name
+		if expense.Seller != "" {
+			name += " (" + expense.Seller + ")"
+		}
+		doc.Mono(row(expense.Date.Time().In(loc).Format(time.DateOnly), name, strings.Join(expense.Tags, ", "), formatAmount(-expense.Price)))
+	}
+	doc.Mono(strings.Repeat("-", pdf.MonoColumns))
+	doc.Mono(row("", "Total", "", formatAmount(report.Total)))
+	doc.Space()
+	doc.Bold("Totals per tag")
+	for _, total := range report.Tags {
+		doc.Mono(row("", tagName(total.Tag), strconv.Itoa(total.Count)+" expenses", formatAmount(total.Total)))
+	}
+	_, err = doc.WriteTo(w)
+	return err
+}
@@ -0,0 +1,15 @@
+ALTER TABLE transactions ADD COLUMN reimbursable INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN reimbursement_status TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN expense_report_id INTEGER;
+
+CREATE TABLE expense_reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ledger_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('submitted', 'reimbursed')),
+    total REAL NOT NULL,
+    payment_id INTEGER,
+    submitted_at TEXT NOT NULL,
+    reimbursed_at TEXT
+);
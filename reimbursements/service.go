@@ -0,0 +1,289 @@
+package reimbursements
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	dto "checkout-go/reimbursements/dtos"
+	"checkout-go/transactions"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+var (
+	ErrNotFound            = errors.New("expense report not found")
+	ErrTransactionNotFound = errors.New("transaction not found")
+)
+
+type ReimbursementService struct {
+	DB                  *goqu.Database
+	TransactionsService *transactions.TransactionService
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func (service *ReimbursementService) getTransaction(ledgerID int64, id int) (*transactions.Transaction, error) {
+	list, err := service.TransactionsService.List(ledgerID, transactions.TransactionList{IDs: &[]int{id}})
+	if err != nil {
+		return nil, err
+	}
+	if len(*list) == 0 {
+		return nil, ErrTransactionNotFound
+	}
+	return &(*list)[0], nil
+}
+
+// SetReimbursable marks an expense as one that will be paid back, or takes the mark off. Expenses that are part of
+// an expense report keep it until the report is deleted.
+func (service *ReimbursementService) SetReimbursable(ledgerID int64, id int, reimbursable bool) (*transactions.Transaction, error) {
+	transaction, err := service.getTransaction(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Price >= 0 {
+		return nil, errors.New("only expenses can be reimbursable")
+	}
+	if transaction.ExpenseReportID != nil {
+		return nil, fmt.Errorf("expense is part of expense report %d", *transaction.ExpenseReportID)
+	}
+	status := transactions.ReimbursementStatus("")
+	if reimbursable {
+		status = transactions.ReimbursementPending
+	}
+	_, err = service.DB.Update("transactions").
+		Set(goqu.Record{"reimbursable": reimbursable, "reimbursement_status": status}).
+		Where(goqu.Ex{"id": id, "ledger_id": ledgerID}).
+		Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update transaction: %w", err)
+	}
+	transaction.Reimbursable = reimbursable
+	transaction.ReimbursementStatus = status
+	return transaction, nil
+}
+
+// Pending lists the reimbursable expenses that are not part of an expense report yet, newest first.
+func (service *ReimbursementService) Pending(ledgerID int64) ([]transactions.Transaction, error) {
+	status := transactions.ReimbursementPending
+	list, err := service.TransactionsService.List(ledgerID, transactions.TransactionList{Reimbursement: &status})
+	if err != nil {
+		return nil, err
+	}
+	return *list, nil
+}
+
+// CreateReport submits pending reimbursable expenses as one report, either the chosen ones or all of them.
+func (service *ReimbursementService) CreateReport(userID int64, ledgerID int64, data dto.CreateReportDTO) (*ExpenseReport, error) {
+	now := time.Now().Format(time.RFC3339)
+	report := ExpenseReport{
+		LedgerID:    ledgerID,
+		UserID:      userID,
+		Name:        strings.TrimSpace(data.Name),
+		Status:      StatusSubmitted,
+		SubmittedAt: now,
+	}
+	if report.Name == "" {
+		report.Name = "Expense report " + now[:len(time.DateOnly)]
+	}
+	err := service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		query := tx.From("transactions").Select("id", "price").Where(goqu.Ex{
+			"ledger_id":            ledgerID,
+			"reimbursement_status": transactions.ReimbursementPending,
+		})
+		if len(data.TransactionIDs) > 0 {
+			query = query.Where(goqu.Ex{"id": data.TransactionIDs})
+		}
+		var expenses []struct {
+			ID    int     `db:"id"`
+			Price float64 `db:"price"`
+		}
+		if err := query.ScanStructs(&expenses); err != nil {
+			return err
+		}
+		found := map[int]bool{}
+		ids := make([]int, 0, len(expenses))
+		for _, expense := range expenses {
+			found[expense.ID] = true
+			ids = append(ids, expense.ID)
+			report.Total -= expense.Price
+		}
+		for _, id := range data.TransactionIDs {
+			if !found[id] {
+				return fmt.Errorf("transaction %d is not a pending reimbursable expense", id)
+			}
+		}
+		if len(ids) == 0 {
+			return errors.New("there are no pending reimbursable expenses")
+		}
+		report.Total = roundCents(report.Total)
+		result, err := tx.Insert("expense_reports").Rows(report).Executor().Exec()
+		if err != nil {
+			return fmt.Errorf("err in inserting expense report: %w", err)
+		}
+		report.ID, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Update("transactions").
+			Set(goqu.Record{"reimbursement_status": transactions.ReimbursementSubmitted, "expense_report_id": report.ID}).
+			Where(goqu.Ex{"id": ids, "ledger_id": ledgerID}).
+			Executor().Exec()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (service *ReimbursementService) List(ledgerID int64) ([]ExpenseReport, error) {
+	reports := []ExpenseReport{}
+	err := service.DB.From("expense_reports").
+		Where(goqu.C("ledger_id").Eq(ledgerID)).
+		Order(goqu.C("submitted_at").Desc(), goqu.C("id").Desc()).
+		ScanStructs(&reports)
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (service *ReimbursementService) getReport(ledgerID int64, id int64) (*ExpenseReport, error) {
+	var report ExpenseReport
+	found, err := service.DB.From("expense_reports").Where(goqu.Ex{"id": id, "ledger_id": ledgerID}).ScanStruct(&report)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &report, nil
+}
+
+// Get returns a report with its expenses, their totals per tag and the payment that reimbursed it.
+func (service *ReimbursementService) Get(ledgerID int64, id int64) (*ReportDetails, error) {
+	report, err := service.getReport(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	zero := 0.0
+	expenses, err := service.TransactionsService.List(ledgerID, transactions.TransactionList{ExpenseReportID: &id, PriceLte: &zero})
+	if err != nil {
+		return nil, err
+	}
+	details := ReportDetails{ExpenseReport: *report, Expenses: *expenses, Tags: []TagTotal{}}
+	byTag := map[string]*TagTotal{}
+	for _, expense := range details.Expenses {
+		tags := []string(expense.Tags)
+		if len(tags) == 0 {
+			tags = []string{""}
+		}
+		for _, tag := range tags {
+			total, ok := byTag[tag]
+			if !ok {
+				total = &TagTotal{Tag: tag}
+				byTag[tag] = total
+			}
+			total.Count++
+			total.Total -= expense.Price
+		}
+	}
+	for _, total := range byTag {
+		total.Total = roundCents(total.Total)
+		details.Tags = append(details.Tags, *total)
+	}
+	sort.Slice(details.Tags, func(i, j int) bool {
+		if details.Tags[i].Total != details.Tags[j].Total {
+			return details.Tags[i].Total > details.Tags[j].Total
+		}
+		return details.Tags[i].Tag < details.Tags[j].Tag
+	})
+	if report.PaymentID != nil {
+		payment, err := service.getTransaction(ledgerID, int(*report.PaymentID))
+		if err != nil && !errors.Is(err, ErrTransactionNotFound) {
+			return nil, err
+		}
+		details.Payment = payment
+	}
+	return &details, nil
+}
+
+// Reimburse links a report to the incoming payment that paid it back. The report's expenses and the payment
+// then no longer count as spending and income in the statistics. The payment has to match the report total,
+// a partial refund would otherwise hide the part that was never paid back.
+func (service *ReimbursementService) Reimburse(ledgerID int64, id int64, paymentID int) (*ReportDetails, error) {
+	report, err := service.getReport(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if report.Status == StatusReimbursed {
+		return nil, errors.New("expense report is already reimbursed")
+	}
+	payment, err := service.getTransaction(ledgerID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Price <= 0 {
+		return nil, errors.New("a report is reimbursed by a payment, not an expense")
+	}
+	if payment.ExpenseReportID != nil {
+		return nil, fmt.Errorf("payment already reimburses expense report %d", *payment.ExpenseReportID)
+	}
+	if roundCents(payment.Price) != report.Total {
+		return nil, fmt.Errorf("payment of %.2f does not match the report total of %.2f", payment.Price, report.Total)
+	}
+	now := time.Now().Format(time.RFC3339)
+	err = service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		_, err := tx.Update("transactions").
+			Set(goqu.Record{"reimbursement_status": transactions.ReimbursementReimbursed}).
+			Where(goqu.Ex{"ledger_id": ledgerID, "expense_report_id": id}).
+			Executor().Exec()
+		if err != nil {
+			return fmt.Errorf("failed to update expenses: %w", err)
+		}
+		_, err = tx.Update("transactions").
+			Set(goqu.Record{"reimbursement_status": transactions.ReimbursementReimbursed, "expense_report_id": id}).
+			Where(goqu.Ex{"ledger_id": ledgerID, "id": paymentID}).
+			Executor().Exec()
+		if err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+		_, err = tx.Update("expense_reports").
+			Set(goqu.Record{"status": StatusReimbursed, "payment_id": paymentID, "reimbursed_at": now}).
+			Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).
+			Executor().Exec()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return service.Get(ledgerID, id)
+}
+
+// Delete withdraws a submitted report, its expenses are pending again. Reimbursed reports are kept.
+func (service *ReimbursementService) Delete(ledgerID int64, id int64) error {
+	report, err := service.getReport(ledgerID, id)
+	if err != nil {
+		return err
+	}
+	if report.Status == StatusReimbursed {
+		return errors.New("a reimbursed expense report cannot be deleted")
+	}
+	return service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		_, err := tx.Update("transactions").
+			Set(goqu.Record{"reimbursement_status": transactions.ReimbursementPending, "expense_report_id": nil}).
+			Where(goqu.Ex{"ledger_id": ledgerID, "expense_report_id": id}).
+			Executor().Exec()
+		if err != nil {
+			return fmt.Errorf("failed to update expenses: %w", err)
+		}
+		_, err = tx.Delete("expense_reports").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+		return err
+	})
+}
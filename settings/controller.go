@@ -0,0 +1,167 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"checkout-go/auth"
+	dto "checkout-go/settings/dtos"
+)
+
+type contextKey int
+
+const settingsKey contextKey = iota
+
+type SettingsContextReader interface {
+	GetSettingsFromRequest(req *http.Request) Settings
+}
+
+// LedgerLookup finds the ledger a request was resolved to, if any.
+type LedgerLookup interface {
+	LookupLedgerIDFromRequest(req *http.Request) (int64, bool)
+}
+
+type SettingsController struct {
+	SettingsService *SettingsService
+	AuthService     auth.UserContextReader
+	LedgerContext   LedgerLookup
+}
+
+// LoadSettings makes the logged in user's settings available to the handlers, with the overrides of the
+// request's ledger applied when it has one. It must run after auth.AuthController.RequireLoginMiddleware and,
+// on ledger routes, after ledgers.LedgersController.RequireRole.
+func (c *SettingsController) LoadSettings(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := c.AuthService.GetUserIDFromRequest(r)
+		var settings *Settings
+		var err error
+		if ledgerID, ok := c.LedgerContext.LookupLedgerIDFromRequest(r); ok {
+			settings, err = c.SettingsService.ForLedger(userID, ledgerID)
+		} else {
+			settings, err = c.SettingsService.Get(userID)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx := context.WithValue(r.Context(), settingsKey, *settings)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (c *SettingsController) GetSettingsFromRequest(req *http.Request) Settings {
+	settings, ok := req.Context().Value(settingsKey).(Settings)
+	if !ok {
+		panic("Settings were not loaded for this request")
+	}
+	return settings
+}
+
+var _ SettingsContextReader = (*SettingsController)(nil) // compile-time check
+
+func (c *SettingsController) GetSettings(w http.ResponseWriter, req *http.Request) {
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	settings, err := c.SettingsService.Get(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(settings)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *SettingsController) UpdateSettings(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var settingsBody dto.UpdateSettingsDTO
+	err = json.Unmarshal(body, &settingsBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	settings, err := c.SettingsService.Update(userID, settingsBody)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(settings)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ledgerID returns the ledger of a request on a ledger route, which RequireRole always resolves.
+func (c *SettingsController) ledgerID(req *http.Request) int64 {
+	ledgerID, ok := c.LedgerContext.LookupLedgerIDFromRequest(req)
+	if !ok {
+		panic("LedgerID was not resolved for this request")
+	}
+	return ledgerID
+}
+
+func (c *SettingsController) GetLedgerSettings(w http.ResponseWriter, req *http.Request) {
+	settings, err := c.SettingsService.GetLedger(c.ledgerID(req))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(settings)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *SettingsController) UpdateLedgerSettings(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var settingsBody dto.UpdateSettingsDTO
+	err = json.Unmarshal(body, &settingsBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	settings, err := c.SettingsService.UpdateLedger(c.ledgerID(req), settingsBody)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(settings)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *SettingsController) DeleteLedgerSettings(w http.ResponseWriter, req *http.Request) {
+	err := c.SettingsService.ClearLedger(c.ledgerID(req))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
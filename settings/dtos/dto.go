@@ -0,0 +1,7 @@
+package settings
+
+type UpdateSettingsDTO struct {
+	Timezone       *string `json:"timezone,omitempty"`
+	PeriodStartDay *int    `json:"periodStartDay,omitempty"`
+	PeriodRule     *string `json:"periodRule,omitempty"`
+}
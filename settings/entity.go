@@ -0,0 +1,50 @@
+package settings
+
+import "time"
+
+type Settings struct {
+	UserID         int64  `db:"user_id" json:"userId"`
+	Timezone       string `db:"timezone" json:"timezone"`
+	PeriodStartDay int    `db:"period_start_day" json:"periodStartDay"`
+	PeriodRule     string `db:"period_rule" json:"periodRule"`
+}
+
+func DefaultSettings(userID int64) Settings {
+	return Settings{
+		UserID:         userID,
+		Timezone:       "UTC",
+		PeriodStartDay: 1,
+		PeriodRule:     PeriodRuleDay,
+	}
+}
+
+// Location returns the user's time zone, which every day and month bucket is computed in.
+func (s Settings) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// LedgerSettings overrides the user's settings in one ledger. A nil field keeps the user's own value.
+type LedgerSettings struct {
+	LedgerID       int64   `db:"ledger_id" json:"ledgerId"`
+	Timezone       *string `db:"timezone" json:"timezone"`
+	PeriodStartDay *int    `db:"period_start_day" json:"periodStartDay"`
+	PeriodRule     *string `db:"period_rule" json:"periodRule"`
+}
+
+// Apply returns s with the ledger's overrides in place.
+func (l LedgerSettings) Apply(s Settings) Settings {
+	if l.Timezone != nil {
+		s.Timezone = *l.Timezone
+	}
+	if l.PeriodStartDay != nil {
+		s.PeriodStartDay = *l.PeriodStartDay
+	}
+	if l.PeriodRule != nil {
+		s.PeriodRule = *l.PeriodRule
+	}
+	return s
+}
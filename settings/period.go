@@ -0,0 +1,90 @@
+package settings
+
+import (
+	"time"
+)
+
+const (
+	// PeriodRuleDay starts every period on PeriodStartDay, clamped to the length of the month.
+	PeriodRuleDay = "day"
+	// PeriodRuleLastBusinessDay starts every period on the last weekday of the month.
+	PeriodRuleLastBusinessDay = "last_business_day"
+)
+
+// Period is a financial month. It is named after the calendar month most of its days fall in,
+// so with a start day of 25 the period from March 25th to April 24th is "2024-04".
+type Period struct {
+	Label string
+	Start time.Time
+	// End is exclusive
+	End time.Time
+}
+
+func ValidPeriodRule(rule string) bool {
+	return rule == PeriodRuleDay || rule == PeriodRuleLastBusinessDay
+}
+
+// anchor returns the day the period starting in the given calendar month begins on.
+func anchor(year int, month time.Month, startDay int, rule string, loc *time.Location) time.Time {
+	firstOfNextMonth := time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+	lastDay := firstOfNextMonth.AddDate(0, 0, -1)
+	if rule == PeriodRuleLastBusinessDay {
+		for lastDay.Weekday() == time.Saturday || lastDay.Weekday() == time.Sunday {
+			lastDay = lastDay.AddDate(0, 0, -1)
+		}
+		return lastDay
+	}
+	day := max(startDay, 1)
+	day = min(day, lastDay.Day())
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// labelShift is how many months after its start month a period is named after.
+func labelShift(startDay int, rule string) int {
+	if rule == PeriodRuleLastBusinessDay || startDay > 15 {
+		return 1
+	}
+	return 0
+}
+
+func newPeriod(start time.Time, startDay int, rule string) Period {
+	end := anchor(start.Year(), start.Month()+1, startDay, rule, start.Location())
+	labelMonth := time.Date(start.Year(), start.Month()+time.Month(labelShift(startDay, rule)), 1, 0, 0, 0, 0, start.Location())
+	return Period{
+		Label: labelMonth.Format("2006-01"),
+		Start: start,
+		End:   end,
+	}
+}
+
+// PeriodContaining returns the financial month t falls in, computed in loc.
+func PeriodContaining(t time.Time, startDay int, rule string, loc *time.Location) Period {
+	local := t.In(loc)
+	start := anchor(local.Year(), local.Month(), startDay, rule, loc)
+	if local.Before(start) {
+		start = anchor(local.Year(), local.Month()-1, startDay, rule, loc)
+	}
+	return newPeriod(start, startDay, rule)
+}
+
+// PeriodContaining returns the user's financial month t falls in.
+func (s Settings) PeriodContaining(t time.Time) Period {
+	return PeriodContaining(t, s.PeriodStartDay, s.PeriodRule, s.Location())
+}
+
+// Period returns the user's financial month named after the given calendar month.
+func (s Settings) Period(year int, month time.Month) Period {
+	startMonth := month - time.Month(labelShift(s.PeriodStartDay, s.PeriodRule))
+	start := anchor(year, startMonth, s.PeriodStartDay, s.PeriodRule, s.Location())
+	return newPeriod(start, s.PeriodStartDay, s.PeriodRule)
+}
+
+// CurrentPeriod returns the user's financial month that is running right now.
+func (s Settings) CurrentPeriod() Period {
+	return s.PeriodContaining(time.Now())
+}
+
+// LastDay returns the inclusive last day of the period, which is what users expect to see.
+func (p Period) LastDay() time.Time {
+	return p.End.AddDate(0, 0, -1)
+}
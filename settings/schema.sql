@@ -0,0 +1,17 @@
+CREATE TABLE user_settings (
+    user_id INTEGER PRIMARY KEY,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    period_start_day INTEGER NOT NULL DEFAULT 1,
+    period_rule TEXT NOT NULL DEFAULT 'day'
+);
+
+ALTER TABLE transactions ADD COLUMN utc_offset INTEGER NOT NULL DEFAULT 0;
+
+-- Overrides of the owner's settings for a ledger, so every book can keep its own time zone and financial month.
+-- A NULL column falls back to the settings of the user making the request.
+CREATE TABLE ledger_settings (
+    ledger_id INTEGER PRIMARY KEY,
+    timezone TEXT,
+    period_start_day INTEGER,
+    period_rule TEXT
+);
@@ -0,0 +1,130 @@
+package settings
+
+import (
+	"fmt"
+	"time"
+	// Android builds have no system zoneinfo database
+	_ "time/tzdata"
+
+	dto "checkout-go/settings/dtos"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+type SettingsService struct {
+	DB *goqu.Database
+}
+
+func (service *SettingsService) Get(userID int64) (*Settings, error) {
+	settings := DefaultSettings(userID)
+	_, err := service.DB.From("user_settings").Where(goqu.Ex{"user_id": userID}).ScanStruct(&settings)
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Validate checks the settings that are set, for a user or as the overrides of a ledger.
+func Validate(updateData dto.UpdateSettingsDTO) error {
+	if updateData.Timezone != nil {
+		if _, err := time.LoadLocation(*updateData.Timezone); err != nil || *updateData.Timezone == "Local" {
+			return fmt.Errorf("invalid time zone: %s", *updateData.Timezone)
+		}
+	}
+	if updateData.PeriodStartDay != nil {
+		if *updateData.PeriodStartDay < 1 || *updateData.PeriodStartDay > 31 {
+			return fmt.Errorf("period start day must be between 1 and 31")
+		}
+	}
+	if updateData.PeriodRule != nil {
+		if !ValidPeriodRule(*updateData.PeriodRule) {
+			return fmt.Errorf("invalid period rule: %s", *updateData.PeriodRule)
+		}
+	}
+	return nil
+}
+
+func (service *SettingsService) Update(userID int64, updateData dto.UpdateSettingsDTO) (*Settings, error) {
+	settings, err := service.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(updateData); err != nil {
+		return nil, err
+	}
+	if updateData.Timezone != nil {
+		settings.Timezone = *updateData.Timezone
+	}
+	if updateData.PeriodStartDay != nil {
+		settings.PeriodStartDay = *updateData.PeriodStartDay
+	}
+	if updateData.PeriodRule != nil {
+		settings.PeriodRule = *updateData.PeriodRule
+	}
+	_, err = service.DB.Insert("user_settings").
+		Rows(settings).
+		OnConflict(goqu.DoUpdate("user_id", settings)).
+		Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save settings: %w", err)
+	}
+	return settings, nil
+}
+
+// GetLedger returns the overrides of a ledger, with every field nil when it has none.
+func (service *SettingsService) GetLedger(ledgerID int64) (*LedgerSettings, error) {
+	settings := LedgerSettings{LedgerID: ledgerID}
+	_, err := service.DB.From("ledger_settings").Where(goqu.Ex{"ledger_id": ledgerID}).ScanStruct(&settings)
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// ForLedger returns the user's settings with the overrides of the ledger applied.
+func (service *SettingsService) ForLedger(userID int64, ledgerID int64) (*Settings, error) {
+	settings, err := service.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := service.GetLedger(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	applied := overrides.Apply(*settings)
+	return &applied, nil
+}
+
+// UpdateLedger sets the given fields as overrides of the ledger, the others keep their overrides.
+func (service *SettingsService) UpdateLedger(ledgerID int64, updateData dto.UpdateSettingsDTO) (*LedgerSettings, error) {
+	if err := Validate(updateData); err != nil {
+		return nil, err
+	}
+	settings, err := service.GetLedger(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	if updateData.Timezone != nil {
+		settings.Timezone = updateData.Timezone
+	}
+	if updateData.PeriodStartDay != nil {
+		settings.PeriodStartDay = updateData.PeriodStartDay
+	}
+	if updateData.PeriodRule != nil {
+		settings.PeriodRule = updateData.PeriodRule
+	}
+	_, err = service.DB.Insert("ledger_settings").
+		Rows(settings).
+		OnConflict(goqu.DoUpdate("ledger_id", settings)).
+		Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save ledger settings: %w", err)
+	}
+	return settings, nil
+}
+
+// ClearLedger removes the overrides of a ledger, it follows the settings of each user again.
+func (service *SettingsService) ClearLedger(ledgerID int64) error {
+	_, err := service.DB.Delete("ledger_settings").Where(goqu.Ex{"ledger_id": ledgerID}).Executor().Exec()
+	return err
+}
@@ -0,0 +1,128 @@
+package sqlitefuncs
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"checkout-go/customtypes"
+	"checkout-go/settings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DriverName is the sqlite3 driver with the app's custom SQL functions registered on every connection.
+const DriverName = "sqlite3_checkout"
+
+// LocalTimeLayout is what local_time returns, a format strftime and date comparisons understand.
+const LocalTimeLayout = "2006-01-02 15:04:05"
+
+func init() {
+	sql.Register(DriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("local_time", localTime, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("period_label", periodLabel, true); err != nil {
+				return err
+			}
+			return conn.RegisterAggregator("median", newMedian, true)
+		},
+	})
+}
+
+var locations sync.Map
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+func parseValue(name string, value any) (string, bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return v, true, nil
+	case []byte:
+		return string(v), true, nil
+	}
+	return "", false, fmt.Errorf("%s: unsupported type: %T", name, value)
+}
+
+// localTime implements local_time(date, zone): it converts a stored UTC date into the wall clock time of zone,
+// so that strftime buckets rows by the user's local day and month instead of UTC ones.
+// Bare calendar dates have no instant attached and are returned unchanged.
+func localTime(value any, zone string) (any, error) {
+	str, ok, err := parseValue("local_time", value)
+	if !ok {
+		return nil, err
+	}
+	t, dateOnly, err := customtypes.ParseStoredTime(str)
+	if err != nil {
+		return nil, err
+	}
+	if dateOnly {
+		return t.Format(LocalTimeLayout), nil
+	}
+	loc, err := loadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("local_time: %w", err)
+	}
+	return t.In(loc).Format(LocalTimeLayout), nil
+}
+
+// periodLabel implements period_label(date, zone, start_day, rule): it names the financial month
+// a stored date falls in, see settings.Period.
+func periodLabel(value any, zone string, startDay int, rule string) (any, error) {
+	str, ok, err := parseValue("period_label", value)
+	if !ok {
+		return nil, err
+	}
+	t, dateOnly, err := customtypes.ParseStoredTime(str)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := loadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("period_label: %w", err)
+	}
+	if dateOnly {
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+	return settings.PeriodContaining(t, startDay, rule, loc).Label, nil
+}
+
+// median implements the median(x) aggregate, which SQLite lacks.
+type median struct {
+	values []float64
+}
+
+func newMedian() *median {
+	return &median{}
+}
+
+func (m *median) Step(value float64) {
+	m.values = append(m.values, value)
+}
+
+func (m *median) Done() float64 {
+	if len(m.values) == 0 {
+		return 0
+	}
+	sort.Float64s(m.values)
+	middle := len(m.values) / 2
+	if len(m.values)%2 == 0 {
+		return (m.values[middle-1] + m.values[middle]) / 2
+	}
+	return m.values[middle]
+}
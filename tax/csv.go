@@ -0,0 +1,46 @@
+package tax
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+func formatAmount(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+func totalsRow(period string, category string, count string, t Totals) []string {
+	return []string{
+		period,
+		category,
+		count,
+		formatAmount(t.DeductibleGross),
+		formatAmount(t.DeductibleNet),
+		formatAmount(t.VATPaid),
+		formatAmount(t.VATCollected),
+		formatAmount(t.VATCollected - t.VATPaid),
+	}
+}
+
+// WriteCSV writes a report with a row per tax category, followed by the total of every quarter and of the whole
+// period, whose category column reads "total".
+func WriteCSV(w io.Writer, report *Report) error {
+	writer := csv.NewWriter(w)
+	rows := [][]string{{"period", "category", "count", "deductible_gross", "deductible_net", "vat_paid", "vat_collected", "vat_balance"}}
+	for _, total := range report.Categories {
+		category := total.Category
+		if category == "" {
+			category = "uncategorized"
+		}
+		rows = append(rows, totalsRow(report.Period, category, strconv.Itoa(total.Count), total.Totals))
+	}
+	for _, quarter := range report.Quarters {
+		rows = append(rows, totalsRow(quarter.Period, "total", "", quarter.Totals))
+	}
+	rows = append(rows, totalsRow(report.Period, "total", "", report.Totals))
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
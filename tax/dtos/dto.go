@@ -0,0 +1,19 @@
+package tax
+
+type CreateCategoryDTO struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+	// Deductible makes every expense with one of the tags deductible
+	Deductible bool `json:"deductible"`
+	// TaxRate is assumed for transactions with one of the tags that have no tax of their own
+	TaxRate *float64 `json:"taxRate"`
+}
+
+type UpdateCategoryDTO struct {
+	Name       *string   `json:"name"`
+	Tags       *[]string `json:"tags"`
+	Deductible *bool     `json:"deductible"`
+	TaxRate    *float64  `json:"taxRate"`
+	// ClearTaxRate removes the category's tax rate, a null taxRate leaves it unchanged
+	ClearTaxRate bool `json:"clearTaxRate"`
+}
@@ -0,0 +1,47 @@
+package tax
+
+import "checkout-go/customtypes"
+
+// Category groups the transactions carrying one of its tags for the tax report. A tag belongs to at most one
+// category of a ledger.
+type Category struct {
+	ID         int64                   `db:"id" goqu:"skipinsert" json:"id"`
+	LedgerID   int64                   `db:"ledger_id" json:"ledgerId"`
+	Name       string                  `db:"name" json:"name"`
+	Tags       customtypes.StringSlice `db:"tags" json:"tags"`
+	Deductible bool                    `db:"deductible" json:"deductible"`
+	TaxRate    *float64                `db:"tax_rate" json:"taxRate,omitempty"`
+	Date       string                  `db:"date" json:"date"`
+}
+
+// Totals are positive amounts: the deductible expenses with and without their tax, and the tax paid on expenses
+// and collected on income.
+type Totals struct {
+	DeductibleGross float64 `json:"deductibleGross"`
+	DeductibleNet   float64 `json:"deductibleNet"`
+	VATPaid         float64 `json:"vatPaid"`
+	VATCollected    float64 `json:"vatCollected"`
+}
+
+// Summary sums a period. VATBalance is what is owed to the tax office, a refund when negative.
+type Summary struct {
+	Period string `json:"period"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Totals
+	VATBalance float64 `json:"vatBalance"`
+}
+
+type CategoryTotal struct {
+	// Category is empty for transactions without a tax category
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+	Totals
+}
+
+type Report struct {
+	Summary
+	// Quarters breaks a yearly report down, it is empty for a quarterly one
+	Quarters   []Summary       `json:"quarters"`
+	Categories []CategoryTotal `json:"categories"`
+}
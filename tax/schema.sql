@@ -0,0 +1,13 @@
+ALTER TABLE transactions ADD COLUMN deductible INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN tax_rate REAL;
+ALTER TABLE transactions ADD COLUMN tax_amount REAL NOT NULL DEFAULT 0;
+
+CREATE TABLE tax_categories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ledger_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    tags JSONB NOT NULL,
+    deductible INTEGER NOT NULL DEFAULT 0,
+    tax_rate REAL,
+    date TEXT NOT NULL
+);
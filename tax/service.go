@@ -0,0 +1,294 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"checkout-go/customtypes"
+	dto "checkout-go/tax/dtos"
+	"checkout-go/transactions"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+var ErrNotFound = errors.New("tax category not found")
+
+type TaxService struct {
+	DB *goqu.Database
+}
+
+func (service *TaxService) ListCategories(ledgerID int64) ([]Category, error) {
+	categories := []Category{}
+	err := service.DB.From("tax_categories").
+		Where(goqu.C("ledger_id").Eq(ledgerID)).
+		Order(goqu.C("id").Asc()).
+		ScanStructs(&categories)
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (service *TaxService) getCategory(ledgerID int64, id int64) (*Category, error) {
+	var category Category
+	found, err := service.DB.From("tax_categories").Where(goqu.Ex{"id": id, "ledger_id": ledgerID}).ScanStruct(&category)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &category, nil
+}
+
+// validate checks a category before it is stored, including that none of its tags belongs to another one.
+func (service *TaxService) validate(category *Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return errors.New("category name cannot be empty")
+	}
+	if category.TaxRate != nil && (*category.TaxRate < 0 || *category.TaxRate > 100) {
+		return errors.New("tax rate must be between 0 and 100")
+	}
+	tags := customtypes.StringSlice{}
+	seen := map[string]bool{}
+	for _, tag := range category.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return errors.New("category needs at least one tag")
+	}
+	category.Tags = tags
+	others, err := service.ListCategories(category.LedgerID)
+	if err != nil {
+		return err
+	}
+	for _, other := range others {
+		if other.ID == category.ID {
+			continue
+		}
+		for _, tag := range other.Tags {
+			if seen[tag] {
+				return fmt.Errorf("tag %q already belongs to category %q", tag, other.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (service *TaxService) CreateCategory(ledgerID int64, data dto.CreateCategoryDTO) (*Category, error) {
+	category := Category{
+		LedgerID:   ledgerID,
+		Name:       data.Name,
+		Tags:       data.Tags,
+		Deductible: data.Deductible,
+		TaxRate:    data.TaxRate,
+		Date:       time.Now().Format(time.RFC3339),
+	}
+	if err := service.validate(&category); err != nil {
+		return nil, err
+	}
+	result, err := service.DB.Insert("tax_categories").Rows(category).Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("err in inserting tax category: %w", err)
+	}
+	category.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (service *TaxService) UpdateCategory(ledgerID int64, id int64, data dto.UpdateCategoryDTO) (*Category, error) {
+	category, err := service.getCategory(ledgerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if data.Name != nil {
+		category.Name = *data.Name
+	}
+	if data.Tags != nil {
+		category.Tags = *data.Tags
+	}
+	if data.Deductible != nil {
+		category.Deductible = *data.Deductible
+	}
+	if data.TaxRate != nil {
+		category.TaxRate = data.TaxRate
+	}
+	if data.ClearTaxRate {
+		category.TaxRate = nil
+	}
+	if err := service.validate(category); err != nil {
+		return nil, err
+	}
+	_, err = service.DB.Update("tax_categories").
+		Set(goqu.Record{
+			"name":       category.Name,
+			"tags":       category.Tags,
+			"deductible": category.Deductible,
+			"tax_rate":   category.TaxRate,
+		}).
+		Where(goqu.Ex{"id": id, "ledger_id": ledgerID}).
+		Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tax category: %w", err)
+	}
+	return category, nil
+}
+
+func (service *TaxService) DeleteCategory(ledgerID int64, id int64) error {
+	result, err := service.DB.Delete("tax_categories").Where(goqu.Ex{"id": id, "ledger_id": ledgerID}).Executor().Exec()
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// period returns the first and last day of a year, or of one of its quarters.
+func period(year int, quarter int) (string, string, string) {
+	if quarter == 0 {
+		return fmt.Sprintf("%04d", year), fmt.Sprintf("%04d-01-01", year), fmt.Sprintf("%04d-12-31", year)
+	}
+	from := time.Date(year, time.Month(quarter*3-2), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 3, -1)
+	return fmt.Sprintf("%04d-Q%d", year, quarter), from.Format(time.DateOnly), to.Format(time.DateOnly)
+}
+
+func newSummary(year int, quarter int) Summary {
+	label, from, to := period(year, quarter)
+	return Summary{Period: label, From: from, To: to}
+}
+
+// add books a transaction into the totals.
+func (t *Totals) add(price float64, tax float64, deductible bool) {
+	if price >= 0 {
+		t.VATCollected += tax
+		return
+	}
+	t.VATPaid += tax
+	if deductible {
+		t.DeductibleGross += -price
+		t.DeductibleNet += -price - tax
+	}
+}
+
+func (t *Totals) round() {
+	t.DeductibleGross = roundCents(t.DeductibleGross)
+	t.DeductibleNet = roundCents(t.DeductibleNet)
+	t.VATPaid = roundCents(t.VATPaid)
+	t.VATCollected = roundCents(t.VATCollected)
+}
+
+func (s *Summary) round() {
+	s.Totals.round()
+	s.VATBalance = roundCents(s.VATCollected - s.VATPaid)
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// Report sums the deductible expenses and the VAT paid and collected of a year, or of a quarter when quarter is
+// 1 to 4. Quarters are calendar quarters in the user's time zone. A transaction's own tax wins over the rate of
+// its category, and an expense is deductible if it is marked so or its category is.
+func (service *TaxService) Report(ledgerID int64, year int, quarter int, loc *time.Location) (*Report, error) {
+	if year < 1 || year > 9999 {
+		return nil, errors.New("invalid year")
+	}
+	if quarter < 0 || quarter > 4 {
+		return nil, errors.New("quarter must be between 1 and 4")
+	}
+	categories, err := service.ListCategories(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	byTag := map[string]*Category{}
+	for i := range categories {
+		for _, tag := range categories[i].Tags {
+			byTag[tag] = &categories[i]
+		}
+	}
+
+	report := Report{Summary: newSummary(year, quarter), Quarters: []Summary{}, Categories: []CategoryTotal{}}
+	if quarter == 0 {
+		for q := 1; q <= 4; q++ {
+			report.Quarters = append(report.Quarters, newSummary(year, q))
+		}
+	}
+	localDate := goqu.L("strftime('%Y-%m-%d', local_time(date, ?))", loc.String())
+	var list []transactions.Transaction
+	err = service.DB.From("transactions").
+		Where(goqu.C("ledger_id").Eq(ledgerID), localDate.Between(goqu.Range(report.From, report.To))).
+		Order(goqu.C("date").Asc()).
+		ScanStructs(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := map[string]*CategoryTotal{}
+	for _, t := range list {
+		var category *Category
+		for _, tag := range t.Tags {
+			if category = byTag[tag]; category != nil {
+				break
+			}
+		}
+		tax := t.TaxAmount
+		if t.TaxRate == nil && t.TaxAmount == 0 && category != nil && category.TaxRate != nil {
+			tax = transactions.IncludedTax(t.Price, *category.TaxRate)
+		}
+		deductible := t.Price < 0 && (t.Deductible || category != nil && category.Deductible)
+		if !deductible && tax == 0 {
+			continue
+		}
+		report.add(t.Price, tax, deductible)
+		if quarter == 0 {
+			month := t.Date.Time().In(loc).Month()
+			report.Quarters[(month-1)/3].add(t.Price, tax, deductible)
+		}
+		name := ""
+		if category != nil {
+			name = category.Name
+		}
+		total, ok := totals[name]
+		if !ok {
+			total = &CategoryTotal{Category: name}
+			totals[name] = total
+		}
+		total.Count++
+		total.add(t.Price, tax, deductible)
+	}
+
+	report.round()
+	for i := range report.Quarters {
+		report.Quarters[i].round()
+	}
+	for _, total := range totals {
+		total.round()
+		report.Categories = append(report.Categories, *total)
+	}
+	// By name, with the uncategorized transactions last
+	sort.Slice(report.Categories, func(i, j int) bool {
+		a, b := report.Categories[i].Category, report.Categories[j].Category
+		if a == "" || b == "" {
+			return b == "" && a != ""
+		}
+		return a < b
+	})
+	return &report, nil
+}
@@ -0,0 +1,382 @@
+package transactions
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"checkout-go/customtypes"
+	"checkout-go/settings"
+
+	goqu "github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+type Dimension string
+
+const (
+	DimensionDay     Dimension = "day"
+	DimensionWeek    Dimension = "week"
+	DimensionMonth   Dimension = "month"
+	DimensionQuarter Dimension = "quarter"
+	DimensionYear    Dimension = "year"
+	DimensionWeekday Dimension = "weekday"
+	DimensionTag     Dimension = "tag"
+	DimensionSeller  Dimension = "seller"
+	DimensionSign    Dimension = "sign"
+)
+
+// DimensionFieldPrefix groups by a user defined field, e.g. "field:person".
+const DimensionFieldPrefix = "field:"
+
+type Metric string
+
+const (
+	MetricCount  Metric = "count"
+	MetricSum    Metric = "sum"
+	MetricAvg    Metric = "avg"
+	MetricMin    Metric = "min"
+	MetricMax    Metric = "max"
+	MetricMedian Metric = "median"
+)
+
+const (
+	SignExpense = "expense"
+	SignIncome  = "income"
+)
+
+// maxAggregationGroups caps how many rows gap filling may produce.
+const maxAggregationGroups = 10000
+
+type AggregationQuery struct {
+	Filter  TransactionList `json:"filter"`
+	GroupBy []Dimension     `json:"groupBy"`
+	Metrics []Metric        `json:"metrics"`
+}
+
+type AggregationRow struct {
+	Group   map[Dimension]string `json:"group"`
+	Metrics map[Metric]float64   `json:"metrics"`
+}
+
+func (d Dimension) isTime() bool {
+	switch d {
+	case DimensionDay, DimensionWeek, DimensionMonth, DimensionQuarter, DimensionYear:
+		return true
+	}
+	return false
+}
+
+// expression is the SQL computing the dimension's group key. Months, quarters and years follow the
+// user's financial months, days and weeks their time zone.
+func (d Dimension) expression(userSettings settings.Settings) (exp.Expression, error) {
+	date := localDate(userSettings.Location())
+	period := periodLabel(userSettings)
+	if name, ok := strings.CutPrefix(string(d), DimensionFieldPrefix); ok && name != "" {
+		return goqu.L("COALESCE(?, '')", fieldValue(name)), nil
+	}
+	switch d {
+	case DimensionDay:
+		return goqu.L("strftime('%Y-%m-%d', ?)", date), nil
+	case DimensionWeek:
+		return goqu.L("strftime('%G-W%V', ?)", date), nil
+	case DimensionMonth:
+		return period, nil
+	case DimensionQuarter:
+		return goqu.L("substr(?, 1, 4) || '-Q' || ((CAST(substr(?, 6, 2) AS INTEGER) + 2) / 3)", period, period), nil
+	case DimensionYear:
+		return goqu.L("substr(?, 1, 4)", period), nil
+	case DimensionWeekday:
+		return goqu.L("strftime('%w', ?)", date), nil
+	case DimensionTag:
+		return goqu.L("COALESCE(tag.value, '')"), nil
+	case DimensionSeller:
+		return goqu.L("COALESCE(seller, '')"), nil
+	case DimensionSign:
+		return goqu.L("CASE WHEN price <= 0 THEN ? ELSE ? END", SignExpense, SignIncome), nil
+	}
+	return nil, fmt.Errorf("unknown dimension %q", d)
+}
+
+// key computes the same group key as expression for a local time, used to enumerate empty groups.
+func (d Dimension) key(t time.Time, userSettings settings.Settings) string {
+	switch d {
+	case DimensionDay:
+		return t.Format(time.DateOnly)
+	case DimensionWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case DimensionMonth:
+		return userSettings.PeriodContaining(t).Label
+	case DimensionQuarter:
+		label := userSettings.PeriodContaining(t).Label
+		month, _ := strconv.Atoi(label[5:7])
+		return fmt.Sprintf("%s-Q%d", label[:4], (month+2)/3)
+	case DimensionYear:
+		return userSettings.PeriodContaining(t).Label[:4]
+	}
+	return ""
+}
+
+func (m Metric) expression() (exp.Expression, error) {
+	switch m {
+	case MetricCount:
+		return goqu.COUNT("*"), nil
+	case MetricSum:
+		return goqu.SUM("price"), nil
+	case MetricAvg:
+		return goqu.AVG("price"), nil
+	case MetricMin:
+		return goqu.MIN("price"), nil
+	case MetricMax:
+		return goqu.MAX("price"), nil
+	case MetricMedian:
+		return goqu.L("median(price)"), nil
+	}
+	return nil, fmt.Errorf("unknown metric %q", m)
+}
+
+// Aggregate groups the filtered transactions by the requested dimensions and computes the requested metrics
+// per group. Empty groups are filled with zero rows: every day, week, month, quarter or year between the
+// first and last date, every weekday and both signs, combined with the tags, sellers and custom field values
+// that occur.
+func (service *TransactionService) Aggregate(ledgerID int64, query AggregationQuery, userSettings settings.Settings) ([]AggregationRow, error) {
+	if len(query.Metrics) == 0 {
+		query.Metrics = []Metric{MetricCount, MetricSum}
+	}
+	var columns []interface{}
+	var groupBy []interface{}
+	joinTags := false
+	for i, dimension := range query.GroupBy {
+		expression, err := dimension.expression(userSettings)
+		if err != nil {
+			return nil, err
+		}
+		alias := "d" + strconv.Itoa(i)
+		columns = append(columns, goqu.L("?", expression).As(alias))
+		groupBy = append(groupBy, goqu.I(alias))
+		if dimension == DimensionTag {
+			joinTags = true
+		}
+	}
+	for i, metric := range query.Metrics {
+		expression, err := metric.expression()
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, goqu.L("?", expression).As("m"+strconv.Itoa(i)))
+	}
+
+	selectStatement := service.DB.From("transactions").Where(goqu.Ex{"ledger_id": ledgerID}, personal())
+	if joinTags {
+		selectStatement = selectStatement.LeftJoin(goqu.L("json_each(tags)").As("tag"), goqu.On(goqu.L("1 = 1")))
+	}
+	selectStatement = applyFilters(selectStatement, query.Filter).Select(columns...)
+	if len(groupBy) > 0 {
+		selectStatement = selectStatement.GroupBy(groupBy...)
+	}
+	sqlQuery, args, err := selectStatement.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := service.DB.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]AggregationRow)
+	observed := make([]map[string]bool, len(query.GroupBy))
+	for i := range observed {
+		observed[i] = make(map[string]bool)
+	}
+	for rows.Next() {
+		keys := make([]sql.NullString, len(query.GroupBy))
+		values := make([]sql.NullFloat64, len(query.Metrics))
+		dest := make([]interface{}, 0, len(keys)+len(values))
+		for i := range keys {
+			dest = append(dest, &keys[i])
+		}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		group := make([]string, len(keys))
+		for i, key := range keys {
+			group[i] = key.String
+			observed[i][key.String] = true
+		}
+		row := newAggregationRow(query, group)
+		for i, value := range values {
+			row.Metrics[query.Metrics[i]] = value.Float64
+		}
+		found[groupKey(group)] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	domains, err := service.aggregationDomains(ledgerID, query, userSettings, observed)
+	if err != nil {
+		return nil, err
+	}
+	total := 1
+	for _, domain := range domains {
+		total *= len(domain)
+		if total > maxAggregationGroups {
+			return nil, fmt.Errorf("aggregation would return more than %d groups, narrow down the filter", maxAggregationGroups)
+		}
+	}
+
+	result := make([]AggregationRow, 0, total)
+	group := make([]string, len(domains))
+	var fill func(dimension int)
+	fill = func(dimension int) {
+		if dimension == len(domains) {
+			row, ok := found[groupKey(group)]
+			if !ok {
+				row = newAggregationRow(query, group)
+			}
+			result = append(result, row)
+			return
+		}
+		for _, key := range domains[dimension] {
+			group[dimension] = key
+			fill(dimension + 1)
+		}
+	}
+	fill(0)
+	for _, row := range result {
+		if weekday, ok := row.Group[DimensionWeekday]; ok {
+			day, _ := strconv.Atoi(weekday)
+			row.Group[DimensionWeekday] = time.Weekday(day).String()
+		}
+	}
+	return result, nil
+}
+
+// aggregationDomains lists, per dimension, the keys that make up the result in their display order.
+func (service *TransactionService) aggregationDomains(ledgerID int64, query AggregationQuery, userSettings settings.Settings, observed []map[string]bool) ([][]string, error) {
+	domains := make([][]string, len(query.GroupBy))
+	var timeKeys func(Dimension) []string
+	for i, dimension := range query.GroupBy {
+		switch {
+		case dimension.isTime():
+			if timeKeys == nil {
+				days, err := service.aggregationDays(ledgerID, query.Filter, userSettings)
+				if err != nil {
+					return nil, err
+				}
+				timeKeys = func(dimension Dimension) []string {
+					var keys []string
+					seen := make(map[string]bool)
+					for _, day := range days {
+						key := dimension.key(day, userSettings)
+						if !seen[key] {
+							seen[key] = true
+							keys = append(keys, key)
+						}
+					}
+					return keys
+				}
+			}
+			domains[i] = timeKeys(dimension)
+		case dimension == DimensionWeekday:
+			for day := time.Monday; day <= time.Saturday; day++ {
+				domains[i] = append(domains[i], strconv.Itoa(int(day)))
+			}
+			domains[i] = append(domains[i], strconv.Itoa(int(time.Sunday)))
+		case dimension == DimensionSign:
+			domains[i] = []string{SignExpense, SignIncome}
+		default:
+			for key := range observed[i] {
+				domains[i] = append(domains[i], key)
+			}
+			sort.Strings(domains[i])
+		}
+	}
+	return domains, nil
+}
+
+// aggregationDays returns every local day of the filtered date range. Open ends are taken from the
+// earliest and latest matching transaction.
+func (service *TransactionService) aggregationDays(ledgerID int64, filters TransactionList, userSettings settings.Settings) ([]time.Time, error) {
+	loc := userSettings.Location()
+	var bounds struct {
+		First sql.NullString `db:"first"`
+		Last  sql.NullString `db:"last"`
+	}
+	_, err := applyFilters(service.DB.From("transactions").Where(goqu.Ex{"ledger_id": ledgerID}, personal()), filters).
+		Select(goqu.MIN("date").As("first"), goqu.MAX("date").As("last")).
+		ScanStruct(&bounds)
+	if err != nil {
+		return nil, err
+	}
+	var first, last time.Time
+	if filters.DateGte != nil {
+		first = filters.DateGte.In(loc)
+	} else if bounds.First.Valid {
+		first, err = parseLocalDate(bounds.First.String, loc)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, nil
+	}
+	if filters.DateLte != nil {
+		last = filters.DateLte.In(loc)
+	} else if bounds.Last.Valid {
+		last, err = parseLocalDate(bounds.Last.String, loc)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, nil
+	}
+	var days []time.Time
+	for day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc); !day.After(last); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+		if len(days) > maxAggregationGroups {
+			return nil, fmt.Errorf("aggregation would return more than %d groups, narrow down the filter", maxAggregationGroups)
+		}
+	}
+	return days, nil
+}
+
+func parseLocalDate(value string, loc *time.Location) (time.Time, error) {
+	t, dateOnly, err := customtypes.ParseStoredTime(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if dateOnly {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc), nil
+	}
+	return t.In(loc), nil
+}
+
+func newAggregationRow(query AggregationQuery, group []string) AggregationRow {
+	row := AggregationRow{
+		Group:   make(map[Dimension]string, len(group)),
+		Metrics: make(map[Metric]float64, len(query.Metrics)),
+	}
+	for i, key := range group {
+		row.Group[query.GroupBy[i]] = key
+	}
+	for _, metric := range query.Metrics {
+		row.Metrics[metric] = 0
+	}
+	return row
+}
+
+func groupKey(group []string) string {
+	key := ""
+	for _, part := range group {
+		key += strconv.Quote(part)
+	}
+	return key
+}
@@ -0,0 +1,108 @@
+package transactions
+
+import (
+	"sort"
+	"time"
+
+	"checkout-go/settings"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+// BookTotals are the totals of one ledger in a combined view.
+type BookTotals struct {
+	LedgerID int64   `json:"ledgerId"`
+	Name     string  `json:"name"`
+	Balance  float64 `json:"balance"`
+	Spent    float64 `json:"spent"`
+	Received float64 `json:"received"`
+}
+
+type TagSpent struct {
+	Tag   string  `json:"tag"`
+	Spent float64 `json:"spent"`
+}
+
+// CombinedView adds up several ledgers, like a personal and a business book, for one financial month. Every
+// ledger keeps its own data, so the view is only computed on request and never stored.
+type CombinedView struct {
+	Period      string       `json:"period"`
+	PeriodStart string       `json:"periodStart"`
+	PeriodEnd   string       `json:"periodEnd"`
+	Balance     float64      `json:"balance"`
+	Spent       float64      `json:"spent"`
+	Received    float64      `json:"received"`
+	Books       []BookTotals `json:"books"`
+	Tags        []TagSpent   `json:"tags"`
+}
+
+// receivedBetween is SpentBetween for incoming payments, without the split by tag.
+func (service *TransactionService) receivedBetween(ledgerID int64, start time.Time, end time.Time, userSettings settings.Settings) (float64, error) {
+	day := goqu.L("strftime('%Y-%m-%d', ?)", localDate(userSettings.Location()))
+	var total float64
+	_, err := service.DB.From("transactions").
+		Select(goqu.L("COALESCE(SUM(price), 0)")).
+		Where(
+			goqu.C("ledger_id").Eq(ledgerID),
+			goqu.C("price").Gt(0),
+			personal(),
+			day.Gte(start.In(userSettings.Location()).Format(time.DateOnly)),
+			day.Lt(end.In(userSettings.Location()).Format(time.DateOnly)),
+		).
+		ScanVal(&total)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Combined sums the balance, spending and income of books in period. The ledgers may have settings of their
+// own, the period and its days are taken from userSettings so that all books cover the same days.
+func (service *TransactionService) Combined(books []BookTotals, period settings.Period, userSettings settings.Settings) (*CombinedView, error) {
+	view := CombinedView{
+		Period:      period.Label,
+		PeriodStart: period.Start.Format(time.DateOnly),
+		PeriodEnd:   period.LastDay().Format(time.DateOnly),
+		Books:       []BookTotals{},
+		Tags:        []TagSpent{},
+	}
+	byTag := map[string]float64{}
+	for _, book := range books {
+		var err error
+		book.Balance, err = service.GetBalance(book.LedgerID)
+		if err != nil {
+			return nil, err
+		}
+		spent, tags, err := service.SpentBetween(book.LedgerID, period.Start, period.End, userSettings)
+		if err != nil {
+			return nil, err
+		}
+		book.Spent = roundCents(spent)
+		for tag, amount := range tags {
+			byTag[tag] += amount
+		}
+		received, err := service.receivedBetween(book.LedgerID, period.Start, period.End, userSettings)
+		if err != nil {
+			return nil, err
+		}
+		book.Received = roundCents(received)
+		book.Balance = roundCents(book.Balance)
+		view.Balance += book.Balance
+		view.Spent += book.Spent
+		view.Received += book.Received
+		view.Books = append(view.Books, book)
+	}
+	for tag, spent := range byTag {
+		view.Tags = append(view.Tags, TagSpent{Tag: tag, Spent: roundCents(spent)})
+	}
+	sort.Slice(view.Tags, func(i, j int) bool {
+		if view.Tags[i].Spent != view.Tags[j].Spent {
+			return view.Tags[i].Spent > view.Tags[j].Spent
+		}
+		return view.Tags[i].Tag < view.Tags[j].Tag
+	})
+	view.Balance = roundCents(view.Balance)
+	view.Spent = roundCents(view.Spent)
+	view.Received = roundCents(view.Received)
+	return &view, nil
+}
@@ -0,0 +1,202 @@
+package transactions
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"checkout-go/settings"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+type ComparisonMode string
+
+const (
+	// ComparePrevious compares a financial month with the one before it.
+	ComparePrevious ComparisonMode = "previous"
+	// CompareLastYear compares a financial month with the same month a year earlier.
+	CompareLastYear ComparisonMode = "last_year"
+	// CompareToDate compares the elapsed days of a financial month with the same number of days of the month before.
+	CompareToDate ComparisonMode = "to_date"
+)
+
+type ComparedPeriod struct {
+	Label string  `json:"label"`
+	Start string  `json:"start"`
+	End   string  `json:"end"`
+	Spent float64 `json:"spent"`
+}
+
+type TagComparison struct {
+	Tag             string   `json:"tag"`
+	Current         float64  `json:"current"`
+	Previous        float64  `json:"previous"`
+	Delta           float64  `json:"delta"`
+	DeltaPercentage *float64 `json:"deltaPercentage"`
+}
+
+type PeriodComparison struct {
+	Mode            ComparisonMode  `json:"mode"`
+	Current         ComparedPeriod  `json:"current"`
+	Previous        ComparedPeriod  `json:"previous"`
+	Delta           float64         `json:"delta"`
+	DeltaPercentage *float64        `json:"deltaPercentage"`
+	Tags            []TagComparison `json:"tags"`
+}
+
+// dateRange is a span of local days, End being exclusive.
+type dateRange struct {
+	Label string
+	Start time.Time
+	End   time.Time
+}
+
+// comparisonRanges picks the two spans of days a comparison looks at.
+func comparisonRanges(mode ComparisonMode, period settings.Period, userSettings settings.Settings, now time.Time) (dateRange, dateRange, error) {
+	labelMonth, err := time.Parse("2006-01", period.Label)
+	if err != nil {
+		return dateRange{}, dateRange{}, err
+	}
+	current := dateRange{Label: period.Label, Start: period.Start, End: period.End}
+	switch mode {
+	case ComparePrevious:
+		previous := userSettings.Period(labelMonth.Year(), labelMonth.Month()-1)
+		return current, dateRange{Label: previous.Label, Start: previous.Start, End: previous.End}, nil
+	case CompareLastYear:
+		previous := userSettings.Period(labelMonth.Year()-1, labelMonth.Month())
+		return current, dateRange{Label: previous.Label, Start: previous.Start, End: previous.End}, nil
+	case CompareToDate:
+		today := now.In(userSettings.Location())
+		end := time.Date(today.Year(), today.Month(), today.Day()+1, 0, 0, 0, 0, today.Location())
+		if end.After(period.End) {
+			end = period.End
+		}
+		if end.Before(period.Start) {
+			end = period.Start
+		}
+		days := int(end.Sub(period.Start).Hours()/24 + 0.5)
+		previous := userSettings.Period(labelMonth.Year(), labelMonth.Month()-1)
+		previousEnd := previous.Start.AddDate(0, 0, days)
+		if previousEnd.After(previous.End) {
+			previousEnd = previous.End
+		}
+		current.End = end
+		return current, dateRange{Label: previous.Label, Start: previous.Start, End: previousEnd}, nil
+	}
+	return dateRange{}, dateRange{}, fmt.Errorf("unknown comparison mode %q", mode)
+}
+
+// ComparePeriods compares the spending of the user's financial month named after year and month with an
+// earlier span picked by mode, in total and per tag. Untagged expenses are reported under an empty tag.
+func (service *TransactionService) ComparePeriods(ledgerID int64, mode ComparisonMode, year int, month int, userSettings settings.Settings) (*PeriodComparison, error) {
+	if month < 1 || month > 12 {
+		return nil, fmt.Errorf("invalid month")
+	}
+	current, previous, err := comparisonRanges(mode, userSettings.Period(year, time.Month(month)), userSettings, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	currentSpent, currentTags, err := service.SpentBetween(ledgerID, current.Start, current.End, userSettings)
+	if err != nil {
+		return nil, err
+	}
+	previousSpent, previousTags, err := service.SpentBetween(ledgerID, previous.Start, previous.End, userSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := PeriodComparison{
+		Mode:            mode,
+		Current:         comparedPeriod(current, currentSpent),
+		Previous:        comparedPeriod(previous, previousSpent),
+		Delta:           currentSpent - previousSpent,
+		DeltaPercentage: deltaPercentage(currentSpent, previousSpent),
+		Tags:            []TagComparison{},
+	}
+	tags := make(map[string]bool)
+	for tag := range currentTags {
+		tags[tag] = true
+	}
+	for tag := range previousTags {
+		tags[tag] = true
+	}
+	for tag := range tags {
+		comparison.Tags = append(comparison.Tags, TagComparison{
+			Tag:             tag,
+			Current:         currentTags[tag],
+			Previous:        previousTags[tag],
+			Delta:           currentTags[tag] - previousTags[tag],
+			DeltaPercentage: deltaPercentage(currentTags[tag], previousTags[tag]),
+		})
+	}
+	sort.Slice(comparison.Tags, func(i, j int) bool {
+		if comparison.Tags[i].Delta != comparison.Tags[j].Delta {
+			return comparison.Tags[i].Delta > comparison.Tags[j].Delta
+		}
+		return comparison.Tags[i].Tag < comparison.Tags[j].Tag
+	})
+	return &comparison, nil
+}
+
+type tagSpending struct {
+	Tag   string  `db:"tag"`
+	Spent float64 `db:"spent"`
+}
+
+// SpentBetween returns the amount spent on the local days from start up to but excluding end, in total and
+// per tag, as positive numbers. Untagged expenses are reported under an empty tag.
+func (service *TransactionService) SpentBetween(ledgerID int64, start time.Time, end time.Time, userSettings settings.Settings) (float64, map[string]float64, error) {
+	day := goqu.L("strftime('%Y-%m-%d', ?)", localDate(userSettings.Location()))
+	inRange := []goqu.Expression{
+		goqu.C("ledger_id").Eq(ledgerID),
+		goqu.C("price").Lt(0),
+		personal(),
+		day.Gte(start.In(userSettings.Location()).Format(time.DateOnly)),
+		day.Lt(end.In(userSettings.Location()).Format(time.DateOnly)),
+	}
+	var total float64
+	_, err := service.DB.From("transactions").
+		Select(goqu.L("COALESCE(-SUM(price), 0)")).
+		Where(inRange...).
+		ScanVal(&total)
+	if err != nil {
+		return 0, nil, err
+	}
+	var spending []tagSpending
+	err = service.DB.From("transactions").
+		LeftJoin(goqu.L("json_each(tags)").As("tag"), goqu.On(goqu.L("1 = 1"))).
+		Select(
+			goqu.L("COALESCE(tag.value, '')").As("tag"),
+			goqu.L("-SUM(price)").As("spent"),
+		).
+		Where(inRange...).
+		GroupBy(goqu.I("tag")).
+		ScanStructs(&spending)
+	if err != nil {
+		return 0, nil, err
+	}
+	tags := make(map[string]float64, len(spending))
+	for _, s := range spending {
+		tags[s.Tag] = s.Spent
+	}
+	return total, tags, nil
+}
+
+func comparedPeriod(r dateRange, spent float64) ComparedPeriod {
+	return ComparedPeriod{
+		Label: r.Label,
+		Start: r.Start.Format(time.DateOnly),
+		End:   r.End.AddDate(0, 0, -1).Format(time.DateOnly),
+		Spent: spent,
+	}
+}
+
+// deltaPercentage is the change from previous to current in percent, nil when there is nothing to compare against.
+func deltaPercentage(current float64, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	percentage := (current - previous) / previous * 100
+	return &percentage
+}
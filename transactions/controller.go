@@ -2,22 +2,129 @@ package transactions
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"checkout-go/customtypes"
 
 	"checkout-go/auth"
+	"checkout-go/ledgers"
+	"checkout-go/settings"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// AnomalyScorer checks a freshly written transaction for unusual amounts.
+type AnomalyScorer interface {
+	ScoreTransaction(ledgerID int64, transactionID int64) error
+}
+
+// AssertionChecker counts the balance assertions of reconciled bank statements that no longer hold.
+type AssertionChecker interface {
+	CountBrokenAssertions(ledgerID int64) (int, error)
+}
+
+// PriceIndex deflates amounts to prices of a base month with a user's price index.
+type PriceIndex interface {
+	Deflator(userID int64, base string) (Deflator, string, error)
+}
+
+// BookLister lists the ledgers a user is a member of.
+type BookLister interface {
+	List(userID int64) ([]ledgers.LedgerWithRole, error)
+}
+
 type TransactionController struct {
 	TransactionsService TransactionService
 	AuthService         auth.UserContextReader
+	LedgerContext       ledgers.LedgerContextReader
+	SettingsContext     settings.SettingsContextReader
+	AnomalyScorer       AnomalyScorer
+	AssertionChecker    AssertionChecker
+	PriceIndex          PriceIndex
+	Books               BookLister
+}
+
+// customFields validates the custom field values of an update, new transactions are checked when they are created.
+func (c *TransactionController) customFields(req *http.Request, values customtypes.Fields) (customtypes.Fields, error) {
+	if c.TransactionsService.CustomFields == nil {
+		if len(values) > 0 {
+			return nil, errors.New("custom fields are not available")
+		}
+		return values, nil
+	}
+	return c.TransactionsService.CustomFields.ValidateFields(c.LedgerContext.GetLedgerIDFromRequest(req), values, false)
+}
+
+// fieldFilters reads the ?field.<name>=value filters on custom fields.
+func fieldFilters(req *http.Request) map[string]string {
+	var fields map[string]string
+	for key, values := range req.URL.Query() {
+		if name, ok := strings.CutPrefix(key, "field."); ok && name != "" && len(values) > 0 {
+			if fields == nil {
+				fields = map[string]string{}
+			}
+			fields[name] = values[0]
+		}
+	}
+	return fields
+}
+
+// scoreAnomalies runs the anomaly scorer without failing the request, the transaction is saved either way.
+func (c *TransactionController) scoreAnomalies(ledgerID int64, transactionID int) {
+	if c.AnomalyScorer == nil {
+		return
+	}
+	if err := c.AnomalyScorer.ScoreTransaction(ledgerID, int64(transactionID)); err != nil {
+		fmt.Printf("could not score transaction %d: %v\n", transactionID, err)
+	}
+}
+
+// realTerms returns the deflator asked for with ?real=true and an optional ?base= month, nil for nominal amounts.
+// The base month is echoed in a header, since it is the latest month of the index unless chosen.
+func (c *TransactionController) realTerms(w http.ResponseWriter, req *http.Request) (Deflator, error) {
+	realStr := req.URL.Query().Get("real")
+	if realStr == "" {
+		return nil, nil
+	}
+	inRealTerms, err := strconv.ParseBool(realStr)
+	if err != nil {
+		return nil, errors.New("invalid real")
+	}
+	if !inRealTerms {
+		return nil, nil
+	}
+	if c.PriceIndex == nil {
+		return nil, errors.New("real terms are not available")
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	deflator, base, err := c.PriceIndex.Deflator(userID, req.URL.Query().Get("base"))
+	if err != nil {
+		return nil, err
+	}
+	w.Header().Set("X-Real-Terms-Base", base)
+	return deflator, nil
+}
+
+// flagBrokenAssertions tells the client through a header when a change broke a reconciled balance.
+// It has to run before the response status is written.
+func (c *TransactionController) flagBrokenAssertions(w http.ResponseWriter, ledgerID int64) {
+	if c.AssertionChecker == nil {
+		return
+	}
+	broken, err := c.AssertionChecker.CountBrokenAssertions(ledgerID)
+	if err != nil {
+		fmt.Printf("could not check balance assertions: %v\n", err)
+		return
+	}
+	if broken > 0 {
+		w.Header().Set("X-Broken-Balance-Assertions", strconv.Itoa(broken))
+	}
 }
 
 func (c *TransactionController) CreateExpense(w http.ResponseWriter, req *http.Request) {
@@ -28,6 +135,8 @@ func (c *TransactionController) CreateExpense(w http.ResponseWriter, req *http.R
 		Note   string                  `json:"comment"`
 		Date   customtypes.TimeWrapper `json:"date"`
 		Tags   []string                `json:"tags"`
+		Tax    *TaxInput               `json:"tax"`
+		Fields customtypes.Fields      `json:"customFields"`
 	}
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
@@ -43,11 +152,26 @@ func (c *TransactionController) CreateExpense(w http.ResponseWriter, req *http.R
 	}
 
 	userID := int(c.AuthService.GetUserIDFromRequest(req))
-	transaction, err := c.TransactionsService.CreateExpense(userID, expense.Name, expense.Price, expense.Seller, expense.Note, time.Time(expense.Date), expense.Tags)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	transaction, err := c.TransactionsService.CreateEntry(NewTransaction{
+		UserID:   userID,
+		LedgerID: ledgerID,
+		Name:     expense.Name,
+		Price:    -expense.Price,
+		Seller:   expense.Seller,
+		Note:     expense.Note,
+		Date:     time.Time(expense.Date),
+		Tags:     expense.Tags,
+		Tax:      expense.Tax,
+
+		CustomFields: expense.Fields,
+	}, loc)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	c.scoreAnomalies(ledgerID, transaction.ID)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	err = json.NewEncoder(w).Encode(transaction)
@@ -70,12 +194,21 @@ func (c *TransactionController) GetExpensesDailyStatisticsForMonthInYear(w http.
 		http.Error(w, "Invalid Year", http.StatusBadRequest)
 		return
 	}
-	userID := int(c.AuthService.GetUserIDFromRequest(req))
-	aggregation, err := c.TransactionsService.GetExpensesDailyStatisticsForMonthInYear(userID, month, year)
+	deflator, err := c.realTerms(w, req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	userSettings := c.SettingsContext.GetSettingsFromRequest(req)
+	aggregation, err := c.TransactionsService.GetExpensesDailyStatisticsForMonthInYear(ledgerID, month, year, userSettings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if deflator != nil {
+		deflator.daily(fmt.Sprintf("%04d-%02d", year, month), *aggregation)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(aggregation)
 	if err != nil {
@@ -90,12 +223,21 @@ func (c *TransactionController) GetExpensesMonthlyStatisticsForAYear(w http.Resp
 		http.Error(w, "Invalid Year", http.StatusBadRequest)
 		return
 	}
-	userID := int(c.AuthService.GetUserIDFromRequest(req))
-	aggregation, err := c.TransactionsService.GetExpensesMonthlyStatisticsForYear(userID, year)
+	deflator, err := c.realTerms(w, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	userSettings := c.SettingsContext.GetSettingsFromRequest(req)
+	aggregation, err := c.TransactionsService.GetExpensesMonthlyStatisticsForYear(ledgerID, year, userSettings)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if deflator != nil {
+		deflator.monthly(*aggregation)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(aggregation)
 	if err != nil {
@@ -113,8 +255,8 @@ func (c *TransactionController) GetTransactionByID(w http.ResponseWriter, req *h
 	filters := TransactionList{
 		IDs: &[]int{id},
 	}
-	userID := c.AuthService.GetUserIDFromRequest(req)
-	aggregation, err := c.TransactionsService.List(userID, filters)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	aggregation, err := c.TransactionsService.List(ledgerID, filters)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -132,8 +274,8 @@ func (c *TransactionController) GetTransactionByID(w http.ResponseWriter, req *h
 }
 
 func (c *TransactionController) GetTagsStatistics(w http.ResponseWriter, req *http.Request) {
-	userID := int(c.AuthService.GetUserIDFromRequest(req))
-	aggregation, err := c.TransactionsService.GetTagsStatistics(userID)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	aggregation, err := c.TransactionsService.GetTagsStatistics(ledgerID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -180,6 +322,18 @@ func (c *TransactionController) ListExpenses(w http.ResponseWriter, req *http.Re
 	if endDateStr != "" {
 		filters.DateLte = (*time.Time)(&endDate)
 	}
+	if reimbursement := req.URL.Query().Get("reimbursement"); reimbursement != "" {
+		status := ReimbursementStatus(reimbursement)
+		filters.Reimbursement = &status
+	}
+	if projectStr := req.URL.Query().Get("project"); projectStr != "" {
+		projectID, err := strconv.ParseInt(projectStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid project", http.StatusBadRequest)
+			return
+		}
+		filters.ProjectID = &projectID
+	}
 	if limitStr != "" {
 		limit, err := strconv.Atoi(limitStr)
 		if err != nil {
@@ -196,10 +350,11 @@ func (c *TransactionController) ListExpenses(w http.ResponseWriter, req *http.Re
 		}
 		filters.Offset = &offset
 	}
+	filters.Fields = fieldFilters(req)
 	zero := 0.0
 	filters.PriceLte = &zero
-	userID := c.AuthService.GetUserIDFromRequest(req)
-	list, err := c.TransactionsService.List(userID, filters)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	list, err := c.TransactionsService.List(ledgerID, filters)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -233,11 +388,12 @@ func (c *TransactionController) ListPayments(w http.ResponseWriter, req *http.Re
 		}
 		filters.Offset = &offset
 	}
+	filters.Fields = fieldFilters(req)
 	// To Lazy to add PriceGt
 	almostZero := 0.0000001
 	filters.PriceGte = &almostZero
-	userID := c.AuthService.GetUserIDFromRequest(req)
-	list, err := c.TransactionsService.List(userID, filters)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	list, err := c.TransactionsService.List(ledgerID, filters)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -251,8 +407,8 @@ func (c *TransactionController) ListPayments(w http.ResponseWriter, req *http.Re
 }
 
 func (c *TransactionController) GetBalance(w http.ResponseWriter, req *http.Request) {
-	userID := int(c.AuthService.GetUserIDFromRequest(req))
-	balance, err := c.TransactionsService.GetBalance(userID)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	balance, err := c.TransactionsService.GetBalance(ledgerID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -273,6 +429,8 @@ func (c *TransactionController) CreatePayment(w http.ResponseWriter, req *http.R
 		Note   string                  `json:"comment"`
 		Date   customtypes.TimeWrapper `json:"date"`
 		Tags   []string                `json:"tags"`
+		Tax    *TaxInput               `json:"tax"`
+		Fields customtypes.Fields      `json:"customFields"`
 	}
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
@@ -287,7 +445,25 @@ func (c *TransactionController) CreatePayment(w http.ResponseWriter, req *http.R
 		return
 	}
 	userID := int(c.AuthService.GetUserIDFromRequest(req))
-	transaction, err := c.TransactionsService.CreatePayment(userID, payment.Name, payment.Price, payment.Seller, payment.Note, time.Time(payment.Date), payment.Tags)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	if err := validatePaymentPrice(payment.Price); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	transaction, err := c.TransactionsService.CreateEntry(NewTransaction{
+		UserID:   userID,
+		LedgerID: ledgerID,
+		Name:     payment.Name,
+		Price:    payment.Price,
+		Seller:   payment.Seller,
+		Note:     payment.Note,
+		Date:     time.Time(payment.Date),
+		Tags:     payment.Tags,
+		Tax:      payment.Tax,
+
+		CustomFields: payment.Fields,
+	}, loc)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -319,17 +495,27 @@ func (c *TransactionController) UpdateExpense(w http.ResponseWriter, req *http.R
 		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
 		return
 	}
-	if *expense.Price > 0 {
+	if expense.Price != nil && *expense.Price > 0 {
 		http.Error(w, fmt.Sprintf("Expense price cannot be higher than 0: %v", *expense.Price), http.StatusBadRequest)
 		return
 	}
 
-	userID := int(c.AuthService.GetUserIDFromRequest(req))
-	transaction, err := c.TransactionsService.Update(userID, id, expense)
+	if expense.CustomFields != nil {
+		expense.CustomFields, err = c.customFields(req, expense.CustomFields)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	transaction, err := c.TransactionsService.Update(ledgerID, id, expense, loc)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	c.scoreAnomalies(ledgerID, transaction.ID)
+	c.flagBrokenAssertions(w, ledgerID)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(transaction)
@@ -362,12 +548,93 @@ func (c *TransactionController) UpdatePayment(w http.ResponseWriter, req *http.R
 		return
 	}
 
-	userID := int(c.AuthService.GetUserIDFromRequest(req))
-	transaction, err := c.TransactionsService.Update(userID, id, payment)
+	if payment.CustomFields != nil {
+		payment.CustomFields, err = c.customFields(req, payment.CustomFields)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	loc := c.SettingsContext.GetSettingsFromRequest(req).Location()
+	transaction, err := c.TransactionsService.Update(ledgerID, id, payment, loc)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	c.flagBrokenAssertions(w, ledgerID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(transaction)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *TransactionController) UpdateTransactionStatus(w http.ResponseWriter, req *http.Request) {
+	type UpdateStatusBody struct {
+		Status Status `json:"status"`
+	}
+	id, err := strconv.Atoi(chi.URLParam(req, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var statusBody UpdateStatusBody
+	err = json.Unmarshal(body, &statusBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	transaction, err := c.TransactionsService.SetStatus(ledgerID, id, statusBody.Status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.flagBrokenAssertions(w, ledgerID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(transaction)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// UpdateTransactionTax records whether a transaction is tax deductible and the tax included in it.
+func (c *TransactionController) UpdateTransactionTax(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(req, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var taxBody TaxInput
+	err = json.Unmarshal(body, &taxBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	transaction, err := c.TransactionsService.SetTax(ledgerID, id, taxBody)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.flagBrokenAssertions(w, ledgerID)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(transaction)
@@ -383,12 +650,13 @@ func (c *TransactionController) DeleteExpense(w http.ResponseWriter, req *http.R
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
-	userID := int(c.AuthService.GetUserIDFromRequest(req))
-	transaction, err := c.TransactionsService.DeleteTransaction(userID, id)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	transaction, err := c.TransactionsService.DeleteTransaction(ledgerID, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	c.flagBrokenAssertions(w, ledgerID)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(transaction)
@@ -399,8 +667,9 @@ func (c *TransactionController) DeleteExpense(w http.ResponseWriter, req *http.R
 }
 
 func (c *TransactionController) GetExpensesSumForCurrentMonth(w http.ResponseWriter, req *http.Request) {
-	userID := c.AuthService.GetUserIDFromRequest(req)
-	transaction, err := c.TransactionsService.GetSumOfExpensesForCurrentMonth(userID)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	userSettings := c.SettingsContext.GetSettingsFromRequest(req)
+	transaction, err := c.TransactionsService.GetSumOfExpensesForCurrentMonth(ledgerID, userSettings)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -415,9 +684,10 @@ func (c *TransactionController) GetExpensesSumForCurrentMonth(w http.ResponseWri
 }
 
 func (c *TransactionController) GetIncomeSpentPercentage(w http.ResponseWriter, req *http.Request) {
-	userID := c.AuthService.GetUserIDFromRequest(req)
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
 
-	data, err := c.TransactionsService.GetIncomeSpentPercentage(userID)
+	userSettings := c.SettingsContext.GetSettingsFromRequest(req)
+	data, err := c.TransactionsService.GetIncomeSpentPercentage(ledgerID, userSettings)
 	if err != nil {
 		fmt.Printf("%v\n", err)
 		http.Error(w, "Failed to fetch income spent percentage", http.StatusInternalServerError)
@@ -429,13 +699,22 @@ func (c *TransactionController) GetIncomeSpentPercentage(w http.ResponseWriter,
 }
 
 func (c *TransactionController) GetCumulativeBalancePerMonth(w http.ResponseWriter, req *http.Request) {
-	userID := c.AuthService.GetUserIDFromRequest(req)
+	deflator, err := c.realTerms(w, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
 
-	balance, err := c.TransactionsService.GetCumulativeBalancePerMonth(userID)
+	userSettings := c.SettingsContext.GetSettingsFromRequest(req)
+	balance, err := c.TransactionsService.GetCumulativeBalancePerMonth(ledgerID, userSettings)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if deflator != nil {
+		deflator.cumulative(balance)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -445,3 +724,176 @@ func (c *TransactionController) GetCumulativeBalancePerMonth(w http.ResponseWrit
 		return
 	}
 }
+
+func (c *TransactionController) Aggregate(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("could not read body: %s\n", err)
+		http.Error(w, fmt.Sprintf("Something went wrong: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var query AggregationQuery
+	err = json.Unmarshal(body, &query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	userSettings := c.SettingsContext.GetSettingsFromRequest(req)
+	result, err := c.TransactionsService.Aggregate(ledgerID, query, userSettings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *TransactionController) GetExpensesMonthlyStatisticsForYears(w http.ResponseWriter, req *http.Request) {
+	var years []int
+	for _, yearStr := range req.URL.Query()["years"] {
+		year, err := strconv.Atoi(yearStr)
+		if err != nil || year < 1 {
+			http.Error(w, "Invalid Year", http.StatusBadRequest)
+			return
+		}
+		years = append(years, year)
+	}
+	if len(years) == 0 {
+		http.Error(w, "At least one year is required", http.StatusBadRequest)
+		return
+	}
+	deflator, err := c.realTerms(w, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	userSettings := c.SettingsContext.GetSettingsFromRequest(req)
+	aggregation, err := c.TransactionsService.GetExpensesMonthlyStatisticsForYears(ledgerID, userSettings, years...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if deflator != nil {
+		deflator.yearly(*aggregation)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(aggregation)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *TransactionController) CompareExpenses(w http.ResponseWriter, req *http.Request) {
+	userSettings := c.SettingsContext.GetSettingsFromRequest(req)
+	mode := ComparisonMode(req.URL.Query().Get("mode"))
+	if mode == "" {
+		mode = ComparePrevious
+	}
+	period, err := time.Parse("2006-01", userSettings.CurrentPeriod().Label)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	year, month := period.Year(), int(period.Month())
+	if yearStr := req.URL.Query().Get("year"); yearStr != "" {
+		year, err = strconv.Atoi(yearStr)
+		if err != nil || year < 1 {
+			http.Error(w, "Invalid Year", http.StatusBadRequest)
+			return
+		}
+	}
+	if monthStr := req.URL.Query().Get("month"); monthStr != "" {
+		month, err = strconv.Atoi(monthStr)
+		if err != nil || month < 1 || month > 12 {
+			http.Error(w, "Invalid Month", http.StatusBadRequest)
+			return
+		}
+	}
+	deflator, err := c.realTerms(w, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ledgerID := c.LedgerContext.GetLedgerIDFromRequest(req)
+	comparison, err := c.TransactionsService.ComparePeriods(ledgerID, mode, year, month, userSettings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if deflator != nil {
+		deflator.comparison(comparison)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(comparison)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetCombined adds up every ledger of the user, or the ones in ?books=, for the financial month named by ?year=
+// and ?month=, the running one by default.
+func (c *TransactionController) GetCombined(w http.ResponseWriter, req *http.Request) {
+	userSettings := c.SettingsContext.GetSettingsFromRequest(req)
+	period := userSettings.CurrentPeriod()
+	yearStr, monthStr := req.URL.Query().Get("year"), req.URL.Query().Get("month")
+	if yearStr != "" || monthStr != "" {
+		year, err := strconv.Atoi(yearStr)
+		if err != nil || year < 1 {
+			http.Error(w, "Invalid Year", http.StatusBadRequest)
+			return
+		}
+		month, err := strconv.Atoi(monthStr)
+		if err != nil || month < 1 || month > 12 {
+			http.Error(w, "Invalid Month", http.StatusBadRequest)
+			return
+		}
+		period = userSettings.Period(year, time.Month(month))
+	}
+	userID := c.AuthService.GetUserIDFromRequest(req)
+	list, err := c.Books.List(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wanted := map[int64]bool{}
+	for _, idStr := range req.URL.Query()["books"] {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || id < 1 {
+			http.Error(w, "Invalid ledger ID", http.StatusBadRequest)
+			return
+		}
+		wanted[id] = true
+	}
+	books := []BookTotals{}
+	for _, ledger := range list {
+		if len(wanted) > 0 && !wanted[ledger.ID] {
+			continue
+		}
+		delete(wanted, ledger.ID)
+		books = append(books, BookTotals{LedgerID: ledger.ID, Name: ledger.Name})
+	}
+	for id := range wanted {
+		http.Error(w, fmt.Sprintf("not a member of ledger %d", id), http.StatusForbidden)
+		return
+	}
+	view, err := c.TransactionsService.Combined(books, period, userSettings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(view)
+	if err != nil {
+		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,23 @@
+package transactions
+
+import (
+	"fmt"
+
+	"checkout-go/customtypes"
+
+	goqu "github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// CustomFieldValidator checks values of a ledger's custom fields, on create the required fields have to be set.
+type CustomFieldValidator interface {
+	ValidateFields(ledgerID int64, values customtypes.Fields, create bool) (customtypes.Fields, error)
+	ValidateFieldsInTx(tx *goqu.TxDatabase, ledgerID int64, values customtypes.Fields, create bool) (customtypes.Fields, error)
+}
+
+// fieldValue is the SQL reading a user defined field as text: numbers as written, booleans as "true" or
+// "false" and NULL when the transaction has no value for the field.
+func fieldValue(name string) exp.LiteralExpression {
+	path := fmt.Sprintf(`$."%s"`, name)
+	return goqu.L("CASE json_type(custom_fields, ?) WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' ELSE CAST(json_extract(custom_fields, ?) AS TEXT) END", path, path)
+}
@@ -2,6 +2,8 @@ package transactions
 
 type IncomeSpentDTO struct {
 	Month           string  `json:"month"`
+	PeriodStart     string  `json:"period_start"`
+	PeriodEnd       string  `json:"period_end"`
 	TotalIncome     float64 `json:"total_income"`
 	TotalSpent      float64 `json:"total_spent"`
 	SpentPercentage float64 `json:"spent_percentage"`
@@ -9,5 +11,7 @@ type IncomeSpentDTO struct {
 
 type CumulativeBalanceDTO struct {
 	YearMonth         string  `json:"year_month"`
+	PeriodStart       string  `json:"period_start"`
+	PeriodEnd         string  `json:"period_end"`
 	CumulativeBalance float64 `json:"cumulative_balance"`
 }
@@ -1,16 +1,62 @@
 package transactions
 
 import (
+	"time"
+
 	"checkout-go/customtypes"
 )
 
 type Transaction struct {
-	ID     int                     `db:"id" goqu:"skipinsert" json:"id"`
-	UserID int                     `db:"user_id" goqu:"omitnil" json:"userId" bson:"userId"` // Comment when running Mongo to SQL migration
-	Name   string                  `db:"name" goqu:"omitnil" json:"name"`
-	Price  float64                 `db:"price" goqu:"omitnil" json:"price"`
-	Seller string                  `db:"seller" goqu:"omitnil" json:"sellerName" bson:"sellerName"`
-	Note   string                  `db:"note" goqu:"omitnil" json:"comment" bson:"comment"`
-	Date   customtypes.TimeWrapper `db:"date" goqu:"omitnil" json:"date"`
-	Tags   customtypes.StringSlice `db:"tags" json:"tags" goqu:"omitnil"`
+	ID                  int                     `db:"id" goqu:"skipinsert" json:"id"`
+	UserID              int                     `db:"user_id" goqu:"omitnil" json:"userId" bson:"userId"` // Comment when running Mongo to SQL migration
+	LedgerID            int64                   `db:"ledger_id" goqu:"omitnil" json:"ledgerId" bson:"-"`
+	Name                string                  `db:"name" goqu:"omitnil" json:"name"`
+	Price               float64                 `db:"price" goqu:"omitnil" json:"price"`
+	Seller              string                  `db:"seller" goqu:"omitnil" json:"sellerName" bson:"sellerName"`
+	Note                string                  `db:"note" goqu:"omitnil" json:"comment" bson:"comment"`
+	Date                customtypes.TimeWrapper `db:"date" goqu:"omitnil" json:"date"`
+	UTCOffset           int                     `db:"utc_offset" json:"-" bson:"-"` // Offset in seconds the date was entered with
+	Tags                customtypes.StringSlice `db:"tags" json:"tags" goqu:"omitnil"`
+	Status              Status                  `db:"status" json:"status" bson:"-"`
+	ReconciliationID    *int64                  `db:"reconciliation_id" json:"reconciliationId,omitempty" bson:"-"` // Reconciliation that matched it with a bank statement
+	Deductible          bool                    `db:"deductible" json:"deductible" bson:"-"`
+	TaxRate             *float64                `db:"tax_rate" json:"taxRate,omitempty" bson:"-"` // VAT or sales tax in percent
+	TaxAmount           float64                 `db:"tax_amount" json:"taxAmount" bson:"-"`       // Tax included in the price, never negative
+	Reimbursable        bool                    `db:"reimbursable" json:"reimbursable" bson:"-"`
+	ReimbursementStatus ReimbursementStatus     `db:"reimbursement_status" json:"reimbursementStatus,omitempty" bson:"-"`
+	ExpenseReportID     *int64                  `db:"expense_report_id" json:"expenseReportId,omitempty" bson:"-"` // Report it was submitted with, or reimbursed by for a payment
+	ProjectID           *int64                  `db:"project_id" json:"projectId,omitempty" bson:"-"`
+	CustomFields        customtypes.Fields      `db:"custom_fields" json:"customFields" bson:"-"` // Values of the user defined fields by field name
+}
+
+type Status string
+
+const (
+	// StatusPending is a transaction not seen on a bank statement yet.
+	StatusPending Status = "pending"
+	// StatusCleared is a transaction ticked off against a statement that is still being reconciled.
+	StatusCleared Status = "cleared"
+	// StatusReconciled is a transaction that is part of a finished reconciliation.
+	StatusReconciled Status = "reconciled"
+)
+
+// ReimbursementStatus tracks an expense someone else, like an employer, pays back.
+type ReimbursementStatus string
+
+const (
+	// ReimbursementPending is a reimbursable expense not handed in yet.
+	ReimbursementPending ReimbursementStatus = "pending"
+	// ReimbursementSubmitted is an expense that is part of a submitted expense report.
+	ReimbursementSubmitted ReimbursementStatus = "submitted"
+	// ReimbursementReimbursed is an expense that was paid back, or the payment that paid it back. Neither
+	// counts as the user's spending or income.
+	ReimbursementReimbursed ReimbursementStatus = "reimbursed"
+)
+
+// restoreOffset shows the date in the offset it was entered with instead of the UTC it is stored in.
+func (t *Transaction) restoreOffset() {
+	if t.UTCOffset == 0 {
+		return
+	}
+	t.Date = customtypes.TimeWrapper(t.Date.Time().In(time.FixedZone("", t.UTCOffset)))
 }
@@ -9,12 +9,24 @@ import (
 )
 
 type Transaction struct {
-	ID     int64          `json:"id"`
-	UserID int64          `json:"user_id"`
-	Name   string         `json:"name"`
-	Price  float64        `json:"price"`
-	Date   string         `json:"date"`
-	Tags   interface{}    `json:"tags"`
-	Seller sql.NullString `json:"seller"`
-	Note   sql.NullString `json:"note"`
+	ID                  int64           `json:"id"`
+	UserID              int64           `json:"user_id"`
+	Name                string          `json:"name"`
+	Price               float64         `json:"price"`
+	Date                string          `json:"date"`
+	Tags                interface{}     `json:"tags"`
+	Seller              sql.NullString  `json:"seller"`
+	Note                sql.NullString  `json:"note"`
+	LedgerID            int64           `json:"ledger_id"`
+	UtcOffset           int64           `json:"utc_offset"`
+	Status              string          `json:"status"`
+	ReconciliationID    sql.NullInt64   `json:"reconciliation_id"`
+	Deductible          int64           `json:"deductible"`
+	TaxRate             sql.NullFloat64 `json:"tax_rate"`
+	TaxAmount           float64         `json:"tax_amount"`
+	Reimbursable        int64           `json:"reimbursable"`
+	ReimbursementStatus string          `json:"reimbursement_status"`
+	ExpenseReportID     sql.NullInt64   `json:"expense_report_id"`
+	ProjectID           sql.NullInt64   `json:"project_id"`
+	CustomFields        string          `json:"custom_fields"`
 }
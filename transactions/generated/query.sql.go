@@ -13,10 +13,10 @@ import (
 const getCumulativeBalancePerMonth = `-- name: GetCumulativeBalancePerMonth :many
 WITH monthly_expenses AS (
     SELECT 
-        CAST(strftime('%Y-%m', date) AS TEXT) AS year_month,  -- Format date as Year-Month
+        CAST(period_label(date, CAST(? AS TEXT), CAST(? AS INTEGER), CAST(? AS TEXT)) AS TEXT) AS year_month,  -- Label of the user's financial month
         SUM(price) AS monthly_balance
     FROM transactions
-    WHERE user_id = ?
+    WHERE ledger_id = ?
     GROUP BY year_month
 )
 SELECT 
@@ -26,13 +26,20 @@ FROM monthly_expenses
 ORDER BY year_month
 `
 
+type GetCumulativeBalancePerMonthParams struct {
+	Zone           string `json:"zone"`
+	PeriodStartDay int64  `json:"period_start_day"`
+	PeriodRule     string `json:"period_rule"`
+	LedgerID       int64  `json:"ledger_id"`
+}
+
 type GetCumulativeBalancePerMonthRow struct {
 	YearMonth         string  `json:"year_month"`
 	CumulativeBalance float64 `json:"cumulative_balance"`
 }
 
-func (q *Queries) GetCumulativeBalancePerMonth(ctx context.Context, userID int64) ([]GetCumulativeBalancePerMonthRow, error) {
-	rows, err := q.db.QueryContext(ctx, getCumulativeBalancePerMonth, userID)
+func (q *Queries) GetCumulativeBalancePerMonth(ctx context.Context, arg GetCumulativeBalancePerMonthParams) ([]GetCumulativeBalancePerMonthRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCumulativeBalancePerMonth, arg.Zone, arg.PeriodStartDay, arg.PeriodRule, arg.LedgerID)
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +64,7 @@ func (q *Queries) GetCumulativeBalancePerMonth(ctx context.Context, userID int64
 const getIncomeSpentPercentage = `-- name: GetIncomeSpentPercentage :many
 WITH stats AS (
 SELECT 
-    CAST(strftime('%Y-%m', date) AS TEXT) AS month,         
+    CAST(period_label(date, CAST(? AS TEXT), CAST(? AS INTEGER), CAST(? AS TEXT)) AS TEXT) AS month,         
     CAST(COALESCE(SUM(CASE WHEN price > 0 THEN price END), 0) AS REAL) AS total_income,  
     CAST(ABS(COALESCE(SUM(CASE WHEN price <= 0 THEN price END), 0)) AS REAL) AS total_spent,
     CAST(
@@ -69,7 +76,7 @@ SELECT
         END 
     AS REAL) AS spent_percentage
 FROM transactions
-WHERE user_id = ?
+WHERE ledger_id = ? AND reimbursement_status != 'reimbursed'
 GROUP BY month
 ORDER BY month DESC
 LIMIT 12
@@ -77,6 +84,13 @@ LIMIT 12
 SELECT month, total_income, total_spent, spent_percentage FROM stats ORDER BY month ASC
 `
 
+type GetIncomeSpentPercentageParams struct {
+	Zone           string `json:"zone"`
+	PeriodStartDay int64  `json:"period_start_day"`
+	PeriodRule     string `json:"period_rule"`
+	LedgerID       int64  `json:"ledger_id"`
+}
+
 type GetIncomeSpentPercentageRow struct {
 	Month           string  `json:"month"`
 	TotalIncome     float64 `json:"total_income"`
@@ -84,8 +98,8 @@ type GetIncomeSpentPercentageRow struct {
 	SpentPercentage float64 `json:"spent_percentage"`
 }
 
-func (q *Queries) GetIncomeSpentPercentage(ctx context.Context, userID int64) ([]GetIncomeSpentPercentageRow, error) {
-	rows, err := q.db.QueryContext(ctx, getIncomeSpentPercentage, userID)
+func (q *Queries) GetIncomeSpentPercentage(ctx context.Context, arg GetIncomeSpentPercentageParams) ([]GetIncomeSpentPercentageRow, error) {
+	rows, err := q.db.QueryContext(ctx, getIncomeSpentPercentage, arg.Zone, arg.PeriodStartDay, arg.PeriodRule, arg.LedgerID)
 	if err != nil {
 		return nil, err
 	}
@@ -115,17 +129,19 @@ func (q *Queries) GetIncomeSpentPercentage(ctx context.Context, userID int64) ([
 const getSumOfExpensesOfAMonth = `-- name: GetSumOfExpensesOfAMonth :one
 SELECT SUM(price)
 FROM transactions
-WHERE user_id = ? AND price < 0 AND CAST(strftime('%Y', date) AS  INT) = ? AND CAST(strftime('%m', date) AS INT) = ?
+WHERE ledger_id = ? AND price < 0 AND reimbursement_status != 'reimbursed' AND period_label(date, CAST(? AS TEXT), CAST(? AS INTEGER), CAST(? AS TEXT)) = CAST(? AS TEXT)
 `
 
 type GetSumOfExpensesOfAMonthParams struct {
-	UserID int64  `json:"user_id"`
-	Date   string `json:"date"`
-	Date_2 string `json:"date_2"`
+	LedgerID       int64  `json:"ledger_id"`
+	Zone           string `json:"zone"`
+	PeriodStartDay int64  `json:"period_start_day"`
+	PeriodRule     string `json:"period_rule"`
+	Period         string `json:"period"`
 }
 
 func (q *Queries) GetSumOfExpensesOfAMonth(ctx context.Context, arg GetSumOfExpensesOfAMonthParams) (sql.NullFloat64, error) {
-	row := q.db.QueryRowContext(ctx, getSumOfExpensesOfAMonth, arg.UserID, arg.Date, arg.Date_2)
+	row := q.db.QueryRowContext(ctx, getSumOfExpensesOfAMonth, arg.LedgerID, arg.Zone, arg.PeriodStartDay, arg.PeriodRule, arg.Period)
 	var sum sql.NullFloat64
 	err := row.Scan(&sum)
 	return sum, err
@@ -0,0 +1,82 @@
+package transactions
+
+import (
+	"fmt"
+	"time"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+// ImportedTransaction is a transaction read from a file by one of the importers.
+type ImportedTransaction struct {
+	// Line is where the transaction starts in the file, for error messages
+	Line   int       `json:"line"`
+	Name   string    `json:"name"`
+	Price  float64   `json:"price"`
+	Seller string    `json:"sellerName"`
+	Note   string    `json:"comment"`
+	Date   time.Time `json:"date"`
+	Tags   []string  `json:"tags"`
+	Status Status    `json:"status"`
+}
+
+type ImportResult struct {
+	Preview  bool `json:"preview"`
+	Imported int  `json:"imported"`
+	// Skipped counts what the file holds that does not become a transaction, like transfers between its accounts
+	Skipped      int                   `json:"skipped"`
+	Transactions []ImportedTransaction `json:"transactions"`
+}
+
+// Import books the transactions an importer read. All of them are checked first and stored in one database
+// transaction, so a file with a bad transaction stores nothing. A preview only does the checks and returns what
+// would be booked.
+func (service *TransactionService) Import(userID int, ledgerID int64, imported []ImportedTransaction, skipped int, preview bool, loc *time.Location) (*ImportResult, error) {
+	for _, t := range imported {
+		if t.Status != StatusPending && t.Status != StatusCleared {
+			return nil, fmt.Errorf("line %d: status must be %q or %q", t.Line, StatusPending, StatusCleared)
+		}
+		if t.Price > 0 {
+			if err := validatePaymentPrice(t.Price); err != nil {
+				return nil, fmt.Errorf("line %d: %w", t.Line, err)
+			}
+		}
+	}
+	// Files carry no custom fields, which only works out while the ledger requires none
+	if service.CustomFields != nil && len(imported) > 0 {
+		if _, err := service.CustomFields.ValidateFields(ledgerID, nil, true); err != nil {
+			return nil, fmt.Errorf("imported transactions have no custom fields: %w", err)
+		}
+	}
+	result := ImportResult{Preview: preview, Skipped: skipped, Transactions: imported}
+	if result.Transactions == nil {
+		result.Transactions = []ImportedTransaction{}
+	}
+	if preview {
+		return &result, nil
+	}
+	err := service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		for _, t := range imported {
+			_, err := service.CreateInTx(tx, NewTransaction{
+				UserID:   userID,
+				LedgerID: ledgerID,
+				Name:     t.Name,
+				Price:    t.Price,
+				Seller:   t.Seller,
+				Note:     t.Note,
+				Date:     t.Date,
+				Tags:     t.Tags,
+				Status:   t.Status,
+			}, loc)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", t.Line, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Imported = len(imported)
+	return &result, nil
+}
@@ -1,12 +1,12 @@
 -- name: GetSumOfExpensesOfAMonth :one
 SELECT SUM(price)
 FROM transactions
-WHERE user_id = ? AND price < 0 AND CAST(strftime('%Y', date) AS  INT) = ? AND CAST(strftime('%m', date) AS INT) = ?;
+WHERE ledger_id = sqlc.arg(ledger_id) AND price < 0 AND reimbursement_status != 'reimbursed' AND period_label(date, CAST(sqlc.arg(zone) AS TEXT), CAST(sqlc.arg(period_start_day) AS INTEGER), CAST(sqlc.arg(period_rule) AS TEXT)) = CAST(sqlc.arg(period) AS TEXT);
 
 -- name: GetIncomeSpentPercentage :many
 WITH stats AS (
 SELECT 
-    CAST(strftime('%Y-%m', date) AS TEXT) AS month,         
+    CAST(period_label(date, CAST(sqlc.arg(zone) AS TEXT), CAST(sqlc.arg(period_start_day) AS INTEGER), CAST(sqlc.arg(period_rule) AS TEXT)) AS TEXT) AS month,         
     CAST(COALESCE(SUM(CASE WHEN price > 0 THEN price END), 0) AS REAL) AS total_income,  
     CAST(ABS(COALESCE(SUM(CASE WHEN price <= 0 THEN price END), 0)) AS REAL) AS total_spent,
     CAST(
@@ -18,7 +18,7 @@ SELECT
         END 
     AS REAL) AS spent_percentage
 FROM transactions
-WHERE user_id = ?
+WHERE ledger_id = sqlc.arg(ledger_id) AND reimbursement_status != 'reimbursed'
 GROUP BY month
 ORDER BY month DESC
 LIMIT 12
@@ -29,10 +29,10 @@ SELECT * FROM stats ORDER BY month ASC;
 -- name: GetCumulativeBalancePerMonth :many
 WITH monthly_expenses AS (
     SELECT 
-        CAST(strftime('%Y-%m', date) AS TEXT) AS year_month,  -- Format date as Year-Month
+        CAST(period_label(date, CAST(sqlc.arg(zone) AS TEXT), CAST(sqlc.arg(period_start_day) AS INTEGER), CAST(sqlc.arg(period_rule) AS TEXT)) AS TEXT) AS year_month,  -- Label of the user's financial month
         SUM(price) AS monthly_balance
     FROM transactions
-    WHERE user_id = ?
+    WHERE ledger_id = sqlc.arg(ledger_id)
     GROUP BY year_month
 )
 SELECT 
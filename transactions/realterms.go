@@ -0,0 +1,74 @@
+package transactions
+
+import (
+	"math"
+	"sort"
+
+	dtos "checkout-go/transactions/dtos"
+)
+
+// Deflator returns the factor that converts an amount of a month ("2024-01") to prices of a base month.
+type Deflator func(month string) float64
+
+func (d Deflator) amount(month string, value float64) float64 {
+	return math.Round(value*d(month)*100) / 100
+}
+
+func (d Deflator) monthly(summaries []MonthlyExpenseSummary) {
+	for i := range summaries {
+		s := &summaries[i]
+		s.Sum = d.amount(s.Period, s.Sum)
+		s.Average = d.amount(s.Period, s.Average)
+		s.Max = d.amount(s.Period, s.Max)
+		s.Min = d.amount(s.Period, s.Min)
+	}
+}
+
+func (d Deflator) yearly(summaries []YearlyExpenseSummary) {
+	for i := range summaries {
+		s := &summaries[i]
+		month := s.Year + "-" + s.Month
+		s.Total = d.amount(month, s.Total)
+		s.Average = d.amount(month, s.Average)
+		s.Max = d.amount(month, s.Max)
+		s.Min = d.amount(month, s.Min)
+	}
+}
+
+// daily deflates the days of the financial month named month, all with that month's factor.
+func (d Deflator) daily(month string, summaries []DailyExpenseSummary) {
+	for i := range summaries {
+		s := &summaries[i]
+		s.Sum = d.amount(month, s.Sum)
+		s.Average = d.amount(month, s.Average)
+		s.Max = d.amount(month, s.Max)
+		s.Min = d.amount(month, s.Min)
+	}
+}
+
+// cumulative deflates the balance at the end of every month with that month's factor.
+func (d Deflator) cumulative(balances []dtos.CumulativeBalanceDTO) {
+	for i := range balances {
+		balances[i].CumulativeBalance = d.amount(balances[i].YearMonth, balances[i].CumulativeBalance)
+	}
+}
+
+func (d Deflator) comparison(c *PeriodComparison) {
+	c.Current.Spent = d.amount(c.Current.Label, c.Current.Spent)
+	c.Previous.Spent = d.amount(c.Previous.Label, c.Previous.Spent)
+	c.Delta = c.Current.Spent - c.Previous.Spent
+	c.DeltaPercentage = deltaPercentage(c.Current.Spent, c.Previous.Spent)
+	for i := range c.Tags {
+		t := &c.Tags[i]
+		t.Current = d.amount(c.Current.Label, t.Current)
+		t.Previous = d.amount(c.Previous.Label, t.Previous)
+		t.Delta = t.Current - t.Previous
+		t.DeltaPercentage = deltaPercentage(t.Current, t.Previous)
+	}
+	sort.Slice(c.Tags, func(i, j int) bool {
+		if c.Tags[i].Delta != c.Tags[j].Delta {
+			return c.Tags[i].Delta > c.Tags[j].Delta
+		}
+		return c.Tags[i].Tag < c.Tags[j].Tag
+	})
+}
@@ -6,6 +6,18 @@ CREATE TABLE transactions (
     "date" TEXT NOT NULL,
     "tags" JSONB,
     "seller" TEXT,
-    "note" TEXT
+    "note" TEXT,
+    "ledger_id" INTEGER NOT NULL DEFAULT 0,
+    "utc_offset" INTEGER NOT NULL DEFAULT 0,
+    "status" TEXT NOT NULL DEFAULT 'pending',
+    "reconciliation_id" INTEGER,
+    "deductible" INTEGER NOT NULL DEFAULT 0,
+    "tax_rate" REAL,
+    "tax_amount" REAL NOT NULL DEFAULT 0,
+    "reimbursable" INTEGER NOT NULL DEFAULT 0,
+    "reimbursement_status" TEXT NOT NULL DEFAULT '',
+    "expense_report_id" INTEGER,
+    "project_id" INTEGER,
+    "custom_fields" TEXT NOT NULL DEFAULT '{}'
 );
 
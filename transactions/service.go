@@ -6,32 +6,133 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"sort"
 	"strconv"
 	"time"
 
 	"checkout-go/customtypes"
+	"checkout-go/settings"
 	dtos "checkout-go/transactions/dtos"
 	queries "checkout-go/transactions/generated"
 
 	goqu "github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
 )
 
 type TransactionService struct {
-	DB *goqu.Database
+	DB           *goqu.Database
+	CustomFields CustomFieldValidator
 }
 
-func (service *TransactionService) Create(userID int, name string, price float64, seller string, note string, date time.Time, tags []string) (*Transaction, error) {
-	transactions := service.DB.From("transactions")
-	result, err := transactions.Insert().Rows(
+func (service *TransactionService) Create(userID int, ledgerID int64, name string, price float64, seller string, note string, date time.Time, tags []string, loc *time.Location) (*Transaction, error) {
+	return service.CreateEntry(NewTransaction{
+		UserID:   userID,
+		LedgerID: ledgerID,
+		Name:     name,
+		Price:    price,
+		Seller:   seller,
+		Note:     note,
+		Date:     date,
+		Tags:     tags,
+	}, loc)
+}
+
+// CreateEntry creates a transaction with its tax and custom fields in a single write.
+func (service *TransactionService) CreateEntry(entry NewTransaction, loc *time.Location) (*Transaction, error) {
+	var transaction *Transaction
+	err := service.DB.WithTx(func(tx *goqu.TxDatabase) error {
+		var err error
+		transaction, err = service.CreateInTx(tx, entry, loc)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// NewTransaction is a transaction to create with everything recorded on it, so it is written at once.
+type NewTransaction struct {
+	UserID   int
+	LedgerID int64
+	Name     string
+	// Price is negative for expenses
+	Price  float64
+	Seller string
+	Note   string
+	Date   time.Time
+	Tags   []string
+	// Status is pending when empty
+	Status Status
+	// Tax is applied to the price as SetTax does
+	Tax *TaxInput
+	// CustomFields are the values of the ledger's custom fields, every required one has to be set
+	CustomFields customtypes.Fields
+}
+
+// CreateInTx inserts a transaction as part of a larger change. Every way of creating a transaction ends up
+// here, so this is where the custom fields of the ledger are checked.
+func (service *TransactionService) CreateInTx(tx *goqu.TxDatabase, entry NewTransaction, loc *time.Location) (*Transaction, error) {
+	fields := entry.CustomFields
+	if service.CustomFields != nil {
+		var err error
+		fields, err = service.CustomFields.ValidateFieldsInTx(tx, entry.LedgerID, entry.CustomFields, true)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(fields) > 0 {
+		return nil, errors.New("custom fields are not available")
+	}
+	date := customtypes.InLocation(entry.Date, loc)
+	_, utcOffset := date.Zone()
+	transaction := Transaction{
+		UserID:    entry.UserID,
+		LedgerID:  entry.LedgerID,
+		Name:      entry.Name,
+		Price:     entry.Price,
+		Seller:    entry.Seller,
+		Note:      entry.Note,
+		Date:      customtypes.TimeWrapper(date),
+		UTCOffset: utcOffset,
+		Tags:      customtypes.StringSlice(entry.Tags),
+		Status:    entry.Status,
+
+		CustomFields: fields,
+	}
+	if transaction.CustomFields == nil {
+		transaction.CustomFields = customtypes.Fields{}
+	}
+	if transaction.Status == "" {
+		transaction.Status = StatusPending
+	}
+	if entry.Tax != nil {
+		if err := entry.Tax.Validate(entry.Price); err != nil {
+			return nil, err
+		}
+		price, tax, err := entry.Tax.apply(entry.Price)
+		if err != nil {
+			return nil, err
+		}
+		transaction.Price = price
+		transaction.Deductible = entry.Tax.Deductible
+		transaction.TaxRate = entry.Tax.TaxRate
+		transaction.TaxAmount = tax
+	}
+	result, err := tx.Insert("transactions").Rows(
 		goqu.Record{
-			"user_id": userID,
-			"name":    name,
-			"price":   price,
-			"date":    date,
-			"seller":  seller,
-			"note":    note,
-			"tags":    customtypes.StringSlice(tags),
+			"user_id":       transaction.UserID,
+			"ledger_id":     transaction.LedgerID,
+			"name":          transaction.Name,
+			"price":         transaction.Price,
+			"date":          date,
+			"utc_offset":    utcOffset,
+			"seller":        transaction.Seller,
+			"note":          transaction.Note,
+			"tags":          transaction.Tags,
+			"status":        transaction.Status,
+			"deductible":    transaction.Deductible,
+			"tax_rate":      transaction.TaxRate,
+			"tax_amount":    transaction.TaxAmount,
+			"custom_fields": transaction.CustomFields,
 		},
 	).Executor().Exec()
 	if err != nil {
@@ -41,28 +142,26 @@ func (service *TransactionService) Create(userID int, name string, price float64
 	if err != nil {
 		return nil, err
 	}
-	transaction := Transaction{
-		ID:     int(insertID),
-		UserID: userID,
-		Name:   name,
-		Price:  price,
-		Seller: seller,
-		Note:   note,
-		Date:   customtypes.TimeWrapper(date),
-		Tags:   customtypes.StringSlice(tags),
-	}
+	transaction.ID = int(insertID)
 	return &transaction, nil
 }
 
-func (service *TransactionService) CreateExpense(userID int, name string, price float64, seller string, note string, date time.Time, tags []string) (*Transaction, error) {
-	return service.Create(userID, name, -price, seller, note, date, tags)
+func (service *TransactionService) CreateExpense(userID int, ledgerID int64, name string, price float64, seller string, note string, date time.Time, tags []string, loc *time.Location) (*Transaction, error) {
+	return service.Create(userID, ledgerID, name, -price, seller, note, date, tags, loc)
 }
 
-func (service *TransactionService) CreatePayment(userID int, name string, price float64, seller string, note string, date time.Time, tags []string) (*Transaction, error) {
+func validatePaymentPrice(price float64) error {
 	if price < 1 {
-		return nil, fmt.Errorf("payment price cannot be less than 1")
+		return fmt.Errorf("payment price cannot be less than 1")
+	}
+	return nil
+}
+
+func (service *TransactionService) CreatePayment(userID int, ledgerID int64, name string, price float64, seller string, note string, date time.Time, tags []string, loc *time.Location) (*Transaction, error) {
+	if err := validatePaymentPrice(price); err != nil {
+		return nil, err
 	}
-	return service.Create(userID, name, price, seller, note, date, tags)
+	return service.Create(userID, ledgerID, name, price, seller, note, date, tags, loc)
 }
 
 type TransactionUpdate struct {
@@ -72,9 +171,11 @@ type TransactionUpdate struct {
 	Note   *string                  `json:"comment,omitempty"`
 	Date   *customtypes.TimeWrapper `json:"date,omitempty"`
 	Tags   *[]string                `json:"tags,omitempty"`
+	// CustomFields sets the given fields and keeps the others, a null value removes a field
+	CustomFields customtypes.Fields `json:"customFields,omitempty"`
 }
 
-func (service *TransactionService) Update(userID, ID int, updateData TransactionUpdate) (*Transaction, error) {
+func (service *TransactionService) Update(ledgerID int64, ID int, updateData TransactionUpdate, loc *time.Location) (*Transaction, error) {
 	fields := map[string]any{}
 
 	if updateData.Name != nil {
@@ -82,6 +183,8 @@ func (service *TransactionService) Update(userID, ID int, updateData Transaction
 	}
 	if updateData.Price != nil {
 		fields["price"] = *updateData.Price
+		// Tax recorded as a rate follows the new price
+		fields["tax_amount"] = goqu.L("CASE WHEN tax_rate IS NULL THEN tax_amount ELSE round(abs(?) * tax_rate / (100 + tax_rate), 2) END", *updateData.Price)
 	}
 	if updateData.Tags != nil {
 		// Convert the tags slice to a JSON string before storing
@@ -92,12 +195,23 @@ func (service *TransactionService) Update(userID, ID int, updateData Transaction
 		fields["tags"] = string(tagsJSON)
 	}
 	if updateData.Date != nil {
-		fields["date"] = updateData.Date.Time().Format(time.RFC3339)
+		// Stored in UTC like inserted rows so that dates keep sorting and comparing as strings
+		date := customtypes.InLocation(updateData.Date.Time(), loc)
+		_, utcOffset := date.Zone()
+		fields["date"] = date.UTC().Format(time.RFC3339)
+		fields["utc_offset"] = utcOffset
+	}
+	if updateData.CustomFields != nil {
+		patch, err := updateData.CustomFields.Value()
+		if err != nil {
+			return nil, err
+		}
+		fields["custom_fields"] = goqu.L("json_patch(custom_fields, ?)", patch)
 	}
 	if len(fields) == 0 {
 		return nil, fmt.Errorf("no fields to update")
 	}
-	update := service.DB.Update("transactions").Set(fields).Where(goqu.Ex{"id": ID, "user_id": userID})
+	update := service.DB.Update("transactions").Set(fields).Where(goqu.Ex{"id": ID, "ledger_id": ledgerID})
 
 	res, err := update.Executor().ExecContext(context.TODO())
 	if err != nil {
@@ -111,10 +225,40 @@ func (service *TransactionService) Update(userID, ID int, updateData Transaction
 		return nil, fmt.Errorf("transaction not found")
 	}
 	transaction := Transaction{}
-	_, err = service.DB.From("transactions").Where(goqu.Ex{"id": ID, "user_id": userID}).ScanStruct(&transaction)
+	_, err = service.DB.From("transactions").Where(goqu.Ex{"id": ID, "ledger_id": ledgerID}).ScanStruct(&transaction)
 	if err != nil {
 		return nil, err
 	}
+	transaction.restoreOffset()
+	return &transaction, nil
+}
+
+// SetStatus marks a transaction as pending or cleared. Transactions only become reconciled by finishing a
+// reconciliation; setting a reconciled one back takes it out of its reconciliation.
+func (service *TransactionService) SetStatus(ledgerID int64, ID int, status Status) (*Transaction, error) {
+	if status != StatusPending && status != StatusCleared {
+		return nil, fmt.Errorf("status must be %q or %q", StatusPending, StatusCleared)
+	}
+	res, err := service.DB.Update("transactions").
+		Set(goqu.Record{"status": status, "reconciliation_id": nil}).
+		Where(goqu.Ex{"id": ID, "ledger_id": ledgerID}).
+		Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update transaction: %w", err)
+	}
+	numRowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if numRowsAffected == 0 {
+		return nil, fmt.Errorf("transaction not found")
+	}
+	transaction := Transaction{}
+	_, err = service.DB.From("transactions").Where(goqu.Ex{"id": ID, "ledger_id": ledgerID}).ScanStruct(&transaction)
+	if err != nil {
+		return nil, err
+	}
+	transaction.restoreOffset()
 	return &transaction, nil
 }
 
@@ -126,16 +270,43 @@ type TransactionList struct {
 	Tags     *[]string  `json:"tags,omitempty"`
 	DateGte  *time.Time `json:"dategte,omitempty"`
 	DateLte  *time.Time `json:"datelte,omitempty"`
-	Limit    *int       `json:"limit"`
-	Offset   *int       `json:"offset"`
+	// Reimbursement lists the expenses with that reimbursement status
+	Reimbursement   *ReimbursementStatus `json:"reimbursement,omitempty"`
+	ExpenseReportID *int64               `json:"expenseReportId,omitempty"`
+	ProjectID       *int64               `json:"projectId,omitempty"`
+	// Fields lists the transactions whose user defined fields have these values, see FieldValue
+	Fields map[string]string `json:"fields,omitempty"`
+	Limit  *int              `json:"limit"`
+	Offset *int              `json:"offset"`
 }
 
-func (service *TransactionService) List(userID int64, filters TransactionList) (*[]Transaction, error) {
+func (service *TransactionService) List(ledgerID int64, filters TransactionList) (*[]Transaction, error) {
 	selectStatement := service.DB.From("transactions").Select("*").Where(goqu.Ex{
-		"user_id": userID,
+		"ledger_id": ledgerID,
 	})
+	selectStatement = applyFilters(selectStatement, filters)
+	if filters.Limit != nil {
+		selectStatement = selectStatement.Limit(uint(*filters.Limit))
+	}
+	if filters.Offset != nil {
+		selectStatement = selectStatement.Offset(uint(*filters.Offset))
+	}
+	selectStatement = selectStatement.Order(goqu.L("date").Desc())
+	transactions := []Transaction{}
+	err := selectStatement.ScanStructs(&transactions)
+	if err != nil {
+		return nil, err
+	}
+	for i := range transactions {
+		transactions[i].restoreOffset()
+	}
+	return &transactions, nil
+}
+
+// applyFilters narrows selectStatement down to the transactions matching filters, ignoring paging.
+func applyFilters(selectStatement *goqu.SelectDataset, filters TransactionList) *goqu.SelectDataset {
 	if filters.IDs != nil {
-		selectStatement = selectStatement.Where(goqu.Ex{"id": filters.IDs})
+		selectStatement = selectStatement.Where(goqu.Ex{"transactions.id": filters.IDs})
 	}
 	if filters.Name != nil {
 		selectStatement = selectStatement.Where(goqu.Ex{
@@ -167,33 +338,45 @@ func (service *TransactionService) List(userID int64, filters TransactionList) (
 			"date": goqu.Op{"lte": filters.DateLte},
 		})
 	}
-	if filters.Limit != nil {
-		selectStatement = selectStatement.Limit(uint(*filters.Limit))
+	if filters.Reimbursement != nil {
+		selectStatement = selectStatement.Where(goqu.Ex{"reimbursement_status": *filters.Reimbursement})
 	}
-	if filters.Offset != nil {
-		selectStatement = selectStatement.Offset(uint(*filters.Offset))
+	if filters.ExpenseReportID != nil {
+		selectStatement = selectStatement.Where(goqu.Ex{"expense_report_id": *filters.ExpenseReportID})
 	}
-	selectStatement = selectStatement.Order(goqu.L("date").Desc())
-	transactions := []Transaction{}
-	err := selectStatement.ScanStructs(&transactions)
-	if err != nil {
-		return nil, err
+	if filters.ProjectID != nil {
+		selectStatement = selectStatement.Where(goqu.Ex{"project_id": *filters.ProjectID})
 	}
-	return &transactions, nil
+	for name, value := range filters.Fields {
+		selectStatement = selectStatement.Where(fieldValue(name).Eq(value))
+	}
+	return selectStatement
+}
+
+// personal leaves out reimbursed expenses and the payments that reimbursed them, which are not the user's own
+// spending and income, from statistics. Balances still include them.
+func personal() exp.Expression {
+	return goqu.C("reimbursement_status").Neq(ReimbursementReimbursed)
 }
 
 type MonthlyExpenseSummary struct {
-	Month   int     `db:"month" json:"month"`
-	Count   int     `db:"count" json:"count"`
-	Sum     float64 `db:"sum" json:"sum"`
-	Average float64 `db:"avg" json:"avg"`
-	Max     float64 `db:"max" json:"max" `
-	Min     float64 `db:"min" json:"min"`
+	Month       int     `db:"month" json:"month"`
+	Period      string  `db:"period" json:"period"`
+	PeriodStart string  `db:"-" json:"periodStart"`
+	PeriodEnd   string  `db:"-" json:"periodEnd"`
+	Count       int     `db:"count" json:"count"`
+	Sum         float64 `db:"sum" json:"sum"`
+	Average     float64 `db:"avg" json:"avg"`
+	Max         float64 `db:"max" json:"max" `
+	Min         float64 `db:"min" json:"min"`
 }
 
-func (service *TransactionService) GetExpensesMonthlyStatisticsForYear(userID int, year int) (*[]MonthlyExpenseSummary, error) {
+// GetExpensesMonthlyStatisticsForYear groups the expenses of a year by the user's financial months.
+func (service *TransactionService) GetExpensesMonthlyStatisticsForYear(ledgerID int64, year int, userSettings settings.Settings) (*[]MonthlyExpenseSummary, error) {
+	period := periodLabel(userSettings)
 	selectStatement := service.DB.From("transactions").Select(
-		goqu.L("CAST(strftime('%m', date) AS INTEGER)").As("month"),
+		goqu.L("CAST(substr(?, 6, 2) AS INTEGER)", period).As("month"),
+		period.As("period"),
 		goqu.COUNT("*").As("count"),
 		goqu.SUM("price").As("sum"),
 		goqu.AVG("price").As("avg"),
@@ -202,39 +385,48 @@ func (service *TransactionService) GetExpensesMonthlyStatisticsForYear(userID in
 	).
 		Where(
 			goqu.Ex{
-				"user_id": userID,
+				"ledger_id": ledgerID,
 			},
-			goqu.L("CAST(strftime('%Y', date) AS INTEGER) = ?", year),
+			goqu.L("substr(?, 1, 4) = ?", period, strconv.Itoa(year)),
 			goqu.C("price").Lte(0),
+			personal(),
 		).
-		GroupBy(goqu.L("strftime('%m', date)"))
+		GroupBy(goqu.I("period"))
 	var summaries []MonthlyExpenseSummary
 	if err := selectStatement.ScanStructs(&summaries); err != nil {
 		fmt.Printf("err: %v\n", err)
 		return nil, err
 	}
+	for i := range summaries {
+		p := userSettings.Period(year, time.Month(summaries[i].Month))
+		summaries[i].PeriodStart = p.Start.Format(time.DateOnly)
+		summaries[i].PeriodEnd = p.LastDay().Format(time.DateOnly)
+	}
 	return &summaries, nil
 }
 
 type YearlyExpenseSummary struct {
-	Month   string  `db:"month"`
-	Year    string  `db:"year"`
-	Count   int     `db:"count"`
-	Total   float64 `db:"sum"`
-	Average float64 `db:"avg"`
-	Max     float64 `db:"max"`
-	Min     float64 `db:"min"`
+	Month   string  `db:"month" json:"month"`
+	Year    string  `db:"year" json:"year"`
+	Count   int     `db:"count" json:"count"`
+	Total   float64 `db:"sum" json:"sum"`
+	Average float64 `db:"avg" json:"avg"`
+	Max     float64 `db:"max" json:"max"`
+	Min     float64 `db:"min" json:"min"`
 }
 
-func (service *TransactionService) GetExpensesMonthlyStatisticsForYears(userID int, years ...int) (*[]YearlyExpenseSummary, error) {
-	yearStrings := make([]string, len(years))
+// GetExpensesMonthlyStatisticsForYears lists the financial months of several years side by side, newest first,
+// so that the same month can be compared across years.
+func (service *TransactionService) GetExpensesMonthlyStatisticsForYears(ledgerID int64, userSettings settings.Settings, years ...int) (*[]YearlyExpenseSummary, error) {
+	period := periodLabel(userSettings)
+	yearStrings := make([]string, 0, len(years))
 
 	for _, year := range years {
 		yearStrings = append(yearStrings, strconv.Itoa(year))
 	}
 	selectStatement := service.DB.From("transactions").Select(
-		goqu.L("strftime('%m', date)").As("month"),
-		goqu.L("strftime('%Y', date)").As("year"),
+		goqu.L("substr(?, 6, 2)", period).As("month"),
+		goqu.L("substr(?, 1, 4)", period).As("year"),
 		goqu.COUNT("*").As("count"),
 		goqu.SUM("price").As("sum"),
 		goqu.AVG("price").As("avg"),
@@ -243,16 +435,14 @@ func (service *TransactionService) GetExpensesMonthlyStatisticsForYears(userID i
 	).
 		Where(
 			goqu.Ex{
-				"user_id": userID,
+				"ledger_id": ledgerID,
 			},
-			goqu.L("strftime('%Y', date)").In(yearStrings),
+			goqu.L("substr(?, 1, 4)", period).In(yearStrings),
 			goqu.C("price").Lte(0),
+			personal(),
 		).
-		GroupBy(goqu.L("strftime('%m', date)")).
-		Order(
-			goqu.L("strftime('%Y', date)").Desc(),
-			goqu.L("strftime('%m', date)").Desc(),
-		)
+		GroupBy(period).
+		Order(period.Desc())
 
 	var summaries []YearlyExpenseSummary
 	if err := selectStatement.ScanStructs(&summaries); err != nil {
@@ -264,6 +454,7 @@ func (service *TransactionService) GetExpensesMonthlyStatisticsForYears(userID i
 
 type DailyExpenseSummary struct {
 	Day     int     `db:"day" json:"day"`
+	Date    string  `db:"local_date" json:"date"`
 	Count   int     `db:"count" json:"count"`
 	Sum     float64 `db:"sum" json:"sum"`
 	Average float64 `db:"avg" json:"avg"`
@@ -271,12 +462,17 @@ type DailyExpenseSummary struct {
 	Min     float64 `db:"min" json:"min"`
 }
 
-func (service *TransactionService) GetExpensesDailyStatisticsForMonthInYear(userID int, month int, year int) (*[]DailyExpenseSummary, error) {
+// GetExpensesDailyStatisticsForMonthInYear returns one row per day of the user's financial month named after month,
+// including days without expenses.
+func (service *TransactionService) GetExpensesDailyStatisticsForMonthInYear(ledgerID int64, month int, year int, userSettings settings.Settings) (*[]DailyExpenseSummary, error) {
 	if month > 12 {
 		return nil, fmt.Errorf("invalid month")
 	}
+	period := userSettings.Period(year, time.Month(month))
+	date := localDate(userSettings.Location())
 	selectStatement := service.DB.From("transactions").Select(
-		goqu.L("CAST(strftime('%d', date) AS INT)").As("day"),
+		goqu.L("CAST(strftime('%d', ?) AS INT)", date).As("day"),
+		goqu.L("strftime('%Y-%m-%d', ?)", date).As("local_date"),
 		goqu.COUNT("*").As("count"),
 		goqu.SUM("price").As("sum"),
 		goqu.AVG("price").As("avg"),
@@ -285,41 +481,31 @@ func (service *TransactionService) GetExpensesDailyStatisticsForMonthInYear(user
 	).
 		Where(
 			goqu.Ex{
-				"user_id": userID,
+				"ledger_id": ledgerID,
 			},
-			goqu.L("strftime('%Y', date) = ?", strconv.Itoa(year)),
-			goqu.L("CAST(strftime('%m', date) AS INT) = ?", month),
+			periodLabel(userSettings).Eq(period.Label),
 			goqu.C("price").Lte(0),
+			personal(),
 		).
-		GroupBy("day").
-		Order(goqu.I("day").Asc())
+		GroupBy("local_date").
+		Order(goqu.I("local_date").Asc())
 	var summaries []DailyExpenseSummary
 	if err := selectStatement.ScanStructs(&summaries); err != nil {
 		return nil, err
 	}
-	daysInMonth := daysInMonth(month, year)
-	if len(summaries) == daysInMonth {
-		return &summaries, nil
-	}
-	daysMap := make(map[int]DailyExpenseSummary, daysInMonth)
+	daysMap := make(map[string]DailyExpenseSummary, len(summaries))
 	for _, expense := range summaries {
-		daysMap[expense.Day+1] = expense
+		daysMap[expense.Date] = expense
 	}
-	for i := range daysInMonth {
-		dayIndex := i + 1
-		_, ok := daysMap[dayIndex]
+	var fullDaySummaries []DailyExpenseSummary
+	for day := period.Start; day.Before(period.End); day = day.AddDate(0, 0, 1) {
+		dayDate := day.Format(time.DateOnly)
+		expense, ok := daysMap[dayDate]
 		if !ok {
-			daysMap[dayIndex] = DailyExpenseSummary{Day: dayIndex}
+			expense = DailyExpenseSummary{Day: day.Day(), Date: dayDate}
 		}
-	}
-	var fullDaySummaries []DailyExpenseSummary
-	for _, expense := range daysMap {
 		fullDaySummaries = append(fullDaySummaries, expense)
 	}
-
-	sort.Slice(fullDaySummaries, func(i, j int) bool {
-		return fullDaySummaries[i].Day < fullDaySummaries[j].Day
-	})
 	return &fullDaySummaries, nil
 }
 
@@ -332,12 +518,13 @@ type TransactionTagsAggregationResult struct {
 	Tag   string  `json:"tag"`
 }
 
-func (service *TransactionService) GetTagsStatistics(userID int) (*[]TransactionTagsAggregationResult, error) {
+func (service *TransactionService) GetTagsStatistics(ledgerID int64) (*[]TransactionTagsAggregationResult, error) {
 	selectStatement := service.DB.From("transactions").
 		Join(goqu.L("json_each(tags)").As("tag"), goqu.On(goqu.L("1 = 1"))).
 		Where(
 			goqu.C("price").Lte(0),
-			goqu.C("user_id").Eq(userID),
+			goqu.C("ledger_id").Eq(ledgerID),
+			personal(),
 		).
 		Select(
 			goqu.COUNT("*").As("count"),
@@ -357,9 +544,10 @@ func (service *TransactionService) GetTagsStatistics(userID int) (*[]Transaction
 	return &result, nil
 }
 
-func (service *TransactionService) GetBalance(userID int) (float64, error) {
+func (service *TransactionService) GetBalance(ledgerID int64) (float64, error) {
 	selectStatement := service.DB.From("transactions").
-		Select(goqu.SUM("price").As("sum"))
+		Select(goqu.COALESCE(goqu.SUM("price"), 0).As("sum")).
+		Where(goqu.Ex{"ledger_id": ledgerID})
 	var balance float64
 	_, err := selectStatement.ScanVal(&balance)
 	if err != nil {
@@ -368,11 +556,11 @@ func (service *TransactionService) GetBalance(userID int) (float64, error) {
 	return balance, nil
 }
 
-func (service *TransactionService) DeleteTransaction(userID int, id int) (*Transaction, error) {
+func (service *TransactionService) DeleteTransaction(ledgerID int64, id int) (*Transaction, error) {
 	var transaction Transaction
 	found, err := service.DB.From("transactions").
 		Where(
-			goqu.Ex{"user_id": userID, "id": id},
+			goqu.Ex{"ledger_id": ledgerID, "id": id},
 		).ScanStruct(&transaction)
 	if err != nil {
 		fmt.Printf("delete expense err: %v\n", err)
@@ -381,8 +569,9 @@ func (service *TransactionService) DeleteTransaction(userID int, id int) (*Trans
 	if !found {
 		fmt.Printf("delete transaction not found for id %v", id)
 	}
+	transaction.restoreOffset()
 	_, err = service.DB.From("transactions").Delete().Where(
-		goqu.Ex{"user_id": userID, "id": id},
+		goqu.Ex{"ledger_id": ledgerID, "id": id},
 	).Executor().Exec()
 	if err != nil {
 		fmt.Printf("delete expense err: %v\n", err)
@@ -391,12 +580,26 @@ func (service *TransactionService) DeleteTransaction(userID int, id int) (*Trans
 	return &transaction, nil
 }
 
-func (service *TransactionService) GetSumOfExpensesForCurrentMonth(userID int64) (float64, error) {
+// DeleteInTx deletes a transaction as part of a larger change.
+func (service *TransactionService) DeleteInTx(tx *goqu.TxDatabase, ledgerID int64, id int) error {
+	_, err := tx.Delete("transactions").Where(goqu.Ex{"ledger_id": ledgerID, "id": id}).Executor().Exec()
+	if err != nil {
+		return fmt.Errorf("failed to delete transaction: %w", err)
+	}
+	return nil
+}
+
+// GetSumOfExpensesForCurrentMonth sums the expenses of the user's running financial month.
+func (service *TransactionService) GetSumOfExpensesForCurrentMonth(ledgerID int64, userSettings settings.Settings) (float64, error) {
 	q := queries.New(service.DB)
-	timeNow := time.Now()
-	year := strconv.Itoa(timeNow.Year())
-	month := strconv.Itoa(int(timeNow.Month()))
-	sum, err := q.GetSumOfExpensesOfAMonth(context.Background(), queries.GetSumOfExpensesOfAMonthParams{UserID: userID, Date: year, Date_2: month})
+	params := queries.GetSumOfExpensesOfAMonthParams{
+		LedgerID:       ledgerID,
+		Zone:           userSettings.Location().String(),
+		PeriodStartDay: int64(userSettings.PeriodStartDay),
+		PeriodRule:     userSettings.PeriodRule,
+		Period:         userSettings.CurrentPeriod().Label,
+	}
+	sum, err := q.GetSumOfExpensesOfAMonth(context.Background(), params)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, nil
@@ -406,9 +609,15 @@ func (service *TransactionService) GetSumOfExpensesForCurrentMonth(userID int64)
 	return sum.Float64, nil
 }
 
-func (service *TransactionService) GetIncomeSpentPercentage(userID int64) ([]dtos.IncomeSpentDTO, error) {
+func (service *TransactionService) GetIncomeSpentPercentage(ledgerID int64, userSettings settings.Settings) ([]dtos.IncomeSpentDTO, error) {
 	q := queries.New(service.DB)
-	data, err := q.GetIncomeSpentPercentage(context.Background(), userID)
+	params := queries.GetIncomeSpentPercentageParams{
+		Zone:           userSettings.Location().String(),
+		PeriodStartDay: int64(userSettings.PeriodStartDay),
+		PeriodRule:     userSettings.PeriodRule,
+		LedgerID:       ledgerID,
+	}
+	data, err := q.GetIncomeSpentPercentage(context.Background(), params)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return []dtos.IncomeSpentDTO{}, nil
@@ -419,8 +628,11 @@ func (service *TransactionService) GetIncomeSpentPercentage(userID int64) ([]dto
 	resultDTO := []dtos.IncomeSpentDTO{}
 	for _, entry := range data {
 		spentPercentage := entry.SpentPercentage
+		periodStart, periodEnd := periodBounds(userSettings, entry.Month)
 		resultDTO = append(resultDTO, dtos.IncomeSpentDTO{
 			Month:           entry.Month,
+			PeriodStart:     periodStart,
+			PeriodEnd:       periodEnd,
 			TotalIncome:     entry.TotalIncome,
 			TotalSpent:      entry.TotalSpent,
 			SpentPercentage: spentPercentage,
@@ -429,11 +641,17 @@ func (service *TransactionService) GetIncomeSpentPercentage(userID int64) ([]dto
 	return resultDTO, nil
 }
 
-func (service *TransactionService) GetCumulativeBalancePerMonth(userID int64) ([]dtos.CumulativeBalanceDTO, error) {
+func (service *TransactionService) GetCumulativeBalancePerMonth(ledgerID int64, userSettings settings.Settings) ([]dtos.CumulativeBalanceDTO, error) {
 	q := queries.New(service.DB)
 
-	// Get cumulative balance per month
-	data, err := q.GetCumulativeBalancePerMonth(context.Background(), userID)
+	// Get cumulative balance per financial month
+	params := queries.GetCumulativeBalancePerMonthParams{
+		Zone:           userSettings.Location().String(),
+		PeriodStartDay: int64(userSettings.PeriodStartDay),
+		PeriodRule:     userSettings.PeriodRule,
+		LedgerID:       ledgerID,
+	}
+	data, err := q.GetCumulativeBalancePerMonth(context.Background(), params)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return []dtos.CumulativeBalanceDTO{}, nil
@@ -444,15 +662,33 @@ func (service *TransactionService) GetCumulativeBalancePerMonth(userID int64) ([
 	// Prepare DTO to return
 	resultDTO := []dtos.CumulativeBalanceDTO{}
 	for _, entry := range data {
+		periodStart, periodEnd := periodBounds(userSettings, entry.YearMonth)
 		resultDTO = append(resultDTO, dtos.CumulativeBalanceDTO{
 			YearMonth:         entry.YearMonth,
+			PeriodStart:       periodStart,
+			PeriodEnd:         periodEnd,
 			CumulativeBalance: entry.CumulativeBalance,
 		})
 	}
 	return resultDTO, nil
 }
 
-// Returns the number of days in a month for a given year.
-func daysInMonth(m int, year int) int {
-	return time.Date(year, time.Month(m+1), 0, 0, 0, 0, 0, time.UTC).Day()
+// localDate is the date column as wall clock time in loc, so strftime buckets by the user's days and months.
+func localDate(loc *time.Location) exp.LiteralExpression {
+	return goqu.L("local_time(date, ?)", loc.String())
+}
+
+// periodLabel is the name of the user's financial month the date column falls in, e.g. "2024-04".
+func periodLabel(userSettings settings.Settings) exp.LiteralExpression {
+	return goqu.L("period_label(date, ?, ?, ?)", userSettings.Location().String(), userSettings.PeriodStartDay, userSettings.PeriodRule)
+}
+
+// periodBounds returns the first and last day of the financial month with the given label.
+func periodBounds(userSettings settings.Settings, label string) (string, string) {
+	labelMonth, err := time.Parse("2006-01", label)
+	if err != nil {
+		return "", ""
+	}
+	period := userSettings.Period(labelMonth.Year(), labelMonth.Month())
+	return period.Start.Format(time.DateOnly), period.LastDay().Format(time.DateOnly)
 }
@@ -0,0 +1,113 @@
+package transactions
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	goqu "github.com/doug-martin/goqu/v9"
+)
+
+// TaxEntry says whether a price was entered with the tax included or without it.
+type TaxEntry string
+
+const (
+	TaxEntryGross TaxEntry = "gross"
+	TaxEntryNet   TaxEntry = "net"
+)
+
+type TaxInput struct {
+	Deductible bool     `json:"deductible"`
+	TaxRate    *float64 `json:"taxRate"`
+	// TaxAmount is the tax itself, for receipts whose tax does not follow from a single rate
+	TaxAmount *float64 `json:"taxAmount"`
+	// Entry defaults to gross. With net the price is the amount before tax and is stored with the tax added.
+	// When tax is recorded already, net takes the stored price without that tax as the amount before tax.
+	Entry TaxEntry `json:"entry"`
+}
+
+// Validate checks the input against the price it is going to be applied to.
+func (input TaxInput) Validate(price float64) error {
+	if input.Entry != "" && input.Entry != TaxEntryGross && input.Entry != TaxEntryNet {
+		return fmt.Errorf("entry must be %q or %q", TaxEntryGross, TaxEntryNet)
+	}
+	if input.TaxRate != nil && (*input.TaxRate < 0 || *input.TaxRate > 100) {
+		return errors.New("tax rate must be between 0 and 100")
+	}
+	if input.TaxAmount != nil && *input.TaxAmount < 0 {
+		return errors.New("tax amount cannot be negative")
+	}
+	_, _, err := input.apply(price)
+	return err
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// IncludedTax is the tax contained in a gross amount at rate percent.
+func IncludedTax(gross float64, rate float64) float64 {
+	return roundCents(math.Abs(gross) * rate / (100 + rate))
+}
+
+// apply returns the gross price and the tax included in it. An explicit tax amount wins over the rate.
+func (input TaxInput) apply(price float64) (float64, float64, error) {
+	amount := math.Abs(price)
+	tax := 0.0
+	switch {
+	case input.TaxAmount != nil:
+		tax = roundCents(*input.TaxAmount)
+	case input.TaxRate != nil && input.Entry == TaxEntryNet:
+		tax = roundCents(amount * *input.TaxRate / 100)
+	case input.TaxRate != nil:
+		tax = IncludedTax(amount, *input.TaxRate)
+	}
+	if input.Entry == TaxEntryNet {
+		amount = roundCents(amount + tax)
+	} else if tax > amount {
+		return 0, 0, errors.New("tax amount cannot exceed the price")
+	}
+	return math.Copysign(amount, price), tax, nil
+}
+
+// SetTax records the tax of a transaction and whether it is deductible, replacing what was recorded before.
+func (service *TransactionService) SetTax(ledgerID int64, ID int, input TaxInput) (*Transaction, error) {
+	transaction := Transaction{}
+	found, err := service.DB.From("transactions").Where(goqu.Ex{"id": ID, "ledger_id": ledgerID}).ScanStruct(&transaction)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("transaction not found")
+	}
+	// A net entry is taken before the tax recorded so far, so that repeating it does not add the tax twice
+	base := transaction.Price
+	if input.Entry == TaxEntryNet && transaction.TaxAmount > 0 {
+		base = math.Copysign(roundCents(math.Abs(base)-transaction.TaxAmount), base)
+	}
+	if err := input.Validate(base); err != nil {
+		return nil, err
+	}
+	price, tax, err := input.apply(base)
+	if err != nil {
+		return nil, err
+	}
+	_, err = service.DB.Update("transactions").
+		Set(goqu.Record{
+			"price":      price,
+			"deductible": input.Deductible,
+			"tax_rate":   input.TaxRate,
+			"tax_amount": tax,
+		}).
+		Where(goqu.Ex{"id": ID, "ledger_id": ledgerID}).
+		Executor().Exec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update transaction: %w", err)
+	}
+	transaction.Price = price
+	transaction.Deductible = input.Deductible
+	transaction.TaxRate = input.TaxRate
+	transaction.TaxAmount = tax
+	transaction.restoreOffset()
+	return &transaction, nil
+}